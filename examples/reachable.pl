@@ -0,0 +1,12 @@
+/* Find all cities reachable from a given city by a sequence of flights. */
+
+:- max_depth(reachable/2, 4).
+
+flight(albuquerque, denver).
+flight(denver, chicago).
+flight(chicago, boston).
+flight(denver, seattle).
+flight(seattle, anchorage).
+
+reachable(From, To) :- flight(From, To).
+reachable(From, To) :- flight(From, Via), reachable(Via, To).
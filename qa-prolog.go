@@ -1,97 +1,351 @@
 // This program implements a compiler for Quantum-Annealing Prolog.  It accepts
 // a small subset of Prolog and generates weights for a Hamiltonian expression,
 // which can be fed to a quantum annealer such as the D-Wave supercomputer.
+// The compiler itself is implemented by the qaprolog package; this program
+// merely provides a command-line interface to it.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
-)
+	"time"
 
-//go:generate pigeon -o parser.go parser.peg
-//go:generate stringer -type=ASTNodeType
+	"github.com/lanl/QA-Prolog/qaprolog"
+)
 
 var notify *log.Logger // Help notify the user of warnings and errors.
 
-// Empty is used to treat maps as sets.
-type Empty struct{}
-
 // CheckError aborts with an error message if an error value is non-nil.
+// Errors associated with a source position are reported in the conventional
+// file:line:col format, without the program-name prefix.
 func CheckError(err error) {
-	if err != nil {
-		notify.Fatal(err)
+	if err == nil {
+		return
+	}
+	if e, ok := err.(*qaprolog.Error); ok && e.Line > 0 {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
 	}
+	notify.Fatal(err)
 }
 
-// BaseName returns a file path with the directory and extension removed.
-func BaseName(filename string) string {
-	return path.Base(strings.TrimSuffix(filename, path.Ext(filename)))
+// VerbosePrintf outputs a message only if verbose output is enabled.
+func VerbosePrintf(p *qaprolog.Parameters, format string, args ...interface{}) {
+	if p.Verbose {
+		notify.Printf("INFO: "+format, args...)
+	}
 }
 
-// Parameters encapsulates all command-line parameters as well as various
-// global values computed from the AST.
-type Parameters struct {
-	// Command-line parameters
-	ProgName   string   // Name of this program
-	InFileName string   // Name of the input file
-	WorkDir    string   // Directory for holding intermediate files
-	IntBits    uint     // Number of bits to use for each program integer
-	Verbose    bool     // Whether to output verbose execution information
-	Query      string   // Query to apply to the program
-	QmasmArgs  []string // Additional qmasm command-line arguments
-
-	// Computed values
-	SymToInt      map[string]int        // Map from a symbol to an integer
-	IntToSym      []string              // Map from an integer to a symbol
-	TopLevel      map[string][]*ASTNode // Top-level clauses, grouped by name and arity
-	SymBits       uint                  // Number of bits to use for each symbol
-	OutFileBase   string                // Base name (no path or extension) for output files
-	DeleteWorkDir bool                  // Whether to delete WorkDir at the end of the program
+// parseSolverOptions validates the command-line options that select and
+// configure a backend, a solver, and an output format.
+func parseSolverOptions(p *qaprolog.Parameters, betaStr string) {
+	// Select a backend and a solver.
+	switch p.Solver {
+	case "qmasm":
+		if p.Backend == "" {
+			p.Backend = "yosys"
+		}
+	case "sa":
+		if p.Backend == "" {
+			p.Backend = "native"
+		}
+		if p.Backend != "native" {
+			notify.Fatal("--solver=sa requires --backend=native")
+		}
+	case "sat":
+		if p.Backend == "" {
+			p.Backend = "native"
+		}
+		if p.Backend != "native" {
+			notify.Fatal("--solver=sat requires --backend=native")
+		}
+	case "reference":
+		if p.Backend == "" {
+			p.Backend = "native"
+		}
+	case "external":
+		if p.Backend == "" {
+			p.Backend = "native"
+		}
+		if len(p.SolverCommand) == 0 {
+			notify.Fatal("--solver=external requires --solver-command")
+		}
+	default:
+		notify.Fatalf("Unrecognized solver %q (must be one of \"qmasm\", \"sa\", \"sat\", \"reference\", or \"external\")", p.Solver)
+	}
+	if p.Backend != "yosys" && p.Backend != "native" {
+		notify.Fatalf("Unrecognized backend %q (must be either \"yosys\" or \"native\")", p.Backend)
+	}
+
+	// Validate the semantics of integer arithmetic.
+	switch p.Arith {
+	case "exact", "modular", "checked":
+	default:
+		notify.Fatalf("Unrecognized arithmetic semantics %q (must be one of \"exact\", \"modular\", or \"checked\")", p.Arith)
+	}
+
+	// Validate the output format.
+	switch p.Format {
+	case "text", "json", "csv":
+	default:
+		notify.Fatalf("Unrecognized format %q (must be one of \"text\", \"json\", or \"csv\")", p.Format)
+	}
+
+	// Validate the artifact to emit, if any.
+	switch p.Emit {
+	case "", "cnf", "smt2", "qubo", "bqpjson":
+	default:
+		notify.Fatalf("Unrecognized artifact %q (must be one of \"cnf\", \"smt2\", \"qubo\", or \"bqpjson\")", p.Emit)
+	}
+
+	// Validate the stage after which to stop, if any.
+	switch p.StopAfter {
+	case "", "parse", "types", "verilog", "edif", "qmasm":
+	default:
+		notify.Fatalf("Unrecognized stage %q (must be one of \"parse\", \"types\", \"verilog\", \"edif\", or \"qmasm\")", p.StopAfter)
+	}
+	switch {
+	case p.StopAfter != "" && p.Emit != "":
+		notify.Fatal("--stop-after and --emit are mutually exclusive")
+	case p.StopAfter == "edif" && p.Backend != "yosys":
+		notify.Fatal("--stop-after=edif requires --backend=yosys")
+	case p.Output != "" && p.StopAfter == "" && p.Emit == "":
+		notify.Fatal("--output requires either --stop-after or --emit")
+	}
+
+	// Validate the stage from which to resume, if any.
+	switch p.From {
+	case "":
+	case "verilog", "edif", "qmasm":
+		switch {
+		case p.StopAfter != "" || p.Emit != "":
+			notify.Fatal("--from is incompatible with --stop-after and --emit")
+		case p.Solver != "qmasm" && p.Solver != "external":
+			notify.Fatal("--from requires either --solver=qmasm or --solver=external")
+		case p.Verify:
+			notify.Fatal("--from is incompatible with --verify")
+		case p.Query != "":
+			notify.Fatal("--from is incompatible with --query; the query is read from the metadata file")
+		}
+	default:
+		notify.Fatalf("Unrecognized stage %q (must be one of \"verilog\", \"edif\", or \"qmasm\")", p.From)
+	}
+
+	// Validate the simulated-annealing parameters.
+	switch p.Schedule {
+	case "geometric", "linear", "pt":
+	default:
+		notify.Fatalf("Unrecognized schedule %q (must be one of \"geometric\", \"linear\", or \"pt\")", p.Schedule)
+	}
+	if betaStr != "" {
+		bs := strings.Split(betaStr, ",")
+		if len(bs) != 2 {
+			notify.Fatalf("Failed to parse %q as two comma-separated inverse temperatures", betaStr)
+		}
+		var err error
+		p.BetaMin, err = strconv.ParseFloat(strings.TrimSpace(bs[0]), 64)
+		CheckError(err)
+		p.BetaMax, err = strconv.ParseFloat(strings.TrimSpace(bs[1]), 64)
+		CheckError(err)
+		if p.BetaMin <= 0 || p.BetaMax <= 0 {
+			notify.Fatal("Inverse temperatures must be positive")
+		}
+	}
+	if p.Seed == 0 {
+		p.Seed = time.Now().UnixNano()
+	}
 }
 
-// ParseError reports a parse error at a given position.
-var ParseError func(pos position, format string, args ...interface{})
+// createOutput opens the file named by p.Output for writing the final
+// artifact, defaulting to a given name if p.Output is empty.  Either name may
+// be "-" to indicate standard output.  It returns the file and its name.
+func createOutput(p *qaprolog.Parameters, defName string) (*os.File, string) {
+	nm := p.Output
+	if nm == "" {
+		nm = defName
+	}
+	if nm == "-" {
+		return os.Stdout, "<stdout>"
+	}
+	f, err := os.Create(nm)
+	CheckError(err)
+	return f, nm
+}
 
-// VerbosePrintf outputs a message only if verbose output is enabled.
-func VerbosePrintf(p *Parameters, fmt string, args ...interface{}) {
-	if !p.Verbose {
-		return
+// closeOutput closes a file returned by createOutput.
+func closeOutput(f *os.File) {
+	if f != os.Stdout {
+		CheckError(f.Close())
+	}
+}
+
+// emitArtifact writes the artifact named by p.Emit instead of solving the
+// program.
+func emitArtifact(prog *qaprolog.Program) {
+	p := prog.Params
+	switch p.Emit {
+	case "cnf":
+		// Write a DIMACS CNF formula and a map from ports to variables.
+		cf, cName := createOutput(p, p.OutFileBase+".cnf")
+		mName := cName + ".map"
+		if cf == os.Stdout {
+			mName = p.OutFileBase + ".cnf.map"
+		}
+		mf, err := os.Create(mName)
+		CheckError(err)
+		VerbosePrintf(p, "Writing a CNF formula to %s and a variable map to %s", cName, mName)
+		err = qaprolog.EmitCNF(prog, cf, mf)
+		CheckError(err)
+		closeOutput(cf)
+		CheckError(mf.Close())
+
+	case "smt2":
+		// Write an SMT-LIB 2 script.
+		sf, sName := createOutput(p, p.OutFileBase+".smt2")
+		VerbosePrintf(p, "Writing SMT-LIB 2 code to %s", sName)
+		err := qaprolog.EmitSMT2(prog, sf)
+		CheckError(err)
+		closeOutput(sf)
+
+	case "qubo", "bqpjson":
+		// Write the Hamiltonian as either a QUBO or an Ising model.
+		defName := p.OutFileBase + ".qubo"
+		emit := qaprolog.EmitQUBO
+		if p.Emit == "bqpjson" {
+			defName = p.OutFileBase + ".bqp.json"
+			emit = qaprolog.EmitBQPJSON
+		}
+		hf, hName := createOutput(p, defName)
+		VerbosePrintf(p, "Writing the Hamiltonian to %s", hName)
+		err := emit(prog, hf)
+		CheckError(err)
+		closeOutput(hf)
+	}
+}
+
+// stopAfter writes the artifact produced by compiling a program through
+// stage p.StopAfter.  The "parse" and "types" stages write to standard output
+// by default.  Later stages copy a file from the working directory to the
+// current directory by default and are accompanied by a metadata file that
+// lets --from resume compilation.
+func stopAfter(prog *qaprolog.Program) {
+	p := prog.Params
+	switch p.StopAfter {
+	case "parse":
+		f, nm := createOutput(p, "-")
+		VerbosePrintf(p, "Writing the abstract syntax tree to %s", nm)
+		fmt.Fprint(f, prog.AST)
+		closeOutput(f)
+
+	case "types":
+		f, nm := createOutput(p, "-")
+		VerbosePrintf(p, "Writing inferred types to %s", nm)
+		err := qaprolog.WriteTypes(prog, f)
+		CheckError(err)
+		closeOutput(f)
+
+	default:
+		src, err := qaprolog.CompileThrough(prog, p.StopAfter)
+		CheckError(err)
+		data, err := ioutil.ReadFile(src)
+		CheckError(err)
+		f, nm := createOutput(p, path.Base(src))
+		VerbosePrintf(p, "Copying %s to %s", src, nm)
+		_, err = f.Write(data)
+		CheckError(err)
+		closeOutput(f)
+		if f != os.Stdout {
+			mf, err := os.Create(qaprolog.MetadataName(nm))
+			CheckError(err)
+			err = qaprolog.WriteMetadata(prog, mf)
+			CheckError(err)
+			CheckError(mf.Close())
+		}
 	}
-	notify.Printf("INFO: "+fmt, args...)
 }
 
 func main() {
 	// Parse the command line.
-	p := Parameters{}
-	p.ProgName = BaseName(os.Args[0])
+	p := qaprolog.Parameters{}
+	p.ProgName = qaprolog.BaseName(os.Args[0])
 	notify = log.New(os.Stderr, p.ProgName+": ", 0)
+	p.Log = notify
+	p.CacheDir = qaprolog.DefaultCacheDir()
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [<options>] [<infile.pl>]\n\n", p.ProgName)
+		fmt.Fprintf(os.Stderr, "Usage: %s [<options>] [<infile.pl>]\n", p.ProgName)
+		fmt.Fprintf(os.Stderr, "       %s --from=<stage> [<options>] <infile>\n", p.ProgName)
+		fmt.Fprintf(os.Stderr, "       %s --cache-clean [<options>]\n\n", p.ProgName)
 		flag.PrintDefaults()
 	}
 	flag.StringVar(&p.Query, "query", "", "Prolog query to apply to the program")
 	flag.UintVar(&p.IntBits, "int-bits", 0, "minimum integer width in bits")
+	flag.StringVar(&p.Arith, "arith", "exact", `semantics of integer arithmetic, one of "exact" (never overflow), "modular" (wrap around at a fixed integer width), or "checked" (fail if any operation yields a negative value or overflows the integer width)`)
+	flag.BoolVar(&p.Signed, "signed", false, `treat integers as signed (two's-complement) numbers, allowing negative values (also enabled by ":- signed." in the program)`)
+	flag.UintVar(&p.MaxListLen, "max-list-len", 0, "minimum bound on the number of elements in a list")
+	flag.UintVar(&p.MaxDepth, "max-depth", 0, "number of levels to which to unroll recursive predicates that lack a max_depth directive")
+	flag.StringVar(&p.Backend, "backend", "", `method for generating QMASM code, either "yosys" (via Verilog, Yosys, and edif2qmasm) or "native" (default: "yosys" for --solver=qmasm, otherwise "native")`)
+	flag.StringVar(&p.Solver, "solver", "qmasm", `method for solving the program, one of "qmasm", "sa" (classical simulated annealing), "sat" (classical CDCL satisfiability), "reference" (classical resolution with finite-domain labeling), or "external" (a command speaking the protocol in EXTERNAL-SOLVERS.md)`)
+	solverCmd := flag.String("solver-command", "", "command line to run for --solver=external")
+	flag.UintVar(&p.Sweeps, "sweeps", 5000, "number of sweeps per read for --solver=sa")
+	flag.UintVar(&p.Reads, "reads", 100, "number of reads for --solver=sa or --solver=external")
+	flag.StringVar(&p.Schedule, "schedule", "geometric", `temperature schedule for --solver=sa, one of "geometric", "linear", or "pt" (parallel tempering)`)
+	betaStr := flag.String("beta-range", "", `initial and final inverse temperatures for --solver=sa, separated by a comma (default: "3,6")`)
+	flag.UintVar(&p.Replicas, "replicas", 8, "number of replicas for --schedule=pt")
+	flag.Int64Var(&p.Seed, "seed", 0, "random-number seed for --solver=sa or --solver=external (default: based on the current time)")
 	flag.StringVar(&p.WorkDir, "work-dir", "", "directory for storing intermediate files (default: "+path.Join(os.TempDir(), "qap-*")+")")
+	noCache := flag.Bool("no-cache", false, "neither reuse nor store EDIF and QMASM files in the compilation cache ("+p.CacheDir+")")
+	cacheClean := flag.Bool("cache-clean", false, "empty the compilation cache and exit without reading a program")
+	flag.StringVar(&p.Emit, "emit", "", `write an artifact instead of solving the program, one of "cnf" (DIMACS CNF plus a variable map), "smt2" (SMT-LIB 2 bit-vector formulas), "qubo" (qbsolv-format QUBO), or "bqpjson" (bqpjson Ising model)`)
+	flag.StringVar(&p.StopAfter, "stop-after", "", `stage after which to stop and write the stage's artifact instead of solving the program, one of "parse", "types", "verilog", "edif", or "qmasm"`)
+	flag.StringVar(&p.Output, "output", "", `file to which to write the artifact requested by --emit or --stop-after, or "-" for standard output (default: based on the input file name)`)
+	flag.StringVar(&p.From, "from", "", `stage whose artifact, accompanied by the metadata file written by --stop-after, is given in place of a Prolog program, one of "verilog", "edif", or "qmasm"`)
+	flag.BoolVar(&p.Verify, "verify", false, "check each solution against the program classically and discard those that fail")
+	flag.StringVar(&p.Format, "format", "text", `output format for solutions, one of "text", "json", or "csv"`)
 	flag.BoolVar(&p.Verbose, "verbose", false, "output informational messages during execution")
 	flag.BoolVar(&p.Verbose, "v", false, "same as -verbose")
 	qmasmStr := flag.String("qmasm-args", "", "additional command-line arguments to pass to qmasm")
 	flag.Parse()
+	if *cacheClean {
+		if flag.NArg() > 0 {
+			notify.Fatal("--cache-clean does not accept an input file")
+		}
+		CheckError(qaprolog.CleanCache(&p))
+		return
+	}
 	if flag.NArg() == 0 {
 		p.InFileName = "<stdin>"
 	} else {
 		p.InFileName = flag.Arg(0)
 	}
 	p.QmasmArgs = strings.Fields(*qmasmStr)
-	ParseError = func(pos position, format string, args ...interface{}) {
-		fmt.Fprintf(os.Stderr, "%s:%d:%d: ", p.InFileName, pos.line, pos.col)
-		fmt.Fprintf(os.Stderr, format, args...)
-		fmt.Fprintln(os.Stderr, "")
-		os.Exit(1)
+	if *noCache {
+		p.CacheDir = ""
+	}
+	p.SolverCommand = strings.Fields(*solverCmd)
+	parseSolverOptions(&p, *betaStr)
+	if p.From != "" {
+		// Solve a previously compiled program.
+		if flag.NArg() == 0 {
+			notify.Fatalf("--from=%s requires an input file", p.From)
+		}
+		prog, err := qaprolog.Resume(&p, p.From, p.InFileName)
+		CheckError(err)
+		sols, err := qaprolog.Solve(prog)
+		CheckError(err)
+		err = qaprolog.WriteSolutions(prog, os.Stdout, sols)
+		CheckError(err)
+		if p.DeleteWorkDir {
+			err = os.RemoveAll(p.WorkDir)
+			CheckError(err)
+		}
+		return
 	}
 
 	// Open the input file.
@@ -103,60 +357,36 @@ func main() {
 		r = f
 	}
 
-	// If a query was specified, append it to the input file.
-	if p.Query != "" {
-		q := "?- " + p.Query
-		if !strings.HasSuffix(q, ".") {
-			q += "."
-		}
-		r = io.MultiReader(r, strings.NewReader(q))
-	}
-
-	// Parse the input file into an AST.
-	VerbosePrintf(&p, "Parsing %s as Prolog code", p.InFileName)
-	a, err := ParseReader(p.InFileName, r)
+	// Parse, type-check, and compile the program, stopping early if
+	// requested.
+	prog, err := qaprolog.Parse(&p, r)
 	CheckError(err)
-	ast := a.(*ASTNode)
+	switch {
+	case p.StopAfter == "parse":
+		stopAfter(prog)
+	case p.StopAfter != "":
+		err = qaprolog.TypeCheck(prog)
+		CheckError(err)
+		stopAfter(prog)
+	case p.Emit != "":
+		err = qaprolog.TypeCheck(prog)
+		CheckError(err)
+		emitArtifact(prog)
+	default:
+		err = qaprolog.TypeCheck(prog)
+		CheckError(err)
+		if p.Solver != "reference" {
+			// The reference solver works directly on the AST.
+			err = qaprolog.Compile(prog)
+			CheckError(err)
+		}
 
-	// Preprocess the AST.
-	if len(ast.FindByType(QueryType)) == 0 {
-		notify.Fatal("A query must be specified")
+		// Solve the program and report the results.
+		sols, err := qaprolog.Solve(prog)
+		CheckError(err)
+		err = qaprolog.WriteSolutions(prog, os.Stdout, sols)
+		CheckError(err)
 	}
-	ast.RejectUnimplemented(&p)
-	ast.StoreAtomNames(&p)
-	ast.AdjustIntBits(&p)
-	ast.BinClauses(&p)
-	VerbosePrintf(&p, "Representing symbols with %d bit(s) and integers with %d bit(s)", p.SymBits, p.IntBits)
-
-	// Perform type inference on the AST.
-	nm2tys, clVarTys := ast.PerformTypeInference()
-
-	// Create a working directory and switch to it.
-	CreateWorkDir(&p)
-	err = os.Chdir(p.WorkDir)
-	CheckError(err)
-
-	// Output Verilog code.
-	p.OutFileBase = BaseName(p.InFileName)
-	vName := p.OutFileBase + ".v"
-	vf, err := os.Create(vName)
-	CheckError(err)
-	VerbosePrintf(&p, "Writing Verilog code to %s", vName)
-	ast.WriteVerilog(vf, &p, nm2tys, clVarTys)
-	vf.Close()
-
-	// Compile the Verilog code to an EDIF netlist.
-	CreateYosysScript(&p)
-	VerbosePrintf(&p, "Converting Verilog code to an EDIF netlist")
-	RunCommand(&p, "yosys", "-q", "-s", p.OutFileBase+".ys",
-		"-b", "edif", "-o", p.OutFileBase+".edif", p.OutFileBase+".v")
-
-	// Compile the EDIF netlist to QMASM code.
-	VerbosePrintf(&p, "Converting the EDIF netlist to QMASM code")
-	RunCommand(&p, "edif2qmasm", "-o", p.OutFileBase+".qmasm", p.OutFileBase+".edif")
-
-	// Run the QMASM code and report the results.
-	ast.RunQMASM(&p, clVarTys)
 
 	// Optionally remove the working directory.
 	if p.DeleteWorkDir {
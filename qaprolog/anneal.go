@@ -0,0 +1,318 @@
+// Find low-energy states of an Ising Hamiltonian with classical simulated
+// annealing.
+
+package qaprolog
+
+import (
+	"math"
+	"math/rand"
+)
+
+// A coupling is one term of the quadratic part of a Hamiltonian as seen from
+// one of its two spins.
+type coupling struct {
+	Other int     // Index of the other spin
+	J     float64 // Coupler strength
+}
+
+// An annealer represents a Hamiltonian in a form that is convenient for
+// repeatedly computing the energy change caused by flipping a single spin.
+type annealer struct {
+	H     []float64    // Linear coefficient of each spin
+	Nbrs  [][]coupling // Spins coupled to each spin
+	Fixed []bool       // Spins that must not be flipped
+	Free  []int        // Indexes of all spins that may be flipped
+	rng   *rand.Rand   // Source of random numbers
+}
+
+// newAnnealer prepares a netlist's Hamiltonian for simulated annealing.
+// Spins are indexed by net.  The constant nets and all nets in pins are held
+// fixed at their initial values.
+func newAnnealer(nl *Netlist, ham Hamiltonian, pins map[Net]bool, seed int64) *annealer {
+	an := &annealer{
+		H:     make([]float64, nl.NumNets),
+		Nbrs:  make([][]coupling, nl.NumNets),
+		Fixed: make([]bool, nl.NumNets),
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+	for v, w := range ham.H {
+		an.H[v] = w
+	}
+	for vs, w := range ham.J {
+		i, j := int(vs[0]), int(vs[1])
+		an.Nbrs[i] = append(an.Nbrs[i], coupling{Other: j, J: w})
+		an.Nbrs[j] = append(an.Nbrs[j], coupling{Other: i, J: w})
+	}
+	an.Fixed[NetFalse] = true
+	an.Fixed[NetTrue] = true
+	for v := range pins {
+		an.Fixed[v] = true
+	}
+	an.Free = make([]int, 0, nl.NumNets)
+	for i, f := range an.Fixed {
+		if !f {
+			an.Free = append(an.Free, i)
+		}
+	}
+	return an
+}
+
+// randomState returns a random assignment of spins that honors the fixed
+// spins.
+func (an *annealer) randomState(pins map[Net]bool) []int8 {
+	s := make([]int8, len(an.H))
+	s[NetFalse] = -1
+	s[NetTrue] = 1
+	for v, b := range pins {
+		s[v] = -1
+		if b {
+			s[v] = 1
+		}
+	}
+	for _, i := range an.Free {
+		s[i] = int8(2*an.rng.Intn(2) - 1)
+	}
+	return s
+}
+
+// deltaE returns the change in energy that would result from flipping a
+// given spin.
+func (an *annealer) deltaE(s []int8, i int) float64 {
+	f := an.H[i]
+	for _, c := range an.Nbrs[i] {
+		f += c.J * float64(s[c.Other])
+	}
+	return -2 * float64(s[i]) * f
+}
+
+// energy returns the energy of a complete assignment of spins.
+func (an *annealer) energy(s []int8) float64 {
+	e := 0.0
+	for i, h := range an.H {
+		e += h * float64(s[i])
+		for _, c := range an.Nbrs[i] {
+			if c.Other > i {
+				e += c.J * float64(s[i]) * float64(s[c.Other])
+			}
+		}
+	}
+	return e
+}
+
+// sweep performs one Metropolis sweep over all free spins at a given inverse
+// temperature.  It returns the resulting change in energy.
+func (an *annealer) sweep(s []int8, beta float64) float64 {
+	total := 0.0
+	for _, i := range an.Free {
+		dE := an.deltaE(s, i)
+		if dE <= 0 || an.rng.Float64() < math.Exp(-beta*dE) {
+			s[i] = -s[i]
+			total += dE
+		}
+	}
+	return total
+}
+
+// defaultBetaMin and defaultBetaMax are the default initial and final
+// inverse temperatures.  Violating any one gate raises a netlist's energy by
+// at least one, so the initial temperature accepts such a violation with
+// probability e^-3 (about 1/20) and the final temperature with probability
+// e^-6 (about 1/400).  Hotter temperatures merely scramble the state, and
+// colder ones prevent it from escaping local minima.
+const (
+	defaultBetaMin = 3.0
+	defaultBetaMax = 6.0
+)
+
+// schedule returns a sequence of n inverse temperatures from bMin to bMax.
+func schedule(kind string, n uint, bMin, bMax float64) []float64 {
+	betas := make([]float64, n)
+	for k := range betas {
+		t := 1.0
+		if n > 1 {
+			t = float64(k) / float64(n-1)
+		}
+		switch kind {
+		case "linear":
+			betas[k] = bMin + (bMax-bMin)*t
+		default:
+			betas[k] = bMin * math.Pow(bMax/bMin, t)
+		}
+	}
+	return betas
+}
+
+// anneal performs a single simulated-annealing read, returning the lowest-
+// energy state observed.
+func (an *annealer) anneal(pins map[Net]bool, betas []float64) []int8 {
+	s := an.randomState(pins)
+	e := an.energy(s)
+	best := append([]int8{}, s...)
+	bestE := e
+	for _, b := range betas {
+		e += an.sweep(s, b)
+		if e < bestE {
+			best = append(best[:0], s...)
+			bestE = e
+		}
+	}
+	return best
+}
+
+// temper performs a single parallel-tempering read, returning the lowest-
+// energy state observed in any replica.
+func (an *annealer) temper(pins map[Net]bool, sweeps uint, betas []float64) []int8 {
+	// Initialize one replica per temperature.
+	reps := make([][]int8, len(betas))
+	es := make([]float64, len(betas))
+	for r := range reps {
+		reps[r] = an.randomState(pins)
+		es[r] = an.energy(reps[r])
+	}
+	best := append([]int8{}, reps[0]...)
+	bestE := es[0]
+
+	// Alternate sweeps of every replica with exchanges between replicas
+	// at adjacent temperatures.
+	for k := uint(0); k < sweeps; k++ {
+		for r, s := range reps {
+			es[r] += an.sweep(s, betas[r])
+			if es[r] < bestE {
+				best = append(best[:0], s...)
+				bestE = es[r]
+			}
+		}
+		for r := int(k % 2); r+1 < len(reps); r += 2 {
+			d := (betas[r+1] - betas[r]) * (es[r+1] - es[r])
+			if d >= 0 || an.rng.Float64() < math.Exp(d) {
+				reps[r], reps[r+1] = reps[r+1], reps[r]
+				es[r], es[r+1] = es[r+1], es[r]
+			}
+		}
+	}
+	return best
+}
+
+// decodePorts converts a spin assignment to a map from each query port name
+// (without the "Query." prefix or bit index) to an integer value.  Ports
+// named in signed are decoded as two's-complement integers.
+func decodePorts(nl *Netlist, s []int8, signed map[string]bool) map[string]int {
+	vals := make(map[string]int)
+	widths := make(map[string]uint)
+	for pName, v := range nl.Ports {
+		if nm, bit, ok := portBit(pName); ok {
+			vals[nm] += int((s[v]+1)/2) << bit
+			if bit >= widths[nm] {
+				widths[nm] = bit + 1
+			}
+		}
+	}
+	for nm := range signed {
+		if _, ok := vals[nm]; ok {
+			vals[nm] = signExtend(vals[nm], widths[nm])
+		}
+	}
+	return vals
+}
+
+// consistent reports whether a spin assignment is consistent with every gate
+// in a netlist (i.e., represents a ground state of the netlist's Hamiltonian).
+func (n *Netlist) consistent(s []int8) bool {
+	for _, g := range n.Gates {
+		a, b, y := s[g.In[0]] > 0, s[g.In[1]] > 0, s[g.Out] > 0
+		var ok bool
+		switch g.Type {
+		case NotGate:
+			ok = y == !a
+		case AndGate:
+			ok = y == (a && b)
+		case OrGate:
+			ok = y == (a || b)
+		case XorGate:
+			ok = y == (a != b)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// An annealSolver solves a netlist with classical simulated annealing or
+// parallel tempering.
+type annealSolver struct{}
+
+// Solve anneals a problem's netlist and returns the distinct solutions it
+// finds.
+func (annealSolver) Solve(prob *Problem) (sols []Solution, err error) {
+	p := prob.Program.Params
+	defer recoverError(p, &err)
+	nl := prob.Netlist
+	if nl == nil {
+		fatal("--solver=sa requires --backend=native")
+	}
+
+	// Pin the requested ports.  If the query contains no variables, its
+	// success or failure depends on whether we can find any ground state
+	// at all with Query.Valid pinned to true.
+	haveVar := hasVariable(prob.Program.queryTypes())
+	pins := make(map[Net]bool, len(prob.Pins)+1)
+	for nm, b := range prob.Pins {
+		v, ok := nl.Ports[nm]
+		if !ok {
+			fatalf("Cannot pin nonexistent port %s", nm)
+		}
+		if v != NetFalse && v != NetTrue {
+			pins[v] = b
+		}
+	}
+	valid := nl.Ports["Query.Valid"]
+	if valid != NetFalse && valid != NetTrue {
+		pins[valid] = true
+	}
+
+	// Prepare the Hamiltonian for annealing.
+	an := newAnnealer(nl, nl.Hamiltonian(), pins, p.Seed)
+	bMin, bMax := p.BetaMin, p.BetaMax
+	if bMin == 0 && bMax == 0 {
+		bMin, bMax = defaultBetaMin, defaultBetaMax
+	}
+	verbosePrintf(p, "Annealing %d spin(s) with a %s schedule from beta = %g to beta = %g (seed %d)",
+		len(an.Free), p.Schedule, bMin, bMax, p.Seed)
+
+	// Perform the requested number of reads, keeping only those that
+	// reach a ground state.
+	var betas []float64
+	if p.Schedule == "pt" {
+		betas = schedule("geometric", p.Replicas, bMin, bMax)
+	} else {
+		betas = schedule(p.Schedule, p.Sweeps, bMin, bMax)
+	}
+	samples := make([]Solution, 0, p.Reads)
+	signed := prob.signedVars()
+	for r := uint(0); r < p.Reads && valid != NetFalse; r++ {
+		var s []int8
+		if p.Schedule == "pt" {
+			s = an.temper(pins, p.Sweeps, betas)
+		} else {
+			s = an.anneal(pins, betas)
+		}
+		if !nl.consistent(s) {
+			continue
+		}
+		samples = append(samples, Solution{Values: decodePorts(nl, s, signed), Energy: an.energy(s), Tally: 1})
+	}
+	verbosePrintf(p, "%d of %d read(s) reached a ground state", len(samples), p.Reads)
+
+	// Report the solutions, most frequently observed first.  If the query
+	// contains no variables, report only whether any ground state was
+	// found.
+	if !haveVar {
+		ok := 0
+		if len(samples) > 0 {
+			ok = 1
+		}
+		return []Solution{{Values: map[string]int{"Valid": ok}, Tally: len(samples)}}, nil
+	}
+	return tallySolutions(samples), nil
+}
@@ -0,0 +1,42 @@
+// Test the simulated-annealing solver.
+
+package qaprolog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAnneal ensures that simulated annealing with the command line's default
+// schedule finds only correct solutions to a small example and finds at least
+// one.
+func TestAnneal(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "examples", "friends.pl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sols [2]string
+	for i, solver := range []string{"reference", "sa"} {
+		p := testParams("friends(P1, P2)")
+		p.InFileName = "friends.pl"
+		p.Sweeps = 5000
+		p.Reads = 10
+		p.Schedule = "geometric"
+		p.Seed = 1
+		prog, err := typeCheck(p, string(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sols[i] = solveText(t, prog, solver)
+	}
+	if strings.Contains(sols[1], "No solutions") {
+		t.Fatal("Expected simulated annealing to find a solution")
+	}
+	for _, s := range strings.Split(sols[1], "\n\n") {
+		if !strings.Contains(sols[0], s) {
+			t.Errorf("Simulated annealing found %q, which is not among the solutions\n%s", s, sols[0])
+		}
+	}
+}
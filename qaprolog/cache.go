@@ -0,0 +1,138 @@
+// Cache the output of the external tools used by the yosys backend
+
+package qaprolog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// cacheVersion identifies the layout of the cache and the Yosys script
+// written by createYosysScript.  Changing either requires changing
+// cacheVersion so that stale entries are never reused.
+const cacheVersion = 1
+
+// DefaultCacheDir returns the directory in which compilation results are
+// cached by default, or the empty string if the user has no cache directory.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "qa-prolog")
+}
+
+// CleanCache removes all compilation results from p.CacheDir.
+func CleanCache(p *Parameters) (err error) {
+	defer recoverError(p, &err)
+	if p.CacheDir == "" {
+		fatal("No cache directory was specified")
+	}
+	verbosePrintf(p, "Removing cache directory %s", p.CacheDir)
+	checkError(os.RemoveAll(p.CacheDir))
+	return nil
+}
+
+// toolID returns a string that changes whenever a given external tool is
+// reinstalled, namely the tool's location, size, and modification time.
+func toolID(name string) string {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return name + " (not found)"
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return path + " (not found)"
+	}
+	return fmt.Sprintf("%s %d %d", path, info.Size(), info.ModTime().UnixNano())
+}
+
+// verilogBody returns Verilog code without its leading comments.  These name
+// the Prolog source file, which would otherwise keep identical programs in
+// different files from sharing cache entries.
+func verilogBody(verilog []byte) []byte {
+	for bytes.HasPrefix(verilog, []byte("//")) {
+		i := bytes.IndexByte(verilog, '\n')
+		if i < 0 {
+			return nil
+		}
+		verilog = verilog[i+1:]
+	}
+	return verilog
+}
+
+// A compileCache stores the EDIF and QMASM files derived from a Verilog
+// file.  Entries are keyed by a hash of the Verilog code, which reflects the
+// Prolog source, the query, and all options that affect code generation,
+// and of the installed versions of yosys and edif2qmasm.  The Verilog code's
+// header comments are excluded from the hash.
+type compileCache struct {
+	p   *Parameters // Parameters that control compilation
+	key string      // Hash of everything that determines the cached files ("" if caching is disabled)
+}
+
+// newCompileCache returns a cache for the outputs derived from a given
+// Verilog file.  The cache is disabled if p.CacheDir is empty.
+func newCompileCache(p *Parameters, vName string) *compileCache {
+	c := &compileCache{p: p}
+	if p.CacheDir == "" {
+		return c
+	}
+	verilog, err := ioutil.ReadFile(vName)
+	checkError(err)
+	h := sha256.New()
+	fmt.Fprintf(h, "cache version %d\n", cacheVersion)
+	fmt.Fprintf(h, "yosys %s\n", toolID("yosys"))
+	fmt.Fprintf(h, "edif2qmasm %s\n", toolID("edif2qmasm"))
+	h.Write(verilogBody(verilog))
+	c.key = hex.EncodeToString(h.Sum(nil))
+	return c
+}
+
+// entry returns the name of the cache entry with a given extension.
+func (c *compileCache) entry(ext string) string {
+	return filepath.Join(c.p.CacheDir, c.key[:2], c.key+ext)
+}
+
+// fetch copies the cache entry with a given extension to a given file.  It
+// returns false if there is no such entry.
+func (c *compileCache) fetch(ext, fName string) bool {
+	if c.key == "" {
+		return false
+	}
+	data, err := ioutil.ReadFile(c.entry(ext))
+	if err != nil {
+		return false
+	}
+	verbosePrintf(c.p, "Reusing %s from the cache", c.entry(ext))
+	err = ioutil.WriteFile(fName, data, 0666)
+	checkError(err)
+	return true
+}
+
+// store copies a given file to the cache entry with a given extension.  The
+// entry is written to a temporary file and then renamed so that concurrent
+// compilations never observe a partial entry.
+func (c *compileCache) store(ext, fName string) {
+	if c.key == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(fName)
+	checkError(err)
+	eName := c.entry(ext)
+	err = os.MkdirAll(filepath.Dir(eName), 0777)
+	checkError(err)
+	tmp, err := ioutil.TempFile(filepath.Dir(eName), "tmp-")
+	checkError(err)
+	_, err = tmp.Write(data)
+	checkError(err)
+	checkError(tmp.Close())
+	checkError(os.Rename(tmp.Name(), eName))
+	verbosePrintf(c.p, "Storing %s in the cache", eName)
+}
@@ -0,0 +1,93 @@
+// Test the compilation cache.
+
+package qaprolog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestCacheKey ensures that cache keys depend on a program's Verilog code but
+// not on the name of the file from which the code was generated.
+func TestCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	p := &Parameters{CacheDir: filepath.Join(dir, "cache")}
+	key := func(verilog string) string {
+		vName := filepath.Join(dir, "test.v")
+		if err := ioutil.WriteFile(vName, []byte(verilog), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return newCompileCache(p, vName).key
+	}
+	body := "module Query (Valid);\n  output Valid;\n  assign Valid = 1;\nendmodule\n"
+	k1 := key("// Verilog version of Prolog program a.pl\n//\n" + body)
+	k2 := key("// Verilog version of Prolog program /tmp/b.pl\n//\n" + body)
+	k3 := key("// Verilog version of Prolog program a.pl\n//\n" + body + "// Extra\n")
+	if k1 != k2 {
+		t.Error("Expected the same program in different files to share a cache key")
+	}
+	if k1 == k3 {
+		t.Error("Expected different programs to have different cache keys")
+	}
+	if k := newCompileCache(&Parameters{}, filepath.Join(dir, "test.v")).key; k != "" {
+		t.Errorf("Expected no cache key without a cache directory but saw %q", k)
+	}
+}
+
+// TestResumeCache ensures that resuming compilation from Verilog code reuses
+// the QMASM code cached for the same program compiled from another file.
+func TestResumeCache(t *testing.T) {
+	// Compile a program to Verilog code.
+	dir := t.TempDir()
+	p := testParams("p(X)")
+	p.Backend = "yosys"
+	p.WorkDir = filepath.Join(dir, "work1")
+	p.CacheDir = filepath.Join(dir, "cache")
+	prog, err := typeCheck(p, "p(1).\np(2).\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vName, err := CompileThrough(prog, "verilog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Seed the cache with fake EDIF and QMASM code.
+	qmasm := []byte("# Cached QMASM code\n")
+	fake := filepath.Join(dir, "fake")
+	if err := ioutil.WriteFile(fake, qmasm, 0666); err != nil {
+		t.Fatal(err)
+	}
+	c := newCompileCache(p, vName)
+	c.store(".edif", fake)
+	c.store(".qmasm", fake)
+
+	// Resume from a copy of the Verilog code with a different file name
+	// in its header.
+	verilog, err := ioutil.ReadFile(vName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verilog = bytes.Replace(verilog, []byte("test.pl"), []byte("other.pl"), 1)
+	oName := filepath.Join(dir, "other.v")
+	if err := ioutil.WriteFile(oName, verilog, 0666); err != nil {
+		t.Fatal(err)
+	}
+	copyFile(MetadataName(vName), MetadataName(oName))
+	p2 := testParams("")
+	p2.WorkDir = filepath.Join(dir, "work2")
+	p2.CacheDir = p.CacheDir
+	prog2, err := Resume(p2, "verilog", oName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(prog2.qmasmFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, qmasm) {
+		t.Fatalf("Expected cached QMASM code %q but saw %q", qmasm, got)
+	}
+}
@@ -0,0 +1,122 @@
+// Express a netlist as a Boolean formula in conjunctive normal form.
+
+package qaprolog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A CNF is a Boolean formula in conjunctive normal form.  As in DIMACS,
+// variables are numbered from 1, and a negative literal denotes the
+// complement of a variable.
+type CNF struct {
+	NumVars int     // Number of variables
+	Clauses [][]int // Disjunctions of literals, all of which must hold
+}
+
+// netVar returns the CNF variable that represents a given net.
+func netVar(v Net) int {
+	return int(v) + 1
+}
+
+// add appends a clause to a formula.
+func (c *CNF) add(lits ...int) {
+	c.Clauses = append(c.Clauses, lits)
+}
+
+// CNF Tseitin-encodes a netlist as a formula that is satisfied by exactly the
+// consistent assignments to the netlist's nets.  Each net is represented by
+// the variable netVar returns, and the ancillary net of each XOR gate is
+// constrained to the AND of the gate's inputs so that every model is also a
+// ground state of the netlist's Hamiltonian.  Each port named in pins is
+// additionally required to take the given value.
+func (n *Netlist) CNF(pins map[string]bool) *CNF {
+	// Fix the values of the two constant nets.
+	c := &CNF{
+		NumVars: n.NumNets,
+		Clauses: make([][]int, 0, len(n.Gates)*3+len(pins)+2),
+	}
+	c.add(-netVar(NetFalse))
+	c.add(netVar(NetTrue))
+
+	// Encode each gate.
+	for _, g := range n.Gates {
+		a, b, y := netVar(g.In[0]), netVar(g.In[1]), netVar(g.Out)
+		switch g.Type {
+		case NotGate:
+			c.add(y, a)
+			c.add(-y, -a)
+		case AndGate:
+			c.add(-y, a)
+			c.add(-y, b)
+			c.add(y, -a, -b)
+		case OrGate:
+			c.add(y, -a)
+			c.add(y, -b)
+			c.add(-y, a, b)
+		case XorGate:
+			c.add(-y, a, b)
+			c.add(-y, -a, -b)
+			c.add(y, -a, b)
+			c.add(y, a, -b)
+			h := netVar(g.Aux)
+			c.add(-h, a)
+			c.add(-h, b)
+			c.add(h, -a, -b)
+		default:
+			fatalf("Internal error: Unexpected gate type %d", g.Type)
+		}
+	}
+
+	// Pin the requested ports in a deterministic order.
+	pNames := make([]string, 0, len(pins))
+	for nm := range pins {
+		pNames = append(pNames, nm)
+	}
+	sort.Strings(pNames)
+	for _, nm := range pNames {
+		v, ok := n.Ports[nm]
+		if !ok {
+			fatalf("Cannot pin nonexistent port %s", nm)
+		}
+		if pins[nm] {
+			c.add(netVar(v))
+		} else {
+			c.add(-netVar(v))
+		}
+	}
+	return c
+}
+
+// WriteDIMACS writes a formula in DIMACS CNF format.
+func (c *CNF) WriteDIMACS(w io.Writer, p *Parameters) {
+	fmt.Fprintf(w, "c DIMACS CNF version of Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "c Conversion by %s, written by Scott Pakin <pakin@lanl.gov>\n", p.ProgName)
+	fmt.Fprintln(w, "c")
+	fmt.Fprintln(w, "c See the accompanying variable map for the meaning of each variable.")
+	fmt.Fprintf(w, "p cnf %d %d\n", c.NumVars, len(c.Clauses))
+	for _, cl := range c.Clauses {
+		for _, l := range cl {
+			fmt.Fprintf(w, "%d ", l)
+		}
+		fmt.Fprintln(w, "0")
+	}
+}
+
+// WriteVarMap writes the CNF variable that represents each of a netlist's
+// ports, one "<port> <variable>" pair per line, sorted by port name.
+func (n *Netlist) WriteVarMap(w io.Writer, p *Parameters) {
+	fmt.Fprintf(w, "# Map from ports to DIMACS variables for Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "# Variables %d and %d are always false and true, respectively.\n",
+		netVar(NetFalse), netVar(NetTrue))
+	ports := make([]string, 0, len(n.Ports))
+	for nm := range n.Ports {
+		ports = append(ports, nm)
+	}
+	sort.Strings(ports)
+	for _, nm := range ports {
+		fmt.Fprintf(w, "%s %d\n", nm, netVar(n.Ports[nm]))
+	}
+}
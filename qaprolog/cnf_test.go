@@ -0,0 +1,161 @@
+// Test the CNF encoding of netlists and the SAT solver.
+
+package qaprolog
+
+import "testing"
+
+// satisfies reports whether an assignment, indexed by net, satisfies every
+// clause of a formula.
+func satisfies(c *CNF, val []bool) bool {
+	for _, cl := range c.Clauses {
+		sat := false
+		for _, l := range cl {
+			if l > 0 && val[l-1] || l < 0 && !val[-l-1] {
+				sat = true
+				break
+			}
+		}
+		if !sat {
+			return false
+		}
+	}
+	return true
+}
+
+// spinsOf converts an assignment, indexed by net, to spins.
+func spinsOf(val []bool) []int8 {
+	s := make([]int8, len(val))
+	for i, b := range val {
+		s[i] = -1
+		if b {
+			s[i] = 1
+		}
+	}
+	return s
+}
+
+// TestGateCNF ensures that the models of each gate's CNF encoding are exactly
+// the assignments consistent with the gate and that each model is a ground
+// state of the gate's Hamiltonian.
+func TestGateCNF(t *testing.T) {
+	gates := []struct {
+		name string
+		make func(n *Netlist, a, b Net) Net
+		eval func(a, b bool) bool
+	}{
+		{"NOT", func(n *Netlist, a, b Net) Net { return n.Not(a) }, func(a, b bool) bool { return !a }},
+		{"AND", (*Netlist).And, func(a, b bool) bool { return a && b }},
+		{"OR", (*Netlist).Or, func(a, b bool) bool { return a || b }},
+		{"XOR", (*Netlist).Xor, func(a, b bool) bool { return a != b }},
+	}
+	for _, g := range gates {
+		n := NewNetlist()
+		a, b := n.NewNet(), n.NewNet()
+		y := g.make(n, a, b)
+		if len(n.Gates) != 1 {
+			t.Fatalf("%s: expected 1 gate but saw %d", g.name, len(n.Gates))
+		}
+		aux := n.Gates[0].Aux
+		c := n.CNF(nil)
+		ham := n.Hamiltonian()
+
+		// Compare every assignment to the formula.
+		nModels := 0
+		minE, modelE := 0.0, 0.0
+		for bits := 0; bits < 1<<uint(n.NumNets); bits++ {
+			val := make([]bool, n.NumNets)
+			for i := range val {
+				val[i] = bits&(1<<uint(i)) != 0
+			}
+			want := !val[NetFalse] && val[NetTrue] && val[y] == g.eval(val[a], val[b])
+			if g.name == "XOR" {
+				want = want && val[aux] == (val[a] && val[b])
+			}
+			got := satisfies(c, val)
+			if got != want {
+				t.Errorf("%s: assignment %v: expected satisfiable = %v but saw %v", g.name, val, want, got)
+			}
+			e := ham.energy(spinsOf(val))
+			if bits == 0 || e < minE {
+				minE = e
+			}
+			if got {
+				if nModels > 0 && e != modelE {
+					t.Errorf("%s: models have energies %g and %g", g.name, modelE, e)
+				}
+				modelE = e
+				nModels++
+			}
+		}
+		if nModels != 4 {
+			t.Errorf("%s: expected 4 models but saw %d", g.name, nModels)
+		}
+		if modelE != minE {
+			t.Errorf("%s: expected models to have the minimum energy, %g, but saw %g", g.name, minE, modelE)
+		}
+	}
+}
+
+// TestCNFPins ensures that pinning a port constrains its net.
+func TestCNFPins(t *testing.T) {
+	n := NewNetlist()
+	a, b := n.NewNet(), n.NewNet()
+	n.Ports["Y"] = n.And(a, b)
+	c := n.CNF(map[string]bool{"Y": true})
+	val := []bool{false, true, true, true, true}
+	if !satisfies(c, val) {
+		t.Fatal("Expected Y = A & B = 1 to be satisfiable")
+	}
+	val[a] = false
+	val[n.Ports["Y"]] = false
+	if satisfies(c, val) {
+		t.Fatal("Expected Y = 0 to violate the pin")
+	}
+}
+
+// countModels returns the number of models of a formula, blocking each model
+// before searching for the next.
+func countModels(c *CNF) int {
+	s := newCDCL(c)
+	nModels := 0
+	for s.solve() {
+		nModels++
+		block := make([]satLit, c.NumVars)
+		for v, a := range s.assigns {
+			block[v] = satLitOf(v + 1)
+			if a > 0 {
+				block[v] = block[v].not()
+			}
+		}
+		s.addClause(block)
+	}
+	return nModels
+}
+
+// TestCDCL tests the SAT solver on small formulas.
+func TestCDCL(t *testing.T) {
+	tests := []struct {
+		name string
+		c    CNF
+		want int
+	}{
+		{"empty", CNF{NumVars: 2}, 4},
+		{"or", CNF{NumVars: 2, Clauses: [][]int{{1, 2}}}, 3},
+		{"xor", CNF{NumVars: 2, Clauses: [][]int{{1, 2}, {-1, -2}}}, 2},
+		{"unit", CNF{NumVars: 3, Clauses: [][]int{{1}, {-1, 2}, {-2, 3}}}, 1},
+		{"contradiction", CNF{NumVars: 1, Clauses: [][]int{{1}, {-1}}}, 0},
+
+		// Three pigeons cannot share two holes.  Variable 2*i+j+1
+		// places pigeon i in hole j.
+		{"pigeonhole", CNF{NumVars: 6, Clauses: [][]int{
+			{1, 2}, {3, 4}, {5, 6},
+			{-1, -3}, {-1, -5}, {-3, -5},
+			{-2, -4}, {-2, -6}, {-4, -6},
+		}}, 0},
+	}
+	for _, tt := range tests {
+		if got := countModels(&tt.c); got != tt.want {
+			t.Errorf("%s: expected %d model(s) but saw %d", tt.name, tt.want, got)
+		}
+	}
+}
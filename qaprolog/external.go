@@ -0,0 +1,237 @@
+// Solve a program with an external command that speaks a JSON protocol
+
+package qaprolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// extProtocolVersion is the version of the external-solver protocol
+// described in EXTERNAL-SOLVERS.md.
+const extProtocolVersion = 1
+
+// An extRequest is the JSON object written to an external solver's standard
+// input.
+type extRequest struct {
+	Version   int                `json:"version"`     // Protocol version
+	QMASMFile string             `json:"qmasm_file"`  // Absolute name of the QMASM file
+	H         map[string]float64 `json:"h,omitempty"` // Linear coefficient of each spin (native backend only)
+	J         []extCoupler       `json:"J,omitempty"` // Quadratic coefficients (native backend only)
+	Pins      map[string]bool    `json:"pins"`        // Spins that must take a given value
+	NumReads  uint               `json:"num_reads"`   // Number of samples requested
+	Seed      int64              `json:"seed"`        // Random-number seed
+}
+
+// An extCoupler is one quadratic term of a Hamiltonian.
+type extCoupler struct {
+	I     string  `json:"i"`     // Name of the first spin
+	J     string  `json:"j"`     // Name of the second spin
+	Value float64 `json:"value"` // Coupler strength
+}
+
+// An extResponse is the JSON object an external solver writes to its
+// standard output.
+type extResponse struct {
+	Samples []extSample `json:"samples"`         // All samples taken
+	Error   string      `json:"error,omitempty"` // Error message, if the solver failed
+}
+
+// An extSample is a single sample returned by an external solver.
+type extSample struct {
+	Spins  map[string]int `json:"spins"`            // Value of each spin (positive for true, otherwise false)
+	Energy *float64       `json:"energy,omitempty"` // Energy of the sample (computed if omitted)
+	Count  int            `json:"count,omitempty"`  // Number of times the sample was observed (default 1)
+}
+
+// An externalSolver solves a program by running a user-specified command
+// that reads an extRequest from its standard input and writes an
+// extResponse to its standard output.
+type externalSolver struct {
+	Command []string // Command and its arguments
+}
+
+// request constructs the JSON request to send to an external solver.  If
+// the program was compiled by the native backend, the request includes the
+// program's Hamiltonian, and pins are expressed in terms of the
+// Hamiltonian's spin names.
+func (es externalSolver) request(prob *Problem) *extRequest {
+	p := prob.Program.Params
+	req := &extRequest{
+		Version:   extProtocolVersion,
+		QMASMFile: prob.QMASMFile,
+		Pins:      make(map[string]bool, len(prob.Pins)),
+		NumReads:  p.Reads,
+		Seed:      p.Seed,
+	}
+	nl := prob.Netlist
+	if nl == nil {
+		for nm, b := range prob.Pins {
+			req.Pins[nm] = b
+		}
+		return req
+	}
+
+	// Express the Hamiltonian in terms of spin names.  As in WriteQMASM,
+	// ensure that every port is mentioned.
+	ham := nl.Hamiltonian()
+	names, _ := nl.netNames()
+	req.H = make(map[string]float64, len(ham.H))
+	for v, w := range ham.H {
+		req.H[names[v]] = w
+	}
+	for _, v := range nl.Ports {
+		if _, ok := ham.H[v]; !ok && v != NetFalse && v != NetTrue {
+			req.H[names[v]] = 0
+		}
+	}
+	req.J = make([]extCoupler, 0, len(ham.J))
+	for vs, w := range ham.J {
+		req.J = append(req.J, extCoupler{I: names[vs[0]], J: names[vs[1]], Value: w})
+	}
+	sort.Slice(req.J, func(i, j int) bool {
+		if req.J[i].I != req.J[j].I {
+			return req.J[i].I < req.J[j].I
+		}
+		return req.J[i].J < req.J[j].J
+	})
+
+	// Pin spins rather than ports.  Ports tied to a constant need no pin.
+	for nm, b := range prob.Pins {
+		v, ok := nl.Ports[nm]
+		if !ok {
+			fatalf("Cannot pin nonexistent port %s", nm)
+		}
+		if v != NetFalse && v != NetTrue {
+			req.Pins[names[v]] = b
+		}
+	}
+	return req
+}
+
+// decode converts an external solver's sample to a Solution.
+func (es externalSolver) decode(prob *Problem, ham Hamiltonian, smp extSample) Solution {
+	sol := Solution{Values: make(map[string]int), Tally: smp.Count}
+	if sol.Tally <= 0 {
+		sol.Tally = 1
+	}
+	if smp.Energy != nil {
+		sol.Energy = *smp.Energy
+	}
+	nl := prob.Netlist
+	if nl == nil {
+		// Without a netlist, only port names can be decoded.
+		widths := make(map[string]uint)
+		for nm, b := range smp.Spins {
+			vName, bit, ok := portBit(nm)
+			if !ok {
+				continue
+			}
+			val := sol.Values[vName]
+			if b > 0 {
+				val |= 1 << bit
+			}
+			sol.Values[vName] = val
+			if bit >= widths[vName] {
+				widths[vName] = bit + 1
+			}
+		}
+		for vName := range prob.signedVars() {
+			if val, ok := sol.Values[vName]; ok {
+				sol.Values[vName] = signExtend(val, widths[vName])
+			}
+		}
+		return sol
+	}
+
+	// Map spin names back to nets, and decode the ports from those.
+	names, nets := nl.netNames()
+	for v, nm := range names {
+		nets[nm] = v
+	}
+	s := make([]int8, nl.NumNets)
+	for i := range s {
+		s[i] = -1
+	}
+	s[NetTrue] = 1
+	seen := make([]bool, nl.NumNets)
+	for nm, b := range smp.Spins {
+		v, ok := nets[nm]
+		if !ok {
+			fatalf("External solver returned unknown spin %q", nm)
+		}
+		if v == NetFalse || v == NetTrue {
+			continue
+		}
+		if b > 0 {
+			s[v] = 1
+		}
+		seen[v] = true
+	}
+	for pName, v := range nl.Ports {
+		if !seen[v] && v != NetFalse && v != NetTrue {
+			fatalf("External solver did not return a value for %s", pName)
+		}
+	}
+	sol.Values = decodePorts(nl, s, prob.signedVars())
+	if smp.Energy == nil {
+		sol.Energy = ham.energy(s)
+	}
+	return sol
+}
+
+// Solve runs the external solver and returns the solutions it reports.
+func (es externalSolver) Solve(prob *Problem) (sols []Solution, err error) {
+	p := prob.Program.Params
+	defer recoverError(p, &err)
+	if prob.QMASMFile == "" {
+		fatal("--solver=external requires a compiled program")
+	}
+
+	// Write the request to a file in case the user wants to look at it
+	// later.
+	req, err := json.MarshalIndent(es.request(prob), "", "  ")
+	checkError(err)
+	base := filepath.Join(p.WorkDir, p.OutFileBase)
+	err = ioutil.WriteFile(base+".request.json", req, 0666)
+	checkError(err)
+
+	// Execute the external solver.
+	var out bytes.Buffer
+	cmd := exec.Command(es.Command[0], es.Command[1:]...)
+	cmd.Dir = p.WorkDir
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	verbosePrintf(p, "Executing %s", strings.Join(es.Command, " "))
+	err = cmd.Run()
+	checkError(err)
+	err = ioutil.WriteFile(base+".response.json", out.Bytes(), 0666)
+	checkError(err)
+
+	// Decode the response.
+	var resp extResponse
+	err = json.Unmarshal(out.Bytes(), &resp)
+	if err != nil {
+		fatalf("Failed to parse the output of %s: %s", es.Command[0], err)
+	}
+	if resp.Error != "" {
+		fatalf("%s: %s", es.Command[0], resp.Error)
+	}
+	var ham Hamiltonian
+	if prob.Netlist != nil {
+		ham = prob.Netlist.Hamiltonian()
+	}
+	sols = make([]Solution, 0, len(resp.Samples))
+	for _, smp := range resp.Samples {
+		sols = append(sols, es.decode(prob, ham, smp))
+	}
+	verbosePrintf(p, "Received %d sample(s) from %s", len(sols), es.Command[0])
+	return tallySolutions(sols), nil
+}
@@ -0,0 +1,185 @@
+// Output solutions in machine-readable formats
+
+package qaprolog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonBindings maps query variables to their values.  Unlike a map, it
+// marshals its variables in the order they appear in the query.
+type jsonBindings struct {
+	names  []string               // Variables that have a value, in query order
+	values map[string]interface{} // Value of each variable
+}
+
+// MarshalJSON marshals a set of bindings as a JSON object.
+func (b jsonBindings) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, nm := range b.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(nm)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(b.values[nm])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// A jsonSolution is the JSON representation of a Solution.
+type jsonSolution struct {
+	Bindings jsonBindings `json:"bindings"`           // Value of each query variable
+	Valid    bool         `json:"valid"`              // Whether the query succeeded
+	Energy   *float64     `json:"energy,omitempty"`   // Energy of the solution (absent for solvers that do not report one)
+	Count    int          `json:"count"`              // Number of times the solution was observed
+	Verified *bool        `json:"verified,omitempty"` // Whether the solution passed verification (absent if not verified)
+}
+
+// jsonTerm converts a decoded term to a value that can be marshaled as JSON:
+// a number, a string (for atoms), an array (for lists), or an object with
+// "functor" and "args" fields (for structures).  Invalid values are
+// represented by nil.
+func jsonTerm(t refTerm) interface{} {
+	switch t := t.(type) {
+	case refInt:
+		return int(t)
+
+	case refAtom:
+		return string(t)
+
+	case refNil, *refCons:
+		elts := make([]interface{}, 0, 8)
+		for c, ok := t.(*refCons); ok; c, ok = c.Tail.(*refCons) {
+			elts = append(elts, jsonTerm(c.Head))
+		}
+		return elts
+
+	case *refStruct:
+		args := make([]interface{}, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = jsonTerm(a)
+		}
+		return map[string]interface{}{
+			"functor": t.Functor[:strings.LastIndex(t.Functor, "/")],
+			"args":    args,
+		}
+
+	default:
+		return nil
+	}
+}
+
+// queryVarNames returns the names of all reportable query variables in the
+// order in which they appear in the query.  If that order is not known (as
+// for metadata written by an older version), the names are sorted instead.
+func queryVarNames(order []string, tys TypeInfo) []string {
+	reportable := func(nm string) bool {
+		ty, ok := tys[nm]
+		return ok && ty != InfUnknown && !strings.HasPrefix(nm, "_")
+	}
+	names := make([]string, 0, len(tys))
+	if order != nil {
+		for _, nm := range order {
+			if reportable(nm) {
+				names = append(names, nm)
+			}
+		}
+		return names
+	}
+	for nm := range tys {
+		if reportable(nm) {
+			names = append(names, nm)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isValid reports whether a solution indicates that the query succeeded.
+func (sol Solution) isValid() bool {
+	v, ok := sol.Values["Valid"]
+	return !ok || v == 1
+}
+
+// writeJSON writes a list of solutions as a JSON array of objects, binding
+// the named query variables in the given order.
+func (p *Parameters) writeJSON(w io.Writer, names []string, tys TypeInfo, sols []Solution) {
+	js := make([]jsonSolution, len(sols))
+	for i, sol := range sols {
+		js[i] = jsonSolution{
+			Bindings: jsonBindings{
+				names:  make([]string, 0, len(names)),
+				values: make(map[string]interface{}, len(names)),
+			},
+			Valid:    sol.isValid(),
+			Count:    sol.Tally,
+			Verified: sol.Verified,
+		}
+		if p.reportsEnergy() {
+			e := sol.Energy
+			js[i].Energy = &e
+		}
+		for _, nm := range names {
+			if val, ok := sol.Values[nm]; ok {
+				js[i].Bindings.names = append(js[i].Bindings.names, nm)
+				js[i].Bindings.values[nm] = jsonTerm(p.decodeTerm(tys[nm], val))
+			}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(js)
+	checkError(err)
+}
+
+// writeCSV writes a list of solutions as CSV, with one column per named query
+// variable, in the given order, followed by columns for each solution's
+// metadata.
+func (p *Parameters) writeCSV(w io.Writer, names []string, tys TypeInfo, sols []Solution) {
+	cw := csv.NewWriter(w)
+	hdr := append(append([]string{}, names...), "valid", "energy", "count", "verified")
+	checkError(cw.Write(hdr))
+	for _, sol := range sols {
+		rec := make([]string, 0, len(hdr))
+		for _, nm := range names {
+			if val, ok := sol.Values[nm]; ok {
+				rec = append(rec, p.formatValue(tys[nm], val))
+			} else {
+				rec = append(rec, "")
+			}
+		}
+		energy := ""
+		if p.reportsEnergy() {
+			energy = strconv.FormatFloat(sol.Energy, 'g', -1, 64)
+		}
+		verified := ""
+		if sol.Verified != nil {
+			verified = strconv.FormatBool(*sol.Verified)
+		}
+		rec = append(rec,
+			strconv.FormatBool(sol.isValid()),
+			energy,
+			strconv.Itoa(sol.Tally),
+			verified)
+		checkError(cw.Write(rec))
+	}
+	cw.Flush()
+	checkError(cw.Error())
+}
@@ -0,0 +1,172 @@
+// Test the output of solutions.
+
+package qaprolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestDecodeInvalid ensures that invalid values are reported as such by every
+// output format and are rejected by verification.
+func TestDecodeInvalid(t *testing.T) {
+	prog, err := typeCheck(testParams("p(X)"), "p([a, b]).\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := prog.Params
+	ty := prog.queryTypes()["X"]
+	eBits := p.typeBits(ty.ElemType())
+	badLen := int(p.MaxListLen+1) << (p.MaxListLen * eBits)
+	badSym := len(p.IntToSym)
+	tests := []struct {
+		ty   VarType
+		val  int
+		want string
+	}{
+		{ty, badLen, "[invalid]"},
+		{InfAtom, badSym, "[invalid]"},
+		{ty, 1<<(p.MaxListLen*eBits) | badSym, "[[invalid]]"},
+	}
+	for _, tt := range tests {
+		if got := p.formatValue(tt.ty, tt.val); got != tt.want {
+			t.Errorf("%s %d: expected %q but saw %q", tt.ty, tt.val, tt.want, got)
+		}
+		if _, ok := p.decodeValue(tt.ty, tt.val); ok {
+			t.Errorf("%s %d: expected decodeValue to reject the value", tt.ty, tt.val)
+		}
+	}
+	if got := jsonTerm(p.decodeTerm(InfAtom, badSym)); got != nil {
+		t.Errorf("Expected an invalid atom to be represented in JSON by nil but saw %v", got)
+	}
+}
+
+// writeFormat solves a program with a given solver and returns its solutions
+// in a given format.
+func writeFormat(t *testing.T, solver, format string, verify bool) string {
+	t.Helper()
+	p := testParams("p(X)")
+	p.Solver = solver
+	p.Format = format
+	p.Verify = verify
+	prog, err := typeCheck(p, "p(f(a, 7)).\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solver == "sat" {
+		prog.buildNetlist()
+	}
+	sols, err := Solve(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSolutions(prog, &buf, sols); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// TestWriteCSV ensures that CSV output omits energies for solvers that do not
+// report them and verification results when verification was not run.
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		solver string
+		verify bool
+		want   string
+	}{
+		{"reference", false, "X,valid,energy,count,verified\n\"f(a, 7)\",true,,1,\n"},
+		{"reference", true, "X,valid,energy,count,verified\n\"f(a, 7)\",true,,1,true\n"},
+		{"sat", false, "X,valid,energy,count,verified\n\"f(a, 7)\",true,,1,\n"},
+		{"sat", true, "X,valid,energy,count,verified\n\"f(a, 7)\",true,,1,true\n"},
+	}
+	for _, tt := range tests {
+		if got := writeFormat(t, tt.solver, "csv", tt.verify); got != tt.want {
+			t.Errorf("--solver=%s --verify=%v: expected %q but saw %q", tt.solver, tt.verify, tt.want, got)
+		}
+	}
+
+	// Solvers that sample the Hamiltonian report an energy.
+	p := &Parameters{Solver: "sa"}
+	var buf bytes.Buffer
+	p.writeCSV(&buf, []string{"X"}, TypeInfo{"X": InfNumeral}, []Solution{{Values: map[string]int{"X": 5}, Energy: -2.5, Tally: 3}})
+	if want := "X,valid,energy,count,verified\n5,true,-2.5,3,\n"; buf.String() != want {
+		t.Errorf("--solver=sa: expected %q but saw %q", want, buf.String())
+	}
+}
+
+// TestWriteJSON ensures that JSON output represents structures as objects and
+// includes energies and verification results only when they are meaningful.
+func TestWriteJSON(t *testing.T) {
+	for _, verify := range []bool{false, true} {
+		var js []map[string]interface{}
+		if err := json.Unmarshal([]byte(writeFormat(t, "sat", "json", verify)), &js); err != nil {
+			t.Fatal(err)
+		}
+		if len(js) != 1 {
+			t.Fatalf("Expected 1 solution but saw %d", len(js))
+		}
+		x, _ := json.Marshal(js[0]["bindings"])
+		if want := `{"X":{"args":["a",7],"functor":"f"}}`; string(x) != want {
+			t.Errorf("Expected bindings %s but saw %s", want, x)
+		}
+		if _, ok := js[0]["energy"]; ok {
+			t.Error("Expected --solver=sat to report no energy")
+		}
+		if v, ok := js[0]["verified"]; ok != verify || verify && v != true {
+			t.Errorf("--verify=%v: expected verified to be %v but saw %v", verify, verify, v)
+		}
+	}
+}
+
+// TestColumnOrder ensures that CSV and JSON output list query variables in
+// the order they appear in the query rather than alphabetically.
+func TestColumnOrder(t *testing.T) {
+	for _, format := range []string{"csv", "json"} {
+		p := testParams("q(Y, X)")
+		p.Format = format
+		prog, err := typeCheck(p, "q(1, 2).\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sols, err := Solve(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := WriteSolutions(prog, &buf, sols); err != nil {
+			t.Fatal(err)
+		}
+		got, want := buf.String(), "Y,X,valid,energy,count,verified\n1,2,true,,1,\n"
+		if format == "json" {
+			got = strings.Join(strings.Fields(got), "")
+			want = `"bindings":{"Y":1,"X":2}`
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("--format=%s: expected %q but saw %q", format, want, got)
+		}
+	}
+}
+
+// TestFormatTerm tests the conversion of terms to strings.
+func TestFormatTerm(t *testing.T) {
+	tests := []struct {
+		t    refTerm
+		want string
+	}{
+		{refInt(-3), "-3"},
+		{refAtom("abc"), "abc"},
+		{refNil{}, "[]"},
+		{&refCons{Head: refInt(1), Tail: &refCons{Head: refInt(2), Tail: refNil{}}}, "[1, 2]"},
+		{&refStruct{Functor: "f/2", Args: []refTerm{refAtom("a"), refInt(7)}}, "f(a, 7)"},
+		{refInvalid{}, "[invalid]"},
+		{&refStruct{Functor: "g/1", Args: []refTerm{refInvalid{}}}, "g([invalid])"},
+	}
+	for _, tt := range tests {
+		if got := formatTerm(tt.t); got != tt.want {
+			t.Errorf("Expected %q but saw %q", tt.want, got)
+		}
+	}
+}
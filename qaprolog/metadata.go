@@ -0,0 +1,232 @@
+// Persist the information needed to decode solutions without the Prolog source
+
+package qaprolog
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metadataVersion is the version of the sidecar metadata format.
+const metadataVersion = 1
+
+// A Metadata records everything that is needed to decode a compiled
+// program's solutions but is not evident from its Verilog, EDIF, or QMASM
+// code.  Types are stored as their String representations.
+type Metadata struct {
+	Version      int                 `json:"version"`       // Metadata format version
+	Program      string              `json:"program"`       // Name of the Prolog program
+	Query        string              `json:"query"`         // Query applied to the program
+	IntBits      uint                `json:"int_bits"`      // Number of bits used for each integer
+	Signed       bool                `json:"signed"`        // Whether integers are signed
+	SymBits      uint                `json:"sym_bits"`      // Number of bits used for each symbol
+	MaxListLen   uint                `json:"max_list_len"`  // Maximum number of elements in a list
+	ListLenBits  uint                `json:"list_len_bits"` // Number of bits used for each list length
+	FunctorBits  uint                `json:"functor_bits"`  // Number of bits used for each functor tag
+	MaxArity     uint                `json:"max_arity"`     // Maximum number of arguments to any functor
+	IntToSym     []string            `json:"symbols"`       // Map from an integer to a symbol
+	IntToFunctor []string            `json:"functors"`      // Map from an integer to a functor's name and arity
+	FunctorTypes map[string][]string `json:"functor_types"` // Argument types for each functor
+	QueryTypes   map[string]string   `json:"query_types"`   // Type of each query variable
+	QueryVars    []string            `json:"query_vars"`    // Names of the query variables in order of appearance
+}
+
+// MetadataName returns the name of the sidecar metadata file that
+// accompanies a given Verilog, EDIF, or QMASM file.
+func MetadataName(fName string) string {
+	return strings.TrimSuffix(fName, filepath.Ext(fName)) + ".meta.json"
+}
+
+// typeStrings converts a list of types to a list of strings.
+func typeStrings(tys []VarType) []string {
+	strs := make([]string, len(tys))
+	for i, ty := range tys {
+		strs[i] = ty.String()
+	}
+	return strs
+}
+
+// parseVarType converts the String representation of a type back to a
+// VarType.
+func parseVarType(s string) VarType {
+	for ty := InfUnknown; ty <= InfStructure; ty++ {
+		if ty.String() == s {
+			return ty
+		}
+	}
+	fatalf("Unrecognized type %q in metadata", s)
+	return InfUnknown // Will never get here
+}
+
+// WriteMetadata writes, as JSON, the metadata needed to decode the solutions
+// to a type-checked program.
+func WriteMetadata(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("WriteMetadata requires a type-checked program")
+	}
+	m := Metadata{
+		Version:      metadataVersion,
+		Program:      p.InFileName,
+		Query:        p.Query,
+		IntBits:      p.IntBits,
+		Signed:       p.Signed,
+		SymBits:      p.SymBits,
+		MaxListLen:   p.MaxListLen,
+		ListLenBits:  p.ListLenBits,
+		FunctorBits:  p.FunctorBits,
+		MaxArity:     p.MaxArity,
+		IntToSym:     p.IntToSym,
+		IntToFunctor: p.IntToFunctor,
+		FunctorTypes: make(map[string][]string, len(p.FunctorTypes)),
+		QueryTypes:   make(map[string]string, len(prog.queryTys)),
+		QueryVars:    prog.queryVars,
+	}
+	for f, tys := range p.FunctorTypes {
+		m.FunctorTypes[f] = typeStrings(tys)
+	}
+	for nm, ty := range prog.queryTys {
+		m.QueryTypes[nm] = ty.String()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// writeMetadataFile writes a program's metadata to the sidecar file that
+// accompanies a given file.
+func (prog *Program) writeMetadataFile(fName string) {
+	mName := MetadataName(fName)
+	mf, err := os.Create(mName)
+	checkError(err)
+	verbosePrintf(prog.Params, "Writing metadata to %s", mName)
+	checkError(WriteMetadata(prog, mf))
+	checkError(mf.Close())
+}
+
+// readMetadata reads the metadata stored in a sidecar file into a set of
+// parameters and returns the types of the query variables and their names in
+// order of appearance.
+func readMetadata(p *Parameters, mName string) (TypeInfo, []string) {
+	data, err := ioutil.ReadFile(mName)
+	checkError(err)
+	var m Metadata
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		fatalf("Failed to parse %s: %s", mName, err)
+	}
+	if m.Version != metadataVersion {
+		fatalf("%s uses metadata version %d, but only version %d is supported", mName, m.Version, metadataVersion)
+	}
+	p.Query = m.Query
+	p.IntBits = m.IntBits
+	p.Signed = m.Signed
+	p.SymBits = m.SymBits
+	p.MaxListLen = m.MaxListLen
+	p.ListLenBits = m.ListLenBits
+	p.FunctorBits = m.FunctorBits
+	p.MaxArity = m.MaxArity
+	p.IntToSym = m.IntToSym
+	p.SymToInt = make(map[string]int, len(m.IntToSym))
+	for i, s := range m.IntToSym {
+		p.SymToInt[s] = i
+	}
+	p.IntToFunctor = m.IntToFunctor
+	p.FunctorToInt = make(map[string]int, len(m.IntToFunctor))
+	for i, f := range m.IntToFunctor {
+		p.FunctorToInt[f] = i
+	}
+	p.FunctorTypes = make(map[string]ArgTypes, len(m.FunctorTypes))
+	for f, strs := range m.FunctorTypes {
+		tys := make(ArgTypes, len(strs))
+		for i, s := range strs {
+			tys[i] = parseVarType(s)
+		}
+		p.FunctorTypes[f] = tys
+	}
+	tys := make(TypeInfo, len(m.QueryTypes))
+	for nm, s := range m.QueryTypes {
+		tys[nm] = parseVarType(s)
+	}
+	verbosePrintf(p, "Read metadata for program %s and query %q from %s", m.Program, m.Query, mName)
+	return tys, m.QueryVars
+}
+
+// copyFile copies a file unless the source and target are the same file.
+func copyFile(src, dst string) {
+	sInfo, err := os.Stat(src)
+	checkError(err)
+	if dInfo, err := os.Stat(dst); err == nil && os.SameFile(sInfo, dInfo) {
+		return
+	}
+	data, err := ioutil.ReadFile(src)
+	checkError(err)
+	err = ioutil.WriteFile(dst, data, 0666)
+	checkError(err)
+}
+
+// Resume prepares a program for solving from a previously generated
+// artifact rather than from Prolog source code.  stage names the artifact's
+// type, which must be "verilog", "edif", or "qmasm", and fName names the
+// artifact itself, which must be accompanied by the metadata file named by
+// MetadataName.  The artifact is copied to p.WorkDir, which is created as by
+// Compile, and compiled to QMASM code.  The resulting program contains no AST so it can be solved only by
+// the "qmasm" and "external" solvers, and its solutions cannot be verified.
+func Resume(p *Parameters, stage, fName string) (prog *Program, err error) {
+	defer recoverError(p, &err)
+	var ext string
+	switch stage {
+	case "verilog":
+		ext = ".v"
+	case "edif":
+		ext = ".edif"
+	case "qmasm":
+		ext = ".qmasm"
+	default:
+		fatalf("Compilation cannot resume from stage %q", stage)
+	}
+	p.InFileName = fName
+	p.OutFileBase = BaseName(fName)
+	prog = &Program{Params: p, typed: true}
+	prog.queryTys, prog.queryVars = readMetadata(p, MetadataName(fName))
+
+	// Copy the artifact and its metadata to the working directory.
+	p.WorkDir, p.DeleteWorkDir = createWorkDir(p)
+	base := filepath.Join(p.WorkDir, p.OutFileBase)
+	copyFile(fName, base+ext)
+	copyFile(MetadataName(fName), base+".meta.json")
+
+	// Compile the artifact to QMASM code.  Verilog code is compiled
+	// through the same cache as Compile uses.
+	cache := &compileCache{p: p}
+	if stage == "verilog" {
+		cache = newCompileCache(p, base+ext)
+		if !cache.fetch(".edif", base+".edif") {
+			createYosysScript(p)
+			verbosePrintf(p, "Converting Verilog code to an EDIF netlist")
+			runCommand(p, "yosys", "-q", "-s", p.OutFileBase+".ys",
+				"-b", "edif", "-o", p.OutFileBase+".edif", p.OutFileBase+".v")
+			cache.store(".edif", base+".edif")
+		}
+	}
+	if stage != "qmasm" && !cache.fetch(".qmasm", base+".qmasm") {
+		verbosePrintf(p, "Converting the EDIF netlist to QMASM code")
+		runCommand(p, "edif2qmasm", "-o", p.OutFileBase+".qmasm", p.OutFileBase+".edif")
+		cache.store(".qmasm", base+".qmasm")
+	}
+	prog.qmasmFile, err = filepath.Abs(base + ".qmasm")
+	checkError(err)
+	return prog, nil
+}
+
+// needSource aborts if a program was not parsed from Prolog source code.
+func (prog *Program) needSource(what string) {
+	if prog.AST == nil {
+		fatalf("%s requires Prolog source code, not a program resumed from %s", what, prog.Params.InFileName)
+	}
+}
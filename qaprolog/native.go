@@ -0,0 +1,773 @@
+// Lower an AST directly to a gate-level netlist, bypassing Verilog.
+
+package qaprolog
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// A circuitBuilder lowers clause groups to logic in a netlist.  Every
+// invocation of a clause group produces a separate copy of its logic.
+type circuitBuilder struct {
+	p        *Parameters           // Global parameters
+	nl       *Netlist              // Netlist under construction
+	nm2tys   map[string]ArgTypes   // Argument types of each clause group
+	clVarTys map[*ASTNode]TypeInfo // Variable types of each clause
+}
+
+// BuildNetlist lowers an entire (preprocessed) AST to a netlist.  The query's
+// arguments become ports named "Query.<var>[<bit>]", and the query's validity
+// bit becomes a port named "Query.Valid".
+func (a *ASTNode) BuildNetlist(p *Parameters, nm2tys map[string]ArgTypes,
+	clVarTys map[*ASTNode]TypeInfo) *Netlist {
+	b := &circuitBuilder{
+		p:        p,
+		nl:       NewNetlist(),
+		nm2tys:   nm2tys,
+		clVarTys: clVarTys,
+	}
+	q := a.FindByType(QueryType)[0]
+	nm := q.Value.(string)
+	_, vArgs := q.args()
+	tys := nm2tys[nm]
+	args := make([]Bits, len(vArgs))
+	for i, v := range vArgs {
+		args[i] = b.nl.NewBits(p.argBits(nm, i, tys[i]))
+		for j, n := range args[i] {
+			b.nl.Ports[portName(v, uint(j))] = n
+		}
+	}
+	b.nl.Ports["Query.Valid"] = b.instantiate(nm, args)
+	return b.nl
+}
+
+// portName returns the name of a given bit of a query variable.
+func portName(v string, i uint) string {
+	return "Query." + v + "[" + strconv.Itoa(int(i)) + "]"
+}
+
+// instantiate returns a net indicating whether any clause in a clause group
+// holds for the given arguments.  As with Verilog module ports, each
+// argument is first resized to the clause group's argument width.
+func (b *circuitBuilder) instantiate(nm string, args []Bits) Net {
+	// The lowest level of a recursive clause group always fails.
+	if _, lvl, ok := splitLevel(nm); ok && lvl == 0 {
+		return NetFalse
+	}
+	cls, ok := b.p.TopLevel[nm]
+	if !ok {
+		fatalf("Internal error: Failed to find clause %s", nm)
+	}
+	tys := b.nm2tys[nm]
+	for i, v := range args {
+		args[i] = b.resize(v, b.p.argBits(nm, i, tys[i]), tys[i])
+	}
+	alts := make([]Net, len(cls))
+	for i, cl := range cls {
+		alts[i] = b.clauseValid(nm, cl, args, tys, b.clVarTys[cl])
+	}
+	return b.nl.OrAll(alts)
+}
+
+// clauseValid returns a net indicating whether a clause in clause group nm
+// holds for the given arguments.  It mirrors writeClauseBody.
+func (b *circuitBuilder) clauseValid(nm string, cl *ASTNode, args []Bits, tys ArgTypes, vTy TypeInfo) Net {
+	// Map Prolog variables to vectors of nets.  As we go along, constrain
+	// all variables with the same Prolog name to have the same value.
+	p := b.p
+	nl := b.nl
+	valid := make([]Net, 0, 16)
+	pArgs, _ := cl.args()
+	p2n := make(map[string]Bits, len(pArgs))
+	rebound := make([]int, 0, len(pArgs))
+	for i, pa := range pArgs {
+		v, seen := p2n[pa]
+		switch {
+		case seen:
+			valid = append(valid, b.equal(args[i], v, vTy[pa]))
+		case p.rebound(nm, cl, i, vTy):
+			rebound = append(rebound, i)
+		default:
+			p2n[pa] = args[i]
+		}
+	}
+
+	// Map variables that appear within list or structure arguments to
+	// portions of those arguments.
+	terms := cl.Children[0].Children[1:]
+	for i, t := range terms {
+		switch c := t.Children[0]; c.Type {
+		case ListType:
+			valid = append(valid, b.bindListArg(c, args[i], tys[i], p2n)...)
+		case StructureType:
+			valid = append(valid, b.bindStructArg(c, args[i], p2n, vTy)...)
+		}
+	}
+
+	// Ensure that lists and structures passed to the query are well
+	// formed.
+	if cl.Type == QueryType {
+		for i, v := range args {
+			valid = append(valid, b.canonical(v, tys[i]))
+		}
+	}
+
+	// Introduce more vectors for local Prolog variables.
+	for _, pv := range cl.FindByType(VariableType) {
+		pName := pv.Text
+		if _, seen := p2n[pName]; seen {
+			continue
+		}
+		v := nl.NewBits(p.varBits(cl, pName, vTy[pName]))
+		valid = append(valid, b.canonical(v, vTy[pName]))
+		p2n[pName] = v
+	}
+
+	// Copy arguments to variables of a different width.
+	for _, i := range rebound {
+		valid = append(valid, b.equal(args[i], p2n[pArgs[i]], InfNumeral))
+	}
+
+	// Compare numeral and atom arguments to their expected values.
+	for i, pa := range pArgs {
+		if t := terms[i].Children[0].Type; t == ListType || t == StructureType {
+			continue // Handled by bindListArg or bindStructArg
+		}
+		r0 := rune(pa[0])
+		switch {
+		case unicode.IsLower(r0):
+			valid = append(valid, nl.Equal(args[i], b.expr(terms[i], p2n, vTy)))
+		case unicode.IsDigit(r0), r0 == '-':
+			valid = append(valid, b.equal(args[i], b.expr(terms[i], p2n, vTy), InfNumeral))
+		}
+	}
+
+	// Convert the clause body to a list of nets.
+	valid = append(valid, b.goalConditions(cl.Children[1:], p2n, vTy)...)
+	return nl.AndAll(valid)
+}
+
+// canonical returns a net indicating whether a list or structure is well
+// formed.  It returns NetTrue for all other types.  It mirrors listCanonical
+// and structCanonical.
+func (b *circuitBuilder) canonical(v Bits, ty VarType) Net {
+	p := b.p
+	nl := b.nl
+	switch {
+	case ty.IsList():
+		eBits := p.typeBits(ty.ElemType())
+		lenBits := b.listLength(v, ty)
+		terms := make([]Net, 0, p.MaxListLen+1)
+		if 1<<p.ListLenBits-1 > p.MaxListLen {
+			terms = append(terms, nl.Not(nl.Less(Const(p.ListLenBits, int(p.MaxListLen)), lenBits)))
+		}
+		for i := uint(0); i < p.MaxListLen; i++ {
+			elt := v[i*eBits : (i+1)*eBits]
+			terms = append(terms, nl.Or(nl.Less(Const(p.ListLenBits, int(i)), lenBits),
+				nl.Equal(elt, Const(eBits, 0))))
+		}
+		return nl.AndAll(terms)
+
+	case ty == InfStructure:
+		fBits := p.fieldBits()
+		tag := v[p.MaxArity*fBits:]
+		alts := make([]Net, len(p.IntToFunctor))
+		for t, f := range p.IntToFunctor {
+			conds := []Net{nl.Equal(tag, Const(p.FunctorBits, t))}
+			tys := p.FunctorTypes[f]
+			for i := uint(0); i < p.MaxArity; i++ {
+				used := uint(0)
+				if i < uint(len(tys)) {
+					used = p.typeBits(tys[i])
+				}
+				if used < fBits {
+					conds = append(conds, nl.Equal(v[i*fBits+used:(i+1)*fBits], Const(fBits-used, 0)))
+				}
+			}
+			alts[t] = nl.AndAll(conds)
+		}
+		return nl.OrAll(alts)
+
+	default:
+		return NetTrue
+	}
+}
+
+// listLength returns the length field of a list.
+func (b *circuitBuilder) listLength(v Bits, ty VarType) Bits {
+	lo := b.p.MaxListLen * b.p.typeBits(ty.ElemType())
+	return v[lo : lo+b.p.ListLenBits]
+}
+
+// bindListArg matches a list-valued clause argument against the list that
+// appears in the clause's head.  It mirrors the Verilog version of
+// bindListArg.
+func (b *circuitBuilder) bindListArg(l *ASTNode, v Bits, ty VarType, p2n map[string]Bits) []Net {
+	// Constrain the length of the list.
+	p := b.p
+	nl := b.nl
+	elts, tail := l.listParts()
+	nElts := uint(len(elts))
+	lenBits := b.listLength(v, ty)
+	valid := make([]Net, 0, nElts+1)
+	switch {
+	case tail == nil:
+		valid = append(valid, nl.Equal(lenBits, Const(p.ListLenBits, int(nElts))))
+	case nElts > 0:
+		valid = append(valid, nl.Not(nl.Less(lenBits, Const(p.ListLenBits, int(nElts)))))
+	}
+
+	// Map each variable element to a slice of the list, and compare each
+	// numeral or atom element to the corresponding slice.
+	eBits := p.typeBits(ty.ElemType())
+	for i, e := range elts {
+		elt := v[uint(i)*eBits : uint(i+1)*eBits]
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, nl.Equal(elt, b.expr(c, p2n, nil)))
+			continue
+		}
+		if pv, seen := p2n[c.Value.(string)]; seen {
+			valid = append(valid, nl.Equal(elt, pv))
+		} else {
+			p2n[c.Value.(string)] = elt
+		}
+	}
+	if tail == nil {
+		return valid
+	}
+
+	// Construct the list's tail.
+	tBits := Concat(v[nElts*eBits:p.MaxListLen*eBits],
+		Const(nElts*eBits, 0),
+		nl.Sub(lenBits, Const(p.ListLenBits, int(nElts))))
+	if pv, seen := p2n[tail.Value.(string)]; seen {
+		valid = append(valid, nl.Equal(tBits, pv))
+	} else {
+		p2n[tail.Value.(string)] = tBits
+	}
+	return valid
+}
+
+// bindStructArg matches a structure-valued clause argument against the
+// structure that appears in the clause's head.  It mirrors the Verilog version
+// of bindStructArg.
+func (b *circuitBuilder) bindStructArg(s *ASTNode, v Bits, p2n map[string]Bits, vTy TypeInfo) []Net {
+	// Constrain the functor.
+	p := b.p
+	nl := b.nl
+	args := s.Children[1:]
+	fBits := p.fieldBits()
+	valid := make([]Net, 0, len(args)+1)
+	tag := v[p.MaxArity*fBits:]
+	valid = append(valid, nl.Equal(tag, Const(p.FunctorBits, p.FunctorToInt[s.functorName()])))
+
+	// Map each variable argument to a field of the structure, and compare
+	// each numeral or atom argument to the corresponding field.
+	tys := p.FunctorTypes[s.functorName()]
+	for i, e := range args {
+		lo := uint(i) * fBits
+		field := v[lo : lo+p.typeBits(tys[i])]
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, nl.Equal(field, b.expr(c, p2n, vTy)))
+			continue
+		}
+		if pv, seen := p2n[c.Value.(string)]; seen {
+			valid = append(valid, nl.Equal(field, pv))
+		} else {
+			p2n[c.Value.(string)] = field
+		}
+	}
+	return valid
+}
+
+// goalConditions converts a list of predicates to a list of nets.  It mirrors
+// the Verilog version of goalConditions.
+func (b *circuitBuilder) goalConditions(goals []*ASTNode, p2n map[string]Bits, tys TypeInfo) []Net {
+	valid := make([]Net, 0, len(goals))
+	for _, pred := range goals {
+		valid = append(valid, b.listSideConditions(pred, p2n, tys)...)
+		valid = append(valid, b.cond(pred, p2n, tys))
+	}
+	return valid
+}
+
+// listSideConditions returns a list of nets that must be true for the lists
+// constructed by a predicate to fit within MaxListLen elements.  It mirrors
+// the Verilog version of listSideConditions.
+func (b *circuitBuilder) listSideConditions(a *ASTNode, p2n map[string]Bits, tys TypeInfo) []Net {
+	if a.Type == PredicateType {
+		switch a.Children[0].Type {
+		case NegationType, DisjunctionType:
+			return nil
+		}
+	}
+	p := b.p
+	conds := make([]Net, 0)
+	for _, l := range a.FindByType(ListType) {
+		elts, tail := l.listParts()
+		if tail == nil {
+			continue
+		}
+		ty, err := l.termType(tys, p.FunctorTypes)
+		checkError(err)
+		lenBits := b.listLength(b.expr(tail, p2n, tys), ty)
+		maxLen := Const(p.ListLenBits, int(p.MaxListLen-uint(len(elts))))
+		conds = append(conds, b.nl.Not(b.nl.Less(maxLen, lenBits)))
+	}
+	return conds
+}
+
+// cond converts a predicate or relation to a single net.  It mirrors the
+// Boolean cases of toVerilogExpr.
+func (b *circuitBuilder) cond(a *ASTNode, p2n map[string]Bits, tys TypeInfo) Net {
+	nl := b.nl
+	switch a.Type {
+	case PredicateType:
+		// Handle predicate AST nodes that are really just wrappers.
+		if len(a.Children) == 1 {
+			c := a.Children[0]
+			if c.Type == AtomType {
+				// Built-in predicates with no arguments
+				switch c.Value.(string) {
+				case "true":
+					return NetTrue
+				case "fail", "false":
+					return NetFalse
+				}
+				fatalf("Internal error: Unexpected predicate %s/0", c.Value)
+			}
+			return b.cond(c, p2n, tys)
+		}
+
+		// Ignore atom/1 and integer/1, which exist solely for the type
+		// system.
+		if len(a.Children) == 2 {
+			pName := a.Children[0].Value.(string)
+			if pName == "atom" || pName == "integer" {
+				return NetTrue
+			}
+		}
+
+		// Instantiate the clause group the predicate refers to.
+		args := make([]Bits, len(a.Children)-1)
+		for i, c := range a.Children[1:] {
+			args[i] = b.expr(c, p2n, tys)
+		}
+		return b.instantiate(a.calleeName(b.p), args)
+
+	case NegationType:
+		g := a.Children[0]
+		conds := append(b.listSideConditions(g, p2n, tys), b.cond(g, p2n, tys))
+		return nl.Not(nl.AndAll(conds))
+
+	case DisjunctionType:
+		// Each branch is guarded by the negation of the conditions of
+		// all preceding if-then branches.
+		alts := make([]Net, len(a.Children))
+		guards := make([]Net, 0, len(a.Children))
+		for i, br := range a.Children {
+			goals := br.Children
+			items := append([]Net{}, guards...)
+			if br.Type == IfThenType {
+				c := nl.AndAll(b.goalConditions(br.Children[0].Children, p2n, tys))
+				items = append(items, c)
+				guards = append(guards, nl.Not(c))
+				goals = br.Children[1].Children
+			}
+			items = append(items, b.goalConditions(goals, p2n, tys)...)
+			alts[i] = nl.AndAll(items)
+		}
+		return nl.OrAll(alts)
+
+	case RelationType:
+		return b.relation(a, p2n, tys)
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return NetFalse // We should never get here.
+}
+
+// relation converts a relation to a single net.
+func (b *circuitBuilder) relation(a *ASTNode, p2n map[string]Bits, tys TypeInfo) Net {
+	nl := b.nl
+	op := a.Value.(string)
+	e1, e2 := a.Children[0], a.Children[2]
+
+	// Lower equality and inequality between two structures to
+	// argument-wise equality, as in structRelation.
+	if e1.Type == TermType && e2.Type == TermType {
+		s1, s2 := e1.Children[0], e2.Children[0]
+		if s1.Type == StructureType && s2.Type == StructureType {
+			eq := NetFalse
+			if s1.functorName() == s2.functorName() {
+				eqs := make([]Net, len(s1.Children)-1)
+				for i, c1 := range s1.Children[1:] {
+					eqs[i] = nl.Equal(b.expr(c1, p2n, tys), b.expr(s2.Children[i+1], p2n, tys))
+				}
+				eq = nl.AndAll(eqs)
+			}
+			if op == "=" {
+				return eq
+			}
+			return nl.Not(eq)
+		}
+	}
+
+	// Compare two expressions.  As in Verilog, every operand is first
+	// extended to the width of the widest operand or, if the relation's
+	// arithmetic could overflow, to a wider width and, if integers are
+	// unsigned, biased to make every value nonnegative.
+	w := b.width(e1, p2n, tys)
+	if w2 := b.width(e2, p2n, tys); w2 > w {
+		w = w2
+	}
+	rw, widen := b.p.RelWidths[a]
+	if widen && rw.Bits > w {
+		w = rw.Bits
+	}
+	v1 := b.arith(e1, p2n, tys, w)
+	v2 := b.arith(e2, p2n, tys, w)
+	if widen && rw.Bias != 0 {
+		v1 = nl.Add(v1, Const(w, rw.Bias))
+		v2 = nl.Add(v2, Const(w, rw.Bias))
+	}
+	var cmp Net
+	switch op {
+	case "=", "is":
+		cmp = nl.Equal(v1, v2)
+	case "\\=":
+		cmp = nl.Not(nl.Equal(v1, v2))
+	case "<":
+		cmp = b.less(v1, v2)
+	case ">":
+		cmp = b.less(v2, v1)
+	case "=<":
+		cmp = nl.Not(b.less(v2, v1))
+	case ">=":
+		cmp = nl.Not(b.less(v1, v2))
+	default:
+		fatalf("Internal error: Failed to convert %s %q to a netlist", a.Type, op)
+	}
+	return nl.And(cmp, b.arithDefined(a, p2n, tys))
+}
+
+// arithDefined returns a net indicating whether every arithmetic operation in
+// a relation is defined: No division, modulo, or remainder operation has a
+// zero divisor, no shift operation has a negative shift amount, and every
+// operation that p.ArithChecks says to check produces a value that fits in
+// IntBits bits.  It mirrors the Verilog version of arithDefined.
+func (b *circuitBuilder) arithDefined(a *ASTNode, p2n map[string]Bits, tys TypeInfo) Net {
+	nl := b.nl
+	top := b.p.storageRange(b.p.IntBits)
+	oks := make([]Net, 0, 4)
+	for _, e := range divisionNodes(a) {
+		dw := b.p.divWidth(e)
+		v2 := b.arith(e.Children[2], p2n, tys, dw.Bits)
+		switch op := e.Children[1].Value.(string); {
+		case op != "<<" && op != ">>":
+			oks = append(oks, nl.Not(nl.Equal(v2, Const(dw.Bits, 0))))
+		case dw.Signed:
+			oks = append(oks, nl.Not(v2[dw.Bits-1]))
+		}
+	}
+	for _, e := range arithNodes(a) {
+		ck, ok := b.p.ArithChecks[e]
+		if !ok {
+			continue
+		}
+		w := b.width(e, p2n, tys)
+		if ck.Bits > w {
+			w = ck.Bits
+		}
+		v := b.arith(e, p2n, tys, w)
+		if ck.Bias != 0 {
+			v = nl.Add(v, Const(w, ck.Bias))
+		}
+		if ck.Bias != 0 || b.p.Signed {
+			oks = append(oks, nl.Not(b.less(v, Const(w, ck.Bias+int(top.Lo)))))
+		}
+		oks = append(oks, nl.Not(b.less(Const(w, ck.Bias+int(top.Hi)), v)))
+	}
+	return nl.AndAll(oks)
+}
+
+// expr converts a term or arithmetic expression to a vector of nets.  It
+// mirrors the value-producing cases of toVerilogExpr.
+func (b *circuitBuilder) expr(a *ASTNode, p2n map[string]Bits, tys TypeInfo) Bits {
+	p := b.p
+	nl := b.nl
+	switch a.Type {
+	case NumeralType:
+		return Const(p.IntBits, a.Value.(int))
+
+	case AtomType:
+		return Const(p.SymBits, p.SymToInt[a.Value.(string)])
+
+	case VariableType:
+		v, ok := p2n[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert variable %s to a netlist", a.Value.(string))
+		}
+		return v
+
+	case TermType, PrimaryExprType:
+		if isFunction(a) {
+			return b.arith(a, p2n, tys, b.width(a, p2n, tys))
+		}
+		return b.expr(a.Children[0], p2n, tys)
+
+	case UnaryExprType, AdditiveExprType, MultiplicativeExprType:
+		return b.arith(a, p2n, tys, b.width(a, p2n, tys))
+
+	case ListType:
+		// Concatenate the list's elements, its tail (if any), and its
+		// length.
+		ty, err := a.termType(tys, p.FunctorTypes)
+		checkError(err)
+		elts, tail := a.listParts()
+		nElts := uint(len(elts))
+		eBits := p.typeBits(ty.ElemType())
+		cs := make([]Bits, 0, len(elts)+2)
+		for _, e := range elts {
+			cs = append(cs, b.expr(e, p2n, tys).Resize(eBits))
+		}
+		if tail == nil {
+			cs = append(cs, Const((p.MaxListLen-nElts)*eBits, 0))
+			cs = append(cs, Const(p.ListLenBits, int(nElts)))
+		} else {
+			tBits := b.expr(tail, p2n, tys)
+			cs = append(cs, tBits[:(p.MaxListLen-nElts)*eBits])
+			cs = append(cs, nl.Add(b.listLength(tBits, ty), Const(p.ListLenBits, int(nElts))))
+		}
+		return Concat(cs...)
+
+	case ListTailType:
+		return b.expr(a.Children[0], p2n, tys)
+
+	case StructureType:
+		// Concatenate the structure's arguments, each zero-extended
+		// to fill a field, and its functor tag.
+		args := a.Children[1:]
+		fBits := p.fieldBits()
+		cs := make([]Bits, 0, len(args)+2)
+		for _, c := range args {
+			cs = append(cs, b.expr(c, p2n, tys).Resize(fBits))
+		}
+		cs = append(cs, Const((p.MaxArity-uint(len(args)))*fBits, 0))
+		cs = append(cs, Const(p.FunctorBits, p.FunctorToInt[a.functorName()]))
+		return Concat(cs...)
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return nil // We should never get here.
+}
+
+// width returns the width of the widest operand of an expression.
+func (b *circuitBuilder) width(a *ASTNode, p2n map[string]Bits, tys TypeInfo) uint {
+	p := b.p
+	switch a.Type {
+	case NumeralType:
+		return p.IntBits
+
+	case AtomType:
+		return p.SymBits
+
+	case VariableType:
+		return uint(len(p2n[a.Value.(string)]))
+
+	case TermType, PrimaryExprType, ListTailType:
+		if isFunction(a) {
+			return p.divWidth(a).Bits
+		}
+		return b.width(a.Children[0], p2n, tys)
+
+	case UnaryExprType, AdditiveExprType, MultiplicativeExprType:
+		if isDivision(a) {
+			return p.divWidth(a).Bits
+		}
+		w := uint(0)
+		for _, c := range a.Children {
+			if cw := b.width(c, p2n, tys); cw > w {
+				w = cw
+			}
+		}
+		return w
+
+	case ListType, StructureType:
+		ty, err := a.termType(tys, p.FunctorTypes)
+		checkError(err)
+		return p.typeBits(ty)
+
+	default:
+		return 0 // Operator
+	}
+}
+
+// arith converts an arithmetic expression to a vector of nets of a given
+// width.  As in Verilog, every operand is extended to that width before any
+// arithmetic is performed.
+func (b *circuitBuilder) arith(a *ASTNode, p2n map[string]Bits, tys TypeInfo, w uint) Bits {
+	nl := b.nl
+	switch a.Type {
+	case TermType, PrimaryExprType:
+		if isFunction(a) {
+			return b.function(a, p2n, tys, w)
+		}
+		return b.arith(a.Children[0], p2n, tys, w)
+
+	case UnaryExprType:
+		if len(a.Children) == 1 {
+			return b.arith(a.Children[0], p2n, tys, w)
+		}
+		v := b.arith(a.Children[1], p2n, tys, w)
+		if a.Children[0].Value.(string) == "\\" {
+			return nl.Invert(v)
+		}
+		return nl.Neg(v)
+
+	case AdditiveExprType, MultiplicativeExprType:
+		if len(a.Children) == 1 {
+			return b.arith(a.Children[0], p2n, tys, w)
+		}
+		if isDivision(a) {
+			return b.divide(a, p2n, tys, w)
+		}
+		v1 := b.arith(a.Children[0], p2n, tys, w)
+		v2 := b.arith(a.Children[2], p2n, tys, w)
+		switch op := a.Children[1].Value.(string); op {
+		case "+":
+			return nl.Add(v1, v2)
+		case "-":
+			return nl.Sub(v1, v2)
+		case "*":
+			return nl.Mul(v1, v2)
+		case "/\\":
+			return nl.AndBits(v1, v2)
+		case "\\/":
+			return nl.OrBits(v1, v2)
+		case "xor":
+			return nl.XorBits(v1, v2)
+		default:
+			fatalf("Internal error: Failed to convert %s %q to a netlist", a.Type, op)
+		}
+	}
+	v := b.expr(a, p2n, tys)
+	if intOperand(a, tys) {
+		return b.resize(v, w, InfNumeral)
+	}
+	return v.Resize(w)
+}
+
+// divide converts a division, modulo, remainder, or shift operation to a
+// vector of nets of a given width.  The operation is performed on operands of
+// the width p.DivWidths specifies, and the result is then extended to the
+// given width.  It mirrors divideExpr.
+func (b *circuitBuilder) divide(a *ASTNode, p2n map[string]Bits, tys TypeInfo, w uint) Bits {
+	nl := b.nl
+	dw := b.p.divWidth(a)
+	v1 := b.arith(a.Children[0], p2n, tys, dw.Bits)
+	v2 := b.arith(a.Children[2], p2n, tys, dw.Bits)
+	op := a.Children[1].Value.(string)
+	switch {
+	case op == "<<":
+		v := nl.ShiftLeft(v1, v2)
+		if dw.Signed {
+			return v.SignExtend(w)
+		}
+		return v.Resize(w)
+	case op == ">>" && dw.Signed:
+		return nl.SignedShiftRight(v1, v2).SignExtend(w)
+	case op == ">>":
+		return nl.ShiftRight(v1, v2).Resize(w)
+	case !dw.Signed:
+		q, r := nl.DivMod(v1, v2)
+		if op == "mod" || op == "rem" {
+			return r.Resize(w)
+		}
+		return q.Resize(w)
+	}
+	var v Bits
+	switch op {
+	case "mod":
+		v = nl.SignedMod(v1, v2)
+	case "rem":
+		_, v = nl.SignedDivRem(v1, v2)
+	default:
+		v, _ = nl.SignedDivRem(v1, v2)
+	}
+	return v.SignExtend(w)
+}
+
+// function converts an application of an evaluable function to a vector of
+// nets of a given width.  As in divide, the function is applied to arguments
+// of the width p.DivWidths specifies, and the result is then extended to the
+// given width.  It mirrors functionExpr.
+func (b *circuitBuilder) function(a *ASTNode, p2n map[string]Bits, tys TypeInfo, w uint) Bits {
+	nl := b.nl
+	dw := b.p.divWidth(a)
+	args := make([]Bits, len(a.Children)-1)
+	for i, c := range a.Children[1:] {
+		args[i] = b.arith(c, p2n, tys, dw.Bits)
+	}
+	less := nl.Less
+	if dw.Signed {
+		less = nl.SignedLess
+	}
+	neg := NetFalse
+	if dw.Signed {
+		neg = args[0][dw.Bits-1]
+	}
+	var v Bits
+	switch fn := a.Value.(string); fn {
+	case "abs":
+		v = args[0]
+		if dw.Signed {
+			v = nl.Mux(neg, v, nl.Neg(v))
+		}
+	case "sign":
+		zero := Const(dw.Bits, 0)
+		v = nl.Mux(nl.Equal(args[0], zero), Const(dw.Bits, 1), zero)
+		v = nl.Mux(neg, v, Const(dw.Bits, -1))
+	case "min":
+		v = nl.Mux(less(args[1], args[0]), args[0], args[1])
+	case "max":
+		v = nl.Mux(less(args[0], args[1]), args[0], args[1])
+	default:
+		fatalf("Internal error: Failed to convert function %q to a netlist", fn)
+	}
+	if dw.Signed {
+		return v.SignExtend(w)
+	}
+	return v.Resize(w)
+}
+
+// resize zero-extends or truncates a vector to a given width.  Integers are
+// instead sign-extended if they are signed.
+func (b *circuitBuilder) resize(v Bits, w uint, ty VarType) Bits {
+	if b.p.Signed && ty == InfNumeral {
+		return v.SignExtend(w)
+	}
+	return v.Resize(w)
+}
+
+// equal returns a net indicating whether two vectors of a given type are
+// equal.  Signed integers of different widths are sign-extended to the
+// wider of the two.
+func (b *circuitBuilder) equal(v1, v2 Bits, ty VarType) Net {
+	w := maxWidth(v1, v2)
+	return b.nl.Equal(b.resize(v1, w, ty), b.resize(v2, w, ty))
+}
+
+// less returns a net indicating whether one integer is less than another,
+// honoring p.Signed.
+func (b *circuitBuilder) less(v1, v2 Bits) Net {
+	if b.p.Signed {
+		return b.nl.SignedLess(v1, v2)
+	}
+	return b.nl.Less(v1, v2)
+}
@@ -0,0 +1,629 @@
+// Represent a program as a gate-level netlist and convert the netlist to an
+// Ising Hamiltonian.
+
+package qaprolog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Net is a single Boolean signal in a netlist.
+type Net int
+
+// Two nets are reserved for the Boolean constants.
+const (
+	NetFalse Net = iota // Always false
+	NetTrue             // Always true
+)
+
+// A GateType is a type of logic gate.
+type GateType int
+
+// We define the following gate types.
+const (
+	NotGate GateType = iota // Y = ~A
+	AndGate                 // Y = A & B
+	OrGate                  // Y = A | B
+	XorGate                 // Y = A ^ B
+)
+
+// A Gate is a single logic gate.
+type Gate struct {
+	Type GateType // Type of gate
+	In   [2]Net   // Inputs (only In[0] is used by NotGate)
+	Out  Net      // Output
+	Aux  Net      // Ancillary net (used only by XorGate)
+}
+
+// A Netlist is a collection of gates and named nets.
+type Netlist struct {
+	NumNets int            // Number of nets, including the two constants
+	Gates   []Gate         // All gates in the netlist
+	Ports   map[string]Net // Map from a port name (e.g., "Query.X[0]") to a net
+	hash    map[Gate]Net   // Map from a gate's type and inputs to its output
+}
+
+// NewNetlist returns an empty netlist.
+func NewNetlist() *Netlist {
+	return &Netlist{
+		NumNets: 2,
+		Gates:   make([]Gate, 0, 1024),
+		Ports:   make(map[string]Net),
+		hash:    make(map[Gate]Net),
+	}
+}
+
+// NewNet allocates a new, unconstrained net.
+func (n *Netlist) NewNet() Net {
+	n.NumNets++
+	return Net(n.NumNets - 1)
+}
+
+// gate returns the output of a gate, reusing an existing gate with the same
+// type and inputs if one exists.
+func (n *Netlist) gate(t GateType, a, b Net) Net {
+	if t != NotGate && a > b {
+		a, b = b, a
+	}
+	key := Gate{Type: t, In: [2]Net{a, b}}
+	if y, ok := n.hash[key]; ok {
+		return y
+	}
+	g := Gate{Type: t, In: key.In, Out: n.NewNet()}
+	if t == XorGate {
+		g.Aux = n.NewNet()
+	}
+	n.hash[key] = g.Out
+	n.Gates = append(n.Gates, g)
+	return g.Out
+}
+
+// Not returns the complement of a net.
+func (n *Netlist) Not(a Net) Net {
+	switch a {
+	case NetFalse:
+		return NetTrue
+	case NetTrue:
+		return NetFalse
+	}
+	return n.gate(NotGate, a, NetFalse)
+}
+
+// And returns the conjunction of two nets.
+func (n *Netlist) And(a, b Net) Net {
+	switch {
+	case a == NetFalse || b == NetFalse:
+		return NetFalse
+	case a == NetTrue:
+		return b
+	case b == NetTrue, a == b:
+		return a
+	}
+	return n.gate(AndGate, a, b)
+}
+
+// Or returns the disjunction of two nets.
+func (n *Netlist) Or(a, b Net) Net {
+	switch {
+	case a == NetTrue || b == NetTrue:
+		return NetTrue
+	case a == NetFalse:
+		return b
+	case b == NetFalse, a == b:
+		return a
+	}
+	return n.gate(OrGate, a, b)
+}
+
+// Xor returns the exclusive or of two nets.
+func (n *Netlist) Xor(a, b Net) Net {
+	switch {
+	case a == b:
+		return NetFalse
+	case a == NetFalse:
+		return b
+	case b == NetFalse:
+		return a
+	case a == NetTrue:
+		return n.Not(b)
+	case b == NetTrue:
+		return n.Not(a)
+	}
+	return n.gate(XorGate, a, b)
+}
+
+// AndAll returns the conjunction of a list of nets.
+func (n *Netlist) AndAll(as []Net) Net {
+	y := NetTrue
+	for _, a := range as {
+		y = n.And(y, a)
+	}
+	return y
+}
+
+// OrAll returns the disjunction of a list of nets.
+func (n *Netlist) OrAll(as []Net) Net {
+	y := NetFalse
+	for _, a := range as {
+		y = n.Or(y, a)
+	}
+	return y
+}
+
+// Bits is a vector of nets, least-significant bit first.
+type Bits []Net
+
+// NewBits allocates a vector of new, unconstrained nets.
+func (n *Netlist) NewBits(w uint) Bits {
+	b := make(Bits, w)
+	for i := range b {
+		b[i] = n.NewNet()
+	}
+	return b
+}
+
+// Const returns a vector of constant nets representing a given value.  The
+// value is truncated to the given width, so negative values are represented
+// in two's-complement form.
+func Const(w uint, v int) Bits {
+	b := make(Bits, w)
+	for i := range b {
+		b[i] = NetFalse
+		if (v>>uint(i))&1 == 1 {
+			b[i] = NetTrue
+		}
+	}
+	return b
+}
+
+// Resize zero-extends or truncates a vector to a given width.
+func (b Bits) Resize(w uint) Bits {
+	if uint(len(b)) >= w {
+		return b[:w]
+	}
+	return append(append(Bits{}, b...), Const(w-uint(len(b)), 0)...)
+}
+
+// SignExtend sign-extends or truncates a vector to a given width.
+func (b Bits) SignExtend(w uint) Bits {
+	if uint(len(b)) >= w || len(b) == 0 {
+		return b.Resize(w)
+	}
+	ext := append(Bits{}, b...)
+	for uint(len(ext)) < w {
+		ext = append(ext, b[len(b)-1])
+	}
+	return ext
+}
+
+// Concat concatenates vectors, with the first vector in the least-significant
+// position.
+func Concat(bs ...Bits) Bits {
+	c := make(Bits, 0, 32)
+	for _, b := range bs {
+		c = append(c, b...)
+	}
+	return c
+}
+
+// maxWidth returns the larger of the widths of two vectors.
+func maxWidth(a, b Bits) uint {
+	if len(a) > len(b) {
+		return uint(len(a))
+	}
+	return uint(len(b))
+}
+
+// Add returns the sum of two vectors, truncated to the wider of the two.
+func (n *Netlist) Add(a, b Bits) Bits {
+	s, _ := n.addCarry(a, b, NetFalse)
+	return s
+}
+
+// addCarry returns the sum of two vectors and a carry-in bit as well as the
+// carry out of the most significant bit.
+func (n *Netlist) addCarry(a, b Bits, c Net) (Bits, Net) {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w)
+	s := make(Bits, w)
+	for i := range s {
+		ab := n.Xor(a[i], b[i])
+		s[i] = n.Xor(ab, c)
+		c = n.Or(n.And(a[i], b[i]), n.And(ab, c))
+	}
+	return s, c
+}
+
+// Invert returns the bitwise complement of a vector.
+func (n *Netlist) Invert(a Bits) Bits {
+	b := make(Bits, len(a))
+	for i, x := range a {
+		b[i] = n.Not(x)
+	}
+	return b
+}
+
+// bitwise applies a two-input gate to corresponding bits of two vectors,
+// both extended to the wider of the two.
+func (n *Netlist) bitwise(gate func(a, b Net) Net, a, b Bits) Bits {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w)
+	y := make(Bits, w)
+	for i := range y {
+		y[i] = gate(a[i], b[i])
+	}
+	return y
+}
+
+// AndBits returns the bitwise conjunction of two vectors.
+func (n *Netlist) AndBits(a, b Bits) Bits {
+	return n.bitwise(n.And, a, b)
+}
+
+// OrBits returns the bitwise disjunction of two vectors.
+func (n *Netlist) OrBits(a, b Bits) Bits {
+	return n.bitwise(n.Or, a, b)
+}
+
+// XorBits returns the bitwise exclusive or of two vectors.
+func (n *Netlist) XorBits(a, b Bits) Bits {
+	return n.bitwise(n.Xor, a, b)
+}
+
+// Sub returns the difference of two vectors, truncated to the wider of the
+// two.
+func (n *Netlist) Sub(a, b Bits) Bits {
+	w := maxWidth(a, b)
+	d, _ := n.addCarry(a.Resize(w), n.Invert(b.Resize(w)), NetTrue)
+	return d
+}
+
+// Neg returns the two's-complement negation of a vector.
+func (n *Netlist) Neg(a Bits) Bits {
+	return n.Sub(Const(uint(len(a)), 0), a)
+}
+
+// Mul returns the product of two vectors, truncated to the wider of the two.
+func (n *Netlist) Mul(a, b Bits) Bits {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w)
+	prod := Const(w, 0)
+	for i := uint(0); i < w; i++ {
+		// Add a shifted, masked copy of a to the product.
+		pp := Const(w, 0)
+		for j := uint(0); j+i < w; j++ {
+			pp[j+i] = n.And(a[j], b[i])
+		}
+		prod = n.Add(prod, pp)
+	}
+	return prod
+}
+
+// Mux returns one of two vectors, extended to the wider of the two: the
+// second if a select net is true and the first otherwise.
+func (n *Netlist) Mux(s Net, a, b Bits) Bits {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w)
+	y := make(Bits, w)
+	for i := range y {
+		y[i] = n.Or(n.And(n.Not(s), a[i]), n.And(s, b[i]))
+	}
+	return y
+}
+
+// DivMod returns the quotient and remainder of dividing one vector by
+// another, both treated as unsigned numbers, truncated to the wider of the
+// two.  As in SMT-LIB, dividing by zero yields a quotient of all ones and a
+// remainder equal to the dividend.
+func (n *Netlist) DivMod(a, b Bits) (q, r Bits) {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w+1)
+	q = make(Bits, w)
+	r = Const(w+1, 0)
+	for i := int(w) - 1; i >= 0; i-- {
+		// Shift the next bit of the dividend into the partial
+		// remainder, and subtract the divisor if it fits.
+		r = append(Bits{a[i]}, r[:w]...)
+		q[i] = n.Not(n.Less(r, b))
+		r = n.Mux(q[i], r, n.Sub(r, b))
+	}
+	return q, r[:w]
+}
+
+// SignedDivRem returns the quotient, truncated toward zero, and remainder of
+// dividing one vector by another, both treated as two's-complement numbers,
+// truncated to the wider of the two.  The remainder takes the sign of the
+// dividend.
+func (n *Netlist) SignedDivRem(a, b Bits) (q, r Bits) {
+	w := maxWidth(a, b)
+	a, b = a.SignExtend(w), b.SignExtend(w)
+	sa, sb := a[w-1], b[w-1]
+	q, r = n.DivMod(n.Mux(sa, a, n.Neg(a)), n.Mux(sb, b, n.Neg(b)))
+	q = n.Mux(n.Xor(sa, sb), q, n.Neg(q))
+	r = n.Mux(sa, r, n.Neg(r))
+	return q, r
+}
+
+// SignedMod returns the modulus of dividing one vector by another, both
+// treated as two's-complement numbers, truncated to the wider of the two.
+// The modulus takes the sign of the divisor.
+func (n *Netlist) SignedMod(a, b Bits) Bits {
+	w := maxWidth(a, b)
+	b = b.SignExtend(w)
+	_, r := n.SignedDivRem(a, b)
+	fix := n.And(n.Not(n.Equal(r, Const(w, 0))), n.Xor(r[w-1], b[w-1]))
+	return n.Mux(fix, r, n.Add(r, b))
+}
+
+// shift shifts a vector left or right by an amount given by another vector,
+// treated as an unsigned number, filling vacated bits with a given net.  Each
+// bit of the shift amount selects between the vector and a copy of it shifted
+// by the corresponding power of two.
+func (n *Netlist) shift(a, s Bits, left bool, fill Net) Bits {
+	w := len(a)
+	for i, si := range s {
+		d := w
+		if i < 31 && 1<<uint(i) < w {
+			d = 1 << uint(i)
+		}
+		sh := make(Bits, w)
+		for j := range sh {
+			k := j + d
+			if left {
+				k = j - d
+			}
+			sh[j] = fill
+			if k >= 0 && k < w {
+				sh[j] = a[k]
+			}
+		}
+		a = n.Mux(si, a, sh)
+	}
+	return a
+}
+
+// ShiftLeft returns a vector shifted left by an unsigned amount.
+func (n *Netlist) ShiftLeft(a, s Bits) Bits {
+	return n.shift(a, s, true, NetFalse)
+}
+
+// ShiftRight returns a vector, treated as an unsigned number, shifted right
+// by an unsigned amount.
+func (n *Netlist) ShiftRight(a, s Bits) Bits {
+	return n.shift(a, s, false, NetFalse)
+}
+
+// SignedShiftRight returns a vector, treated as a two's-complement number,
+// shifted arithmetically right by an unsigned amount.
+func (n *Netlist) SignedShiftRight(a, s Bits) Bits {
+	return n.shift(a, s, false, a[len(a)-1])
+}
+
+// Equal returns a net that is true if and only if two vectors are equal.
+func (n *Netlist) Equal(a, b Bits) Net {
+	w := maxWidth(a, b)
+	a, b = a.Resize(w), b.Resize(w)
+	eqs := make([]Net, w)
+	for i := range eqs {
+		eqs[i] = n.Not(n.Xor(a[i], b[i]))
+	}
+	return n.AndAll(eqs)
+}
+
+// Less returns a net that is true if and only if one vector is less than
+// another when both are treated as unsigned numbers.
+func (n *Netlist) Less(a, b Bits) Net {
+	// a < b if and only if a - b borrows.
+	w := maxWidth(a, b)
+	_, c := n.addCarry(a.Resize(w), n.Invert(b.Resize(w)), NetTrue)
+	return n.Not(c)
+}
+
+// SignedLess returns a net that is true if and only if one vector is less
+// than another when both are treated as two's-complement numbers.
+func (n *Netlist) SignedLess(a, b Bits) Net {
+	// Complementing the sign bits maps signed order to unsigned order.
+	w := maxWidth(a, b)
+	a, b = append(Bits{}, a.SignExtend(w)...), append(Bits{}, b.SignExtend(w)...)
+	a[w-1], b[w-1] = n.Not(a[w-1]), n.Not(b[w-1])
+	return n.Less(a, b)
+}
+
+// Hamiltonian represents an Ising Hamiltonian as a set of linear (h) and
+// quadratic (J) coefficients on spins.
+type Hamiltonian struct {
+	H map[Net]float64    // Linear coefficients
+	J map[[2]Net]float64 // Quadratic coefficients
+}
+
+// qubo accumulates penalty functions over Boolean variables.
+type qubo struct {
+	Linear    map[Net]float64
+	Quadratic map[[2]Net]float64
+}
+
+// add adds a linear (a == b) or quadratic term to a QUBO.
+func (q *qubo) add(a, b Net, w float64) {
+	switch {
+	case a == b:
+		q.Linear[a] += w
+	case a < b:
+		q.Quadratic[[2]Net{a, b}] += w
+	default:
+		q.Quadratic[[2]Net{b, a}] += w
+	}
+}
+
+// Hamiltonian converts a netlist to an Ising Hamiltonian whose ground states
+// correspond to all consistent assignments to the netlist's nets.  Each
+// gate contributes a penalty function that is 0 when the gate's output is
+// consistent with its inputs and at least 1 otherwise.  XOR gates require an
+// ancillary spin.
+func (n *Netlist) Hamiltonian() Hamiltonian {
+	// Express each gate as a QUBO penalty function.
+	q := qubo{
+		Linear:    make(map[Net]float64, n.NumNets),
+		Quadratic: make(map[[2]Net]float64, len(n.Gates)*3),
+	}
+	for _, g := range n.Gates {
+		a, b, y := g.In[0], g.In[1], g.Out
+		switch g.Type {
+		case NotGate:
+			// 1 - a - y + 2ay
+			q.add(a, a, -1)
+			q.add(y, y, -1)
+			q.add(a, y, 2)
+		case AndGate:
+			// ab - 2ay - 2by + 3y
+			q.add(a, b, 1)
+			q.add(a, y, -2)
+			q.add(b, y, -2)
+			q.add(y, y, 3)
+		case OrGate:
+			// a + b + y + ab - 2ay - 2by
+			q.add(a, a, 1)
+			q.add(b, b, 1)
+			q.add(y, y, 1)
+			q.add(a, b, 1)
+			q.add(a, y, -2)
+			q.add(b, y, -2)
+		case XorGate:
+			// (a + b - y - 2h)^2, where h is an ancilla
+			h := g.Aux
+			for _, v := range []struct {
+				x, y Net
+				w    float64
+			}{
+				{a, a, 1}, {b, b, 1}, {y, y, 1}, {h, h, 4},
+				{a, b, 2}, {a, y, -2}, {a, h, -4},
+				{b, y, -2}, {b, h, -4}, {y, h, 4},
+			} {
+				q.add(v.x, v.y, v.w)
+			}
+		default:
+			fatalf("Internal error: Unexpected gate type %d", g.Type)
+		}
+	}
+
+	// Convert from Booleans in {0, 1} to spins in {-1, +1} by
+	// substituting (s + 1)/2 for each Boolean variable.
+	ham := Hamiltonian{
+		H: make(map[Net]float64, len(q.Linear)),
+		J: make(map[[2]Net]float64, len(q.Quadratic)),
+	}
+	for v, w := range q.Linear {
+		ham.H[v] += w / 2
+	}
+	for vs, w := range q.Quadratic {
+		ham.H[vs[0]] += w / 4
+		ham.H[vs[1]] += w / 4
+		if w != 0 {
+			ham.J[vs] += w / 4
+		}
+	}
+	return ham
+}
+
+// energy returns the energy of a complete assignment of spins, indexed by
+// net.
+func (ham Hamiltonian) energy(s []int8) float64 {
+	e := 0.0
+	for v, w := range ham.H {
+		e += w * float64(s[v])
+	}
+	for vs, w := range ham.J {
+		e += w * float64(s[vs[0]]) * float64(s[vs[1]])
+	}
+	return e
+}
+
+// netNames assigns a name to every net in a netlist, preferring port names.
+// It returns the name of each net and a list of additional port names that
+// share a net with another port.
+func (n *Netlist) netNames() (map[Net]string, map[string]Net) {
+	// Visit ports in a deterministic order.
+	ports := make([]string, 0, len(n.Ports))
+	for nm := range n.Ports {
+		ports = append(ports, nm)
+	}
+	sort.Strings(ports)
+
+	// Name each net after the first port that refers to it.
+	names := make(map[Net]string, n.NumNets)
+	extras := make(map[string]Net)
+	for _, nm := range ports {
+		v := n.Ports[nm]
+		if _, seen := names[v]; seen || v == NetFalse || v == NetTrue {
+			extras[nm] = v
+		} else {
+			names[v] = nm
+		}
+	}
+	for v := Net(2); int(v) < n.NumNets; v++ {
+		if _, ok := names[v]; !ok {
+			names[v] = fmt.Sprintf("$n%d", v)
+		}
+	}
+	return names, extras
+}
+
+// WriteQMASM writes a netlist as a flat QMASM program.
+func (n *Netlist) WriteQMASM(w io.Writer, p *Parameters) {
+	// Output some header comments.
+	ham := n.Hamiltonian()
+	fmt.Fprintf(w, "# QMASM version of Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "# Conversion by %s, written by Scott Pakin <pakin@lanl.gov>\n", p.ProgName)
+	fmt.Fprintf(w, "#\n# This program contains %d gate(s) represented with %d spin(s).\n", len(n.Gates), len(ham.H))
+	fmt.Fprintln(w, "")
+
+	// Output the linear and quadratic coefficients in a deterministic
+	// order.  Ensure that every port is mentioned, even if it is
+	// unconstrained.
+	names, extras := n.netNames()
+	for _, v := range n.Ports {
+		if _, ok := ham.H[v]; !ok && v != NetFalse && v != NetTrue {
+			ham.H[v] = 0
+		}
+	}
+	hs := make([]Net, 0, len(ham.H))
+	for v := range ham.H {
+		hs = append(hs, v)
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i] < hs[j] })
+	for _, v := range hs {
+		fmt.Fprintf(w, "%s %g\n", names[v], ham.H[v])
+	}
+	js := make([][2]Net, 0, len(ham.J))
+	for vs := range ham.J {
+		js = append(js, vs)
+	}
+	sort.Slice(js, func(i, j int) bool {
+		if js[i][0] != js[j][0] {
+			return js[i][0] < js[j][0]
+		}
+		return js[i][1] < js[j][1]
+	})
+	for _, vs := range js {
+		fmt.Fprintf(w, "%s %s %g\n", names[vs[0]], names[vs[1]], ham.J[vs])
+	}
+
+	// Pin ports that are constant, and chain together ports that share a
+	// net with another port.
+	xNames := make([]string, 0, len(extras))
+	for nm := range extras {
+		xNames = append(xNames, nm)
+	}
+	sort.Strings(xNames)
+	for _, nm := range xNames {
+		switch v := extras[nm]; v {
+		case NetFalse:
+			fmt.Fprintf(w, "%s 0\n%s := false\n", nm, nm)
+		case NetTrue:
+			fmt.Fprintf(w, "%s 0\n%s := true\n", nm, nm)
+		default:
+			fmt.Fprintf(w, "%s = %s\n", nm, names[v])
+		}
+	}
+}
@@ -7,7 +7,7 @@
 // but with various bugs corrected, support for relational and arithmetic
 // expressions added, and the whole grammar converted to a PEG.
 
-package main
+package qaprolog
 
 import (
 	"bytes"
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -30,14 +31,15 @@ type ASTNodeType int
 // Declare all of the AST node types we intend to use.
 const (
 	UnknownType            ASTNodeType = iota // Should never be used
-	NumeralType                               // Non-negative integer (e.g., "123")
+	NumeralType                               // Integer (e.g., "123" or "-45")
 	AtomType                                  // Atom, with quotes stripped (e.g., "scott")
 	VariableType                              // Variable (e.g., "Name")
 	TermType                                  // Term (a numeral, atom, or variable)
 	TermListType                              // List of terms
 	ListTailType                              // Tail of a list (e.g., the "T" in "[H|T]").
 	ListType                                  // List (e.g., "[a, b, c]" or "[x, y, z|More]")
-	PrimaryExprType                           // Primary expression (e.g., "(2+3)")
+	PrimaryExprType                           // Primary expression (e.g., "(2+3)" or "abs(X)")
+	FunctionOpType                            // Evaluable functor (e.g., "abs")
 	UnaryExprType                             // Unary expression (e.g., "-X")
 	UnaryOpType                               // Unary operator (e.g., "-")
 	MultiplicativeExprType                    // Multiplicative expression (e.g., "7 * 5")
@@ -47,10 +49,15 @@ const (
 	RelationOpType                            // Relation operator (e.g., "=<")
 	RelationType                              // Relation (e.g., "happy(X) = Y" or "N < 10")
 	PredicateType                             // Predicate (e.g., "likes(john, mary)")
+	NegationType                              // Negation as failure (e.g., "\+ likes(john, mary)")
+	DisjunctionType                           // Disjunction (e.g., "(likes(john, X) ; likes(mary, X))")
+	IfThenType                                // If-then branch of a disjunction (e.g., "X > 5 -> Y = 1")
 	StructureType                             // Structure (e.g., "likes(john, mary)")
 	PredicateListType                         // List of predicates (e.g., "likes(john, X), likes(X, mary)")
 	ClauseType                                // Clause (e.g., "likes(john, X) :- likes(mary, X).")
 	ClauseListType                            // List of clauses (e.g., "likes(john, X) :- likes(mary, X). likes(mary, cheese).")
+	PredIndicatorType                         // Predicate indicator (e.g., "likes/2")
+	DirectiveType                             // Directive (e.g., ":- max_depth(likes/2, 5).")
 	QueryType                                 // Query (e.g., "?- likes(john, X).")
 	ProgramType                               // A complete Prolog program
 )
@@ -117,6 +124,50 @@ func (c *current) ConstructList(t ASTNodeType, v, n, ns interface{}) *ASTNode {
 	return &node
 }
 
+// ConstructFunction constructs an AST node of type PrimaryExprType that
+// applies an evaluable functor to one or more arguments.  The node's value is
+// the functor's name, and its children are the functor followed by the
+// arguments.
+func (c *current) ConstructFunction(f interface{}, args ...interface{}) *ASTNode {
+	fn := f.(*ASTNode)
+	node := ASTNode{
+		Type:     PrimaryExprType,
+		Text:     string(c.text),
+		Value:    fn.Value,
+		Pos:      c.pos,
+		Children: []*ASTNode{fn},
+	}
+	for _, a := range args {
+		node.Children = append(node.Children, a.(*ASTNode))
+	}
+	return &node
+}
+
+// FoldLeft takes an expression and a list of {operator, expression} pairs
+// (interspersed with whitespace) and returns a left-associative tree of AST
+// nodes of a given type.  Each node has either a single child (the first
+// expression) or three children (a left-hand side, an operator, and a
+// right-hand side).
+func (c *current) FoldLeft(t ASTNodeType, e1, rest interface{}) *ASTNode {
+	node := c.ConstructList(t, "", e1, nil)
+	for _, r := range rest.([]interface{}) {
+		oe := r.([]interface{})
+		kids := []*ASTNode{
+			node,
+			oe[1].(*ASTNode),
+			oe[3].(*ASTNode),
+		}
+		node = &ASTNode{
+			Type:     t,
+			Text:     string(c.text),
+			Value:    kids[1].Value,
+			Pos:      c.pos,
+			Children: kids,
+		}
+	}
+	return node
+}
+
 // PrepareRelation takes two expressions and an operator and returns an ASTNode
 // representing that relation.
 func (c *current) PrepareRelation(e1, o, e2 interface{}) *ASTNode {
@@ -139,84 +190,85 @@ var g = &grammar{
 	rules: []*rule{
 		{
 			name: "Program",
-			pos:  position{line: 126, col: 1, offset: 5840},
+			pos:  position{line: 156, col: 1, offset: 7356},
 			expr: &choiceExpr{
-				pos: position{line: 126, col: 12, offset: 5851},
+				pos: position{line: 156, col: 12, offset: 7367},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 126, col: 12, offset: 5851},
+						pos: position{line: 156, col: 12, offset: 7367},
 						run: (*parser).callonProgram2,
 						expr: &seqExpr{
-							pos: position{line: 126, col: 12, offset: 5851},
+							pos: position{line: 156, col: 12, offset: 7367},
 							exprs: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 126, col: 12, offset: 5851},
+									pos:  position{line: 156, col: 12, offset: 7367},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 126, col: 17, offset: 5856},
+									pos:   position{line: 156, col: 17, offset: 7372},
 									label: "cl",
 									expr: &ruleRefExpr{
-										pos:  position{line: 126, col: 20, offset: 5859},
+										pos:  position{line: 156, col: 20, offset: 7375},
 										name: "ClauseList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 126, col: 31, offset: 5870},
+									pos:  position{line: 156, col: 31, offset: 7386},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 126, col: 36, offset: 5875},
+									pos:   position{line: 156, col: 36, offset: 7391},
 									label: "q",
 									expr: &ruleRefExpr{
-										pos:  position{line: 126, col: 38, offset: 5877},
+										pos:  position{line: 156, col: 38, offset: 7393},
 										name: "Query",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 126, col: 44, offset: 5883},
+									pos:  position{line: 156, col: 44, offset: 7399},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 126, col: 49, offset: 5888},
+									pos:        position{line: 156, col: 49, offset: 7404},
 									val:        ".",
 									ignoreCase: false,
+									want:       "\".\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 126, col: 53, offset: 5892},
+									pos:  position{line: 156, col: 53, offset: 7408},
 									name: "Skip",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 126, col: 58, offset: 5897},
+									pos:  position{line: 156, col: 58, offset: 7413},
 									name: "EOF",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 131, col: 5, offset: 6075},
+						pos: position{line: 161, col: 5, offset: 7591},
 						run: (*parser).callonProgram14,
 						expr: &seqExpr{
-							pos: position{line: 131, col: 5, offset: 6075},
+							pos: position{line: 161, col: 5, offset: 7591},
 							exprs: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 131, col: 5, offset: 6075},
+									pos:  position{line: 161, col: 5, offset: 7591},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 131, col: 10, offset: 6080},
+									pos:   position{line: 161, col: 10, offset: 7596},
 									label: "cl",
 									expr: &ruleRefExpr{
-										pos:  position{line: 131, col: 13, offset: 6083},
+										pos:  position{line: 161, col: 13, offset: 7599},
 										name: "ClauseList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 131, col: 24, offset: 6094},
+									pos:  position{line: 161, col: 24, offset: 7610},
 									name: "Skip",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 131, col: 29, offset: 6099},
+									pos:  position{line: 161, col: 29, offset: 7615},
 									name: "EOF",
 								},
 							},
@@ -227,27 +279,28 @@ var g = &grammar{
 		},
 		{
 			name: "Query",
-			pos:  position{line: 136, col: 1, offset: 6212},
+			pos:  position{line: 166, col: 1, offset: 7728},
 			expr: &actionExpr{
-				pos: position{line: 136, col: 10, offset: 6221},
+				pos: position{line: 166, col: 10, offset: 7737},
 				run: (*parser).callonQuery1,
 				expr: &seqExpr{
-					pos: position{line: 136, col: 10, offset: 6221},
+					pos: position{line: 166, col: 10, offset: 7737},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 136, col: 10, offset: 6221},
+							pos:        position{line: 166, col: 10, offset: 7737},
 							val:        "?-",
 							ignoreCase: false,
+							want:       "\"?-\"",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 136, col: 15, offset: 6226},
+							pos:  position{line: 166, col: 15, offset: 7742},
 							name: "Skip",
 						},
 						&labeledExpr{
-							pos:   position{line: 136, col: 20, offset: 6231},
+							pos:   position{line: 166, col: 20, offset: 7747},
 							label: "ps",
 							expr: &ruleRefExpr{
-								pos:  position{line: 136, col: 23, offset: 6234},
+								pos:  position{line: 166, col: 23, offset: 7750},
 								name: "PredicateList",
 							},
 						},
@@ -257,33 +310,42 @@ var g = &grammar{
 		},
 		{
 			name: "ClauseList",
-			pos:  position{line: 180, col: 1, offset: 7249},
+			pos:  position{line: 214, col: 1, offset: 8901},
 			expr: &choiceExpr{
-				pos: position{line: 180, col: 15, offset: 7263},
+				pos: position{line: 214, col: 15, offset: 8915},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 180, col: 15, offset: 7263},
+						pos: position{line: 214, col: 15, offset: 8915},
 						run: (*parser).callonClauseList2,
 						expr: &seqExpr{
-							pos: position{line: 180, col: 15, offset: 7263},
+							pos: position{line: 214, col: 15, offset: 8915},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 180, col: 15, offset: 7263},
+									pos:   position{line: 214, col: 15, offset: 8915},
 									label: "cl",
-									expr: &ruleRefExpr{
-										pos:  position{line: 180, col: 18, offset: 7266},
-										name: "Clause",
+									expr: &choiceExpr{
+										pos: position{line: 214, col: 19, offset: 8919},
+										alternatives: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 214, col: 19, offset: 8919},
+												name: "Clause",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 214, col: 28, offset: 8928},
+												name: "Directive",
+											},
+										},
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 180, col: 25, offset: 7273},
+									pos:  position{line: 214, col: 39, offset: 8939},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 180, col: 30, offset: 7278},
+									pos:   position{line: 214, col: 44, offset: 8944},
 									label: "cls",
 									expr: &ruleRefExpr{
-										pos:  position{line: 180, col: 34, offset: 7282},
+										pos:  position{line: 214, col: 48, offset: 8948},
 										name: "ClauseList",
 									},
 								},
@@ -291,14 +353,258 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 182, col: 5, offset: 7365},
-						run: (*parser).callonClauseList9,
+						pos: position{line: 216, col: 5, offset: 9031},
+						run: (*parser).callonClauseList11,
 						expr: &labeledExpr{
-							pos:   position{line: 182, col: 5, offset: 7365},
+							pos:   position{line: 216, col: 5, offset: 9031},
 							label: "cl",
+							expr: &choiceExpr{
+								pos: position{line: 216, col: 9, offset: 9035},
+								alternatives: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 216, col: 9, offset: 9035},
+										name: "Clause",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 216, col: 18, offset: 9044},
+										name: "Directive",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Directive",
+			pos:  position{line: 221, col: 1, offset: 9171},
+			expr: &choiceExpr{
+				pos: position{line: 221, col: 14, offset: 9184},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 221, col: 14, offset: 9184},
+						run: (*parser).callonDirective2,
+						expr: &seqExpr{
+							pos: position{line: 221, col: 14, offset: 9184},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 221, col: 14, offset: 9184},
+									val:        ":-",
+									ignoreCase: false,
+									want:       "\":-\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 221, col: 19, offset: 9189},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 221, col: 24, offset: 9194},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 221, col: 26, offset: 9196},
+										name: "Atom",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 221, col: 31, offset: 9201},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 221, col: 36, offset: 9206},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 221, col: 40, offset: 9210},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 221, col: 45, offset: 9215},
+									label: "ds",
+									expr: &ruleRefExpr{
+										pos:  position{line: 221, col: 48, offset: 9218},
+										name: "DirectiveArgList",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 221, col: 65, offset: 9235},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 221, col: 70, offset: 9240},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 221, col: 74, offset: 9244},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 221, col: 79, offset: 9249},
+									val:        ".",
+									ignoreCase: false,
+									want:       "\".\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 223, col: 5, offset: 9337},
+						run: (*parser).callonDirective17,
+						expr: &seqExpr{
+							pos: position{line: 223, col: 5, offset: 9337},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 223, col: 5, offset: 9337},
+									val:        ":-",
+									ignoreCase: false,
+									want:       "\":-\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 223, col: 10, offset: 9342},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 223, col: 15, offset: 9347},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 223, col: 17, offset: 9349},
+										name: "Atom",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 223, col: 22, offset: 9354},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 223, col: 27, offset: 9359},
+									val:        ".",
+									ignoreCase: false,
+									want:       "\".\"",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DirectiveArgList",
+			pos:  position{line: 228, col: 1, offset: 9524},
+			expr: &choiceExpr{
+				pos: position{line: 228, col: 21, offset: 9544},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 228, col: 21, offset: 9544},
+						run: (*parser).callonDirectiveArgList2,
+						expr: &seqExpr{
+							pos: position{line: 228, col: 21, offset: 9544},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 228, col: 21, offset: 9544},
+									label: "d",
+									expr: &ruleRefExpr{
+										pos:  position{line: 228, col: 23, offset: 9546},
+										name: "DirectiveArg",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 228, col: 36, offset: 9559},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 228, col: 41, offset: 9564},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 228, col: 45, offset: 9568},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 228, col: 50, offset: 9573},
+									label: "ds",
+									expr: &ruleRefExpr{
+										pos:  position{line: 228, col: 53, offset: 9576},
+										name: "DirectiveArgList",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 230, col: 5, offset: 9661},
+						run: (*parser).callonDirectiveArgList11,
+						expr: &labeledExpr{
+							pos:   position{line: 230, col: 5, offset: 9661},
+							label: "d",
 							expr: &ruleRefExpr{
-								pos:  position{line: 182, col: 8, offset: 7368},
-								name: "Clause",
+								pos:  position{line: 230, col: 7, offset: 9663},
+								name: "DirectiveArg",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DirectiveArg",
+			pos:  position{line: 235, col: 1, offset: 9815},
+			expr: &choiceExpr{
+				pos: position{line: 235, col: 17, offset: 9831},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 235, col: 17, offset: 9831},
+						name: "PredIndicator",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 235, col: 33, offset: 9847},
+						name: "Term",
+					},
+				},
+			},
+		},
+		{
+			name: "PredIndicator",
+			pos:  position{line: 238, col: 1, offset: 9902},
+			expr: &actionExpr{
+				pos: position{line: 238, col: 18, offset: 9919},
+				run: (*parser).callonPredIndicator1,
+				expr: &seqExpr{
+					pos: position{line: 238, col: 18, offset: 9919},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 238, col: 18, offset: 9919},
+							label: "a",
+							expr: &ruleRefExpr{
+								pos:  position{line: 238, col: 20, offset: 9921},
+								name: "Atom",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 238, col: 25, offset: 9926},
+							name: "Skip",
+						},
+						&litMatcher{
+							pos:        position{line: 238, col: 30, offset: 9931},
+							val:        "/",
+							ignoreCase: false,
+							want:       "\"/\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 238, col: 34, offset: 9935},
+							name: "Skip",
+						},
+						&labeledExpr{
+							pos:   position{line: 238, col: 39, offset: 9940},
+							label: "n",
+							expr: &ruleRefExpr{
+								pos:  position{line: 238, col: 41, offset: 9942},
+								name: "Numeral",
 							},
 						},
 					},
@@ -307,79 +613,82 @@ var g = &grammar{
 		},
 		{
 			name: "Clause",
-			pos:  position{line: 187, col: 1, offset: 7488},
+			pos:  position{line: 244, col: 1, offset: 10145},
 			expr: &choiceExpr{
-				pos: position{line: 187, col: 11, offset: 7498},
+				pos: position{line: 244, col: 11, offset: 10155},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 187, col: 11, offset: 7498},
+						pos: position{line: 244, col: 11, offset: 10155},
 						run: (*parser).callonClause2,
 						expr: &seqExpr{
-							pos: position{line: 187, col: 11, offset: 7498},
+							pos: position{line: 244, col: 11, offset: 10155},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 187, col: 11, offset: 7498},
+									pos:   position{line: 244, col: 11, offset: 10155},
 									label: "p",
 									expr: &ruleRefExpr{
-										pos:  position{line: 187, col: 13, offset: 7500},
+										pos:  position{line: 244, col: 13, offset: 10157},
 										name: "Predicate",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 187, col: 23, offset: 7510},
+									pos:  position{line: 244, col: 23, offset: 10167},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 187, col: 28, offset: 7515},
+									pos:        position{line: 244, col: 28, offset: 10172},
 									val:        ":-",
 									ignoreCase: false,
+									want:       "\":-\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 187, col: 33, offset: 7520},
+									pos:  position{line: 244, col: 33, offset: 10177},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 187, col: 38, offset: 7525},
+									pos:   position{line: 244, col: 38, offset: 10182},
 									label: "ps",
 									expr: &ruleRefExpr{
-										pos:  position{line: 187, col: 41, offset: 7528},
+										pos:  position{line: 244, col: 41, offset: 10185},
 										name: "PredicateList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 187, col: 55, offset: 7542},
+									pos:  position{line: 244, col: 55, offset: 10199},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 187, col: 60, offset: 7547},
+									pos:        position{line: 244, col: 60, offset: 10204},
 									val:        ".",
 									ignoreCase: false,
+									want:       "\".\"",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 192, col: 5, offset: 7735},
+						pos: position{line: 249, col: 5, offset: 10392},
 						run: (*parser).callonClause13,
 						expr: &seqExpr{
-							pos: position{line: 192, col: 5, offset: 7735},
+							pos: position{line: 249, col: 5, offset: 10392},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 192, col: 5, offset: 7735},
+									pos:   position{line: 249, col: 5, offset: 10392},
 									label: "p",
 									expr: &ruleRefExpr{
-										pos:  position{line: 192, col: 7, offset: 7737},
+										pos:  position{line: 249, col: 7, offset: 10394},
 										name: "Predicate",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 192, col: 17, offset: 7747},
+									pos:  position{line: 249, col: 17, offset: 10404},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 192, col: 22, offset: 7752},
+									pos:        position{line: 249, col: 22, offset: 10409},
 									val:        ".",
 									ignoreCase: false,
+									want:       "\".\"",
 								},
 							},
 						},
@@ -389,42 +698,43 @@ var g = &grammar{
 		},
 		{
 			name: "PredicateList",
-			pos:  position{line: 200, col: 1, offset: 7989},
+			pos:  position{line: 257, col: 1, offset: 10646},
 			expr: &choiceExpr{
-				pos: position{line: 200, col: 18, offset: 8006},
+				pos: position{line: 257, col: 18, offset: 10663},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 200, col: 18, offset: 8006},
+						pos: position{line: 257, col: 18, offset: 10663},
 						run: (*parser).callonPredicateList2,
 						expr: &seqExpr{
-							pos: position{line: 200, col: 18, offset: 8006},
+							pos: position{line: 257, col: 18, offset: 10663},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 200, col: 18, offset: 8006},
+									pos:   position{line: 257, col: 18, offset: 10663},
 									label: "p",
 									expr: &ruleRefExpr{
-										pos:  position{line: 200, col: 20, offset: 8008},
+										pos:  position{line: 257, col: 20, offset: 10665},
 										name: "Predicate",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 200, col: 30, offset: 8018},
+									pos:  position{line: 257, col: 30, offset: 10675},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 200, col: 35, offset: 8023},
+									pos:        position{line: 257, col: 35, offset: 10680},
 									val:        ",",
 									ignoreCase: false,
+									want:       "\",\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 200, col: 39, offset: 8027},
+									pos:  position{line: 257, col: 39, offset: 10684},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 200, col: 44, offset: 8032},
+									pos:   position{line: 257, col: 44, offset: 10689},
 									label: "ps",
 									expr: &ruleRefExpr{
-										pos:  position{line: 200, col: 47, offset: 8035},
+										pos:  position{line: 257, col: 47, offset: 10692},
 										name: "PredicateList",
 									},
 								},
@@ -432,13 +742,13 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 202, col: 5, offset: 8122},
+						pos: position{line: 259, col: 5, offset: 10779},
 						run: (*parser).callonPredicateList11,
 						expr: &labeledExpr{
-							pos:   position{line: 202, col: 5, offset: 8122},
+							pos:   position{line: 259, col: 5, offset: 10779},
 							label: "p",
 							expr: &ruleRefExpr{
-								pos:  position{line: 202, col: 7, offset: 8124},
+								pos:  position{line: 259, col: 7, offset: 10781},
 								name: "Predicate",
 							},
 						},
@@ -448,77 +758,143 @@ var g = &grammar{
 		},
 		{
 			name: "Predicate",
-			pos:  position{line: 207, col: 1, offset: 8252},
+			pos:  position{line: 264, col: 1, offset: 10909},
 			expr: &choiceExpr{
-				pos: position{line: 207, col: 14, offset: 8265},
+				pos: position{line: 264, col: 14, offset: 10922},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 207, col: 14, offset: 8265},
+						pos: position{line: 264, col: 14, offset: 10922},
 						run: (*parser).callonPredicate2,
+						expr: &seqExpr{
+							pos: position{line: 264, col: 14, offset: 10922},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 264, col: 14, offset: 10922},
+									val:        "\\+",
+									ignoreCase: false,
+									want:       "\"\\\\+\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 264, col: 20, offset: 10928},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 264, col: 25, offset: 10933},
+									label: "g",
+									expr: &ruleRefExpr{
+										pos:  position{line: 264, col: 27, offset: 10935},
+										name: "Predicate",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 267, col: 5, offset: 11071},
+						run: (*parser).callonPredicate8,
 						expr: &labeledExpr{
-							pos:   position{line: 207, col: 14, offset: 8265},
+							pos:   position{line: 267, col: 5, offset: 11071},
 							label: "r",
 							expr: &ruleRefExpr{
-								pos:  position{line: 207, col: 16, offset: 8267},
+								pos:  position{line: 267, col: 7, offset: 11073},
 								name: "Relation",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 209, col: 5, offset: 8346},
-						run: (*parser).callonPredicate5,
+						pos: position{line: 269, col: 5, offset: 11152},
+						run: (*parser).callonPredicate11,
+						expr: &seqExpr{
+							pos: position{line: 269, col: 5, offset: 11152},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 269, col: 5, offset: 11152},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 269, col: 9, offset: 11156},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 269, col: 14, offset: 11161},
+									label: "d",
+									expr: &ruleRefExpr{
+										pos:  position{line: 269, col: 16, offset: 11163},
+										name: "Disjunction",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 269, col: 28, offset: 11175},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 269, col: 33, offset: 11180},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 271, col: 5, offset: 11254},
+						run: (*parser).callonPredicate19,
 						expr: &seqExpr{
-							pos: position{line: 209, col: 5, offset: 8346},
+							pos: position{line: 271, col: 5, offset: 11254},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 209, col: 5, offset: 8346},
+									pos:   position{line: 271, col: 5, offset: 11254},
 									label: "a",
 									expr: &ruleRefExpr{
-										pos:  position{line: 209, col: 7, offset: 8348},
+										pos:  position{line: 271, col: 7, offset: 11256},
 										name: "Atom",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 209, col: 12, offset: 8353},
+									pos:  position{line: 271, col: 12, offset: 11261},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 209, col: 17, offset: 8358},
+									pos:        position{line: 271, col: 17, offset: 11266},
 									val:        "(",
 									ignoreCase: false,
+									want:       "\"(\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 209, col: 21, offset: 8362},
+									pos:  position{line: 271, col: 21, offset: 11270},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 209, col: 26, offset: 8367},
+									pos:   position{line: 271, col: 26, offset: 11275},
 									label: "ts",
 									expr: &ruleRefExpr{
-										pos:  position{line: 209, col: 29, offset: 8370},
+										pos:  position{line: 271, col: 29, offset: 11278},
 										name: "TermList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 209, col: 38, offset: 8379},
+									pos:  position{line: 271, col: 38, offset: 11287},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 209, col: 43, offset: 8384},
+									pos:        position{line: 271, col: 43, offset: 11292},
 									val:        ")",
 									ignoreCase: false,
+									want:       "\")\"",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 211, col: 5, offset: 8457},
-						run: (*parser).callonPredicate16,
+						pos: position{line: 273, col: 5, offset: 11365},
+						run: (*parser).callonPredicate30,
 						expr: &labeledExpr{
-							pos:   position{line: 211, col: 5, offset: 8457},
+							pos:   position{line: 273, col: 5, offset: 11365},
 							label: "a",
 							expr: &ruleRefExpr{
-								pos:  position{line: 211, col: 7, offset: 8459},
+								pos:  position{line: 273, col: 7, offset: 11367},
 								name: "Atom",
 							},
 						},
@@ -526,47 +902,159 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "Disjunction",
+			pos:  position{line: 278, col: 1, offset: 11488},
+			expr: &choiceExpr{
+				pos: position{line: 278, col: 16, offset: 11503},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 278, col: 16, offset: 11503},
+						run: (*parser).callonDisjunction2,
+						expr: &seqExpr{
+							pos: position{line: 278, col: 16, offset: 11503},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 278, col: 16, offset: 11503},
+									label: "b",
+									expr: &ruleRefExpr{
+										pos:  position{line: 278, col: 18, offset: 11505},
+										name: "Branch",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 278, col: 25, offset: 11512},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 278, col: 30, offset: 11517},
+									val:        ";",
+									ignoreCase: false,
+									want:       "\";\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 278, col: 34, offset: 11521},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 278, col: 39, offset: 11526},
+									label: "d",
+									expr: &ruleRefExpr{
+										pos:  position{line: 278, col: 41, offset: 11528},
+										name: "Disjunction",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 280, col: 5, offset: 11610},
+						run: (*parser).callonDisjunction11,
+						expr: &labeledExpr{
+							pos:   position{line: 280, col: 5, offset: 11610},
+							label: "b",
+							expr: &ruleRefExpr{
+								pos:  position{line: 280, col: 7, offset: 11612},
+								name: "Branch",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Branch",
+			pos:  position{line: 285, col: 1, offset: 11763},
+			expr: &choiceExpr{
+				pos: position{line: 285, col: 11, offset: 11773},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 285, col: 11, offset: 11773},
+						run: (*parser).callonBranch2,
+						expr: &seqExpr{
+							pos: position{line: 285, col: 11, offset: 11773},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 285, col: 11, offset: 11773},
+									label: "cnd",
+									expr: &ruleRefExpr{
+										pos:  position{line: 285, col: 15, offset: 11777},
+										name: "PredicateList",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 285, col: 29, offset: 11791},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 285, col: 34, offset: 11796},
+									val:        "->",
+									ignoreCase: false,
+									want:       "\"->\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 285, col: 39, offset: 11801},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 285, col: 44, offset: 11806},
+									label: "thn",
+									expr: &ruleRefExpr{
+										pos:  position{line: 285, col: 48, offset: 11810},
+										name: "PredicateList",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 289, col: 5, offset: 11976},
+						name: "PredicateList",
+					},
+				},
+			},
+		},
 		{
 			name: "Relation",
-			pos:  position{line: 216, col: 1, offset: 8577},
+			pos:  position{line: 292, col: 1, offset: 12035},
 			expr: &choiceExpr{
-				pos: position{line: 216, col: 13, offset: 8589},
+				pos: position{line: 292, col: 13, offset: 12047},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 216, col: 13, offset: 8589},
+						pos: position{line: 292, col: 13, offset: 12047},
 						run: (*parser).callonRelation2,
 						expr: &seqExpr{
-							pos: position{line: 216, col: 14, offset: 8590},
+							pos: position{line: 292, col: 14, offset: 12048},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 216, col: 14, offset: 8590},
+									pos:   position{line: 292, col: 14, offset: 12048},
 									label: "e1",
 									expr: &ruleRefExpr{
-										pos:  position{line: 216, col: 17, offset: 8593},
+										pos:  position{line: 292, col: 17, offset: 12051},
 										name: "AdditiveExpr",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 216, col: 30, offset: 8606},
+									pos:  position{line: 292, col: 30, offset: 12064},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 216, col: 35, offset: 8611},
+									pos:   position{line: 292, col: 35, offset: 12069},
 									label: "o",
 									expr: &ruleRefExpr{
-										pos:  position{line: 216, col: 37, offset: 8613},
+										pos:  position{line: 292, col: 37, offset: 12071},
 										name: "RelationOperator",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 216, col: 54, offset: 8630},
+									pos:  position{line: 292, col: 54, offset: 12088},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 216, col: 59, offset: 8635},
+									pos:   position{line: 292, col: 59, offset: 12093},
 									label: "e2",
 									expr: &ruleRefExpr{
-										pos:  position{line: 216, col: 62, offset: 8638},
+										pos:  position{line: 292, col: 62, offset: 12096},
 										name: "AdditiveExpr",
 									},
 								},
@@ -574,40 +1062,40 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 218, col: 5, offset: 8707},
+						pos: position{line: 294, col: 5, offset: 12165},
 						run: (*parser).callonRelation12,
 						expr: &seqExpr{
-							pos: position{line: 218, col: 6, offset: 8708},
+							pos: position{line: 294, col: 6, offset: 12166},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 218, col: 6, offset: 8708},
+									pos:   position{line: 294, col: 6, offset: 12166},
 									label: "e1",
 									expr: &ruleRefExpr{
-										pos:  position{line: 218, col: 9, offset: 8711},
+										pos:  position{line: 294, col: 9, offset: 12169},
 										name: "Term",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 218, col: 14, offset: 8716},
+									pos:  position{line: 294, col: 14, offset: 12174},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 218, col: 19, offset: 8721},
+									pos:   position{line: 294, col: 19, offset: 12179},
 									label: "o",
 									expr: &ruleRefExpr{
-										pos:  position{line: 218, col: 21, offset: 8723},
+										pos:  position{line: 294, col: 21, offset: 12181},
 										name: "EqualityOperator",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 218, col: 38, offset: 8740},
+									pos:  position{line: 294, col: 38, offset: 12198},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 218, col: 43, offset: 8745},
+									pos:   position{line: 294, col: 43, offset: 12203},
 									label: "e2",
 									expr: &ruleRefExpr{
-										pos:  position{line: 218, col: 46, offset: 8748},
+										pos:  position{line: 294, col: 46, offset: 12206},
 										name: "Term",
 									},
 								},
@@ -619,42 +1107,48 @@ var g = &grammar{
 		},
 		{
 			name: "RelationOperator",
-			pos:  position{line: 223, col: 1, offset: 8865},
+			pos:  position{line: 299, col: 1, offset: 12323},
 			expr: &actionExpr{
-				pos: position{line: 223, col: 21, offset: 8885},
+				pos: position{line: 299, col: 21, offset: 12343},
 				run: (*parser).callonRelationOperator1,
 				expr: &choiceExpr{
-					pos: position{line: 223, col: 22, offset: 8886},
+					pos: position{line: 299, col: 22, offset: 12344},
 					alternatives: []interface{}{
 						&litMatcher{
-							pos:        position{line: 223, col: 22, offset: 8886},
+							pos:        position{line: 299, col: 22, offset: 12344},
 							val:        "=<",
 							ignoreCase: false,
+							want:       "\"=<\"",
 						},
 						&litMatcher{
-							pos:        position{line: 223, col: 29, offset: 8893},
+							pos:        position{line: 299, col: 29, offset: 12351},
 							val:        ">=",
 							ignoreCase: false,
+							want:       "\">=\"",
 						},
 						&litMatcher{
-							pos:        position{line: 223, col: 36, offset: 8900},
+							pos:        position{line: 299, col: 36, offset: 12358},
 							val:        "<",
 							ignoreCase: false,
+							want:       "\"<\"",
 						},
 						&litMatcher{
-							pos:        position{line: 223, col: 42, offset: 8906},
+							pos:        position{line: 299, col: 42, offset: 12364},
 							val:        ">",
 							ignoreCase: false,
+							want:       "\">\"",
 						},
 						&litMatcher{
-							pos:        position{line: 223, col: 48, offset: 8912},
+							pos:        position{line: 299, col: 48, offset: 12370},
 							val:        "=",
 							ignoreCase: false,
+							want:       "\"=\"",
 						},
 						&litMatcher{
-							pos:        position{line: 223, col: 54, offset: 8918},
+							pos:        position{line: 299, col: 54, offset: 12376},
 							val:        "\\=",
 							ignoreCase: false,
+							want:       "\"\\\\=\"",
 						},
 					},
 				},
@@ -662,22 +1156,24 @@ var g = &grammar{
 		},
 		{
 			name: "EqualityOperator",
-			pos:  position{line: 229, col: 1, offset: 9084},
+			pos:  position{line: 305, col: 1, offset: 12542},
 			expr: &actionExpr{
-				pos: position{line: 229, col: 21, offset: 9104},
+				pos: position{line: 305, col: 21, offset: 12562},
 				run: (*parser).callonEqualityOperator1,
 				expr: &choiceExpr{
-					pos: position{line: 229, col: 22, offset: 9105},
+					pos: position{line: 305, col: 22, offset: 12563},
 					alternatives: []interface{}{
 						&litMatcher{
-							pos:        position{line: 229, col: 22, offset: 9105},
+							pos:        position{line: 305, col: 22, offset: 12563},
 							val:        "=",
 							ignoreCase: false,
+							want:       "\"=\"",
 						},
 						&litMatcher{
-							pos:        position{line: 229, col: 28, offset: 9111},
+							pos:        position{line: 305, col: 28, offset: 12569},
 							val:        "\\=",
 							ignoreCase: false,
+							want:       "\"\\\\=\"",
 						},
 					},
 				},
@@ -685,60 +1181,164 @@ var g = &grammar{
 		},
 		{
 			name: "AdditiveExpr",
-			pos:  position{line: 234, col: 1, offset: 9227},
-			expr: &choiceExpr{
-				pos: position{line: 234, col: 17, offset: 9243},
-				alternatives: []interface{}{
-					&actionExpr{
-						pos: position{line: 234, col: 17, offset: 9243},
-						run: (*parser).callonAdditiveExpr2,
-						expr: &seqExpr{
-							pos: position{line: 234, col: 17, offset: 9243},
-							exprs: []interface{}{
-								&labeledExpr{
-									pos:   position{line: 234, col: 17, offset: 9243},
-									label: "e1",
-									expr: &ruleRefExpr{
-										pos:  position{line: 234, col: 20, offset: 9246},
-										name: "MultiplicativeExpr",
-									},
-								},
-								&ruleRefExpr{
-									pos:  position{line: 234, col: 39, offset: 9265},
-									name: "Skip",
-								},
-								&labeledExpr{
-									pos:   position{line: 234, col: 44, offset: 9270},
-									label: "o",
-									expr: &ruleRefExpr{
-										pos:  position{line: 234, col: 46, offset: 9272},
-										name: "AdditiveOperator",
+			pos:  position{line: 310, col: 1, offset: 12718},
+			expr: &actionExpr{
+				pos: position{line: 310, col: 17, offset: 12734},
+				run: (*parser).callonAdditiveExpr1,
+				expr: &seqExpr{
+					pos: position{line: 310, col: 17, offset: 12734},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 310, col: 17, offset: 12734},
+							label: "e1",
+							expr: &ruleRefExpr{
+								pos:  position{line: 310, col: 20, offset: 12737},
+								name: "MultiplicativeExpr",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 310, col: 39, offset: 12756},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 310, col: 44, offset: 12761},
+								expr: &seqExpr{
+									pos: position{line: 310, col: 45, offset: 12762},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 310, col: 45, offset: 12762},
+											name: "Skip",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 310, col: 50, offset: 12767},
+											name: "AdditiveOperator",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 310, col: 67, offset: 12784},
+											name: "Skip",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 310, col: 72, offset: 12789},
+											name: "MultiplicativeExpr",
+										},
 									},
 								},
-								&ruleRefExpr{
-									pos:  position{line: 234, col: 63, offset: 9289},
-									name: "Skip",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "AdditiveOperator",
+			pos:  position{line: 319, col: 1, offset: 13115},
+			expr: &actionExpr{
+				pos: position{line: 319, col: 21, offset: 13135},
+				run: (*parser).callonAdditiveOperator1,
+				expr: &choiceExpr{
+					pos: position{line: 319, col: 22, offset: 13136},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 319, col: 22, offset: 13136},
+							val:        "+",
+							ignoreCase: false,
+							want:       "\"+\"",
+						},
+						&litMatcher{
+							pos:        position{line: 319, col: 28, offset: 13142},
+							val:        "-",
+							ignoreCase: false,
+							want:       "\"-\"",
+						},
+						&litMatcher{
+							pos:        position{line: 319, col: 34, offset: 13148},
+							val:        "/\\",
+							ignoreCase: false,
+							want:       "\"/\\\\\"",
+						},
+						&litMatcher{
+							pos:        position{line: 319, col: 42, offset: 13156},
+							val:        "\\/",
+							ignoreCase: false,
+							want:       "\"\\\\/\"",
+						},
+						&seqExpr{
+							pos: position{line: 319, col: 50, offset: 13164},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 319, col: 50, offset: 13164},
+									val:        "xor",
+									ignoreCase: false,
+									want:       "\"xor\"",
 								},
-								&labeledExpr{
-									pos:   position{line: 234, col: 68, offset: 9294},
-									label: "e2",
-									expr: &ruleRefExpr{
-										pos:  position{line: 234, col: 71, offset: 9297},
-										name: "AdditiveExpr",
+								&notExpr{
+									pos: position{line: 319, col: 56, offset: 13170},
+									expr: &choiceExpr{
+										pos: position{line: 319, col: 58, offset: 13172},
+										alternatives: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 319, col: 58, offset: 13172},
+												name: "Lowercase_letter",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 319, col: 77, offset: 13191},
+												name: "Uppercase_letter",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 319, col: 96, offset: 13210},
+												name: "Digit",
+											},
+										},
 									},
 								},
 							},
 						},
 					},
-					&actionExpr{
-						pos: position{line: 248, col: 5, offset: 9699},
-						run: (*parser).callonAdditiveExpr12,
-						expr: &labeledExpr{
-							pos:   position{line: 248, col: 5, offset: 9699},
-							label: "e",
+				},
+			},
+		},
+		{
+			name: "MultiplicativeExpr",
+			pos:  position{line: 321, col: 1, offset: 13107},
+			expr: &actionExpr{
+				pos: position{line: 321, col: 23, offset: 13129},
+				run: (*parser).callonMultiplicativeExpr1,
+				expr: &seqExpr{
+					pos: position{line: 321, col: 23, offset: 13129},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 321, col: 23, offset: 13129},
+							label: "e1",
 							expr: &ruleRefExpr{
-								pos:  position{line: 248, col: 7, offset: 9701},
-								name: "MultiplicativeExpr",
+								pos:  position{line: 321, col: 26, offset: 13132},
+								name: "UnaryExpr",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 321, col: 36, offset: 13142},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 321, col: 41, offset: 13147},
+								expr: &seqExpr{
+									pos: position{line: 321, col: 42, offset: 13148},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 321, col: 42, offset: 13148},
+											name: "Skip",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 321, col: 47, offset: 13153},
+											name: "MultiplicativeOperator",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 321, col: 70, offset: 13176},
+											name: "Skip",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 321, col: 75, offset: 13181},
+											name: "UnaryExpr",
+										},
+									},
+								},
 							},
 						},
 					},
@@ -746,84 +1346,148 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "AdditiveOperator",
-			pos:  position{line: 253, col: 1, offset: 9837},
+			name: "MultiplicativeOperator",
+			pos:  position{line: 333, col: 1, offset: 13796},
 			expr: &actionExpr{
-				pos: position{line: 253, col: 21, offset: 9857},
-				run: (*parser).callonAdditiveOperator1,
+				pos: position{line: 333, col: 27, offset: 13822},
+				run: (*parser).callonMultiplicativeOperator1,
 				expr: &choiceExpr{
-					pos: position{line: 253, col: 22, offset: 9858},
+					pos: position{line: 333, col: 28, offset: 13823},
 					alternatives: []interface{}{
 						&litMatcher{
-							pos:        position{line: 253, col: 22, offset: 9858},
-							val:        "+",
+							pos:        position{line: 333, col: 28, offset: 13823},
+							val:        "*",
 							ignoreCase: false,
+							want:       "\"*\"",
 						},
 						&litMatcher{
-							pos:        position{line: 253, col: 28, offset: 9864},
-							val:        "-",
+							pos:        position{line: 333, col: 34, offset: 13829},
+							val:        "//",
+							ignoreCase: false,
+							want:       "\"//\"",
+						},
+						&seqExpr{
+							pos: position{line: 333, col: 41, offset: 13836},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 333, col: 41, offset: 13836},
+									val:        "/",
+									ignoreCase: false,
+									want:       "\"/\"",
+								},
+								&notExpr{
+									pos: position{line: 333, col: 45, offset: 13840},
+									expr: &litMatcher{
+										pos:        position{line: 333, col: 46, offset: 13841},
+										val:        "\\",
+										ignoreCase: false,
+										want:       "\"\\\\\"",
+									},
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 333, col: 53, offset: 13848},
+							val:        "<<",
 							ignoreCase: false,
+							want:       "\"<<\"",
+						},
+						&litMatcher{
+							pos:        position{line: 333, col: 60, offset: 13855},
+							val:        ">>",
+							ignoreCase: false,
+							want:       "\">>\"",
+						},
+						&seqExpr{
+							pos: position{line: 333, col: 67, offset: 13862},
+							exprs: []interface{}{
+								&choiceExpr{
+									pos: position{line: 333, col: 68, offset: 13863},
+									alternatives: []interface{}{
+										&litMatcher{
+											pos:        position{line: 333, col: 68, offset: 13863},
+											val:        "mod",
+											ignoreCase: false,
+											want:       "\"mod\"",
+										},
+										&litMatcher{
+											pos:        position{line: 333, col: 76, offset: 13871},
+											val:        "rem",
+											ignoreCase: false,
+											want:       "\"rem\"",
+										},
+									},
+								},
+								&notExpr{
+									pos: position{line: 333, col: 83, offset: 13878},
+									expr: &choiceExpr{
+										pos: position{line: 333, col: 85, offset: 13880},
+										alternatives: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 333, col: 85, offset: 13880},
+												name: "Lowercase_letter",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 333, col: 104, offset: 13899},
+												name: "Uppercase_letter",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 333, col: 123, offset: 13918},
+												name: "Digit",
+											},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "MultiplicativeExpr",
-			pos:  position{line: 258, col: 1, offset: 9988},
+			name: "UnaryExpr",
+			pos:  position{line: 331, col: 1, offset: 13464},
 			expr: &choiceExpr{
-				pos: position{line: 258, col: 23, offset: 10010},
+				pos: position{line: 331, col: 14, offset: 13477},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 258, col: 23, offset: 10010},
-						run: (*parser).callonMultiplicativeExpr2,
+						pos: position{line: 331, col: 14, offset: 13477},
+						run: (*parser).callonUnaryExpr2,
 						expr: &seqExpr{
-							pos: position{line: 258, col: 23, offset: 10010},
+							pos: position{line: 331, col: 14, offset: 13477},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 258, col: 23, offset: 10010},
-									label: "e1",
-									expr: &ruleRefExpr{
-										pos:  position{line: 258, col: 26, offset: 10013},
-										name: "UnaryExpr",
-									},
-								},
-								&ruleRefExpr{
-									pos:  position{line: 258, col: 36, offset: 10023},
-									name: "Skip",
-								},
-								&labeledExpr{
-									pos:   position{line: 258, col: 41, offset: 10028},
+									pos:   position{line: 331, col: 14, offset: 13477},
 									label: "o",
 									expr: &ruleRefExpr{
-										pos:  position{line: 258, col: 43, offset: 10030},
-										name: "MultiplicativeOperator",
+										pos:  position{line: 331, col: 16, offset: 13479},
+										name: "UnaryOperator",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 258, col: 66, offset: 10053},
+									pos:  position{line: 331, col: 30, offset: 13493},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 258, col: 71, offset: 10058},
-									label: "e2",
+									pos:   position{line: 331, col: 35, offset: 13498},
+									label: "e",
 									expr: &ruleRefExpr{
-										pos:  position{line: 258, col: 74, offset: 10061},
-										name: "MultiplicativeExpr",
+										pos:  position{line: 331, col: 37, offset: 13500},
+										name: "PrimaryExpr",
 									},
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 272, col: 5, offset: 10475},
-						run: (*parser).callonMultiplicativeExpr12,
+						pos: position{line: 344, col: 5, offset: 13866},
+						run: (*parser).callonUnaryExpr9,
 						expr: &labeledExpr{
-							pos:   position{line: 272, col: 5, offset: 10475},
+							pos:   position{line: 344, col: 5, offset: 13866},
 							label: "e",
 							expr: &ruleRefExpr{
-								pos:  position{line: 272, col: 7, offset: 10477},
-								name: "UnaryExpr",
+								pos:  position{line: 344, col: 7, offset: 13868},
+								name: "PrimaryExpr",
 							},
 						},
 					},
@@ -831,142 +1495,213 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "MultiplicativeOperator",
-			pos:  position{line: 277, col: 1, offset: 10615},
+			name: "UnaryOperator",
+			pos:  position{line: 356, col: 1, offset: 14602},
 			expr: &actionExpr{
-				pos: position{line: 277, col: 27, offset: 10641},
-				run: (*parser).callonMultiplicativeOperator1,
-				expr: &litMatcher{
-					pos:        position{line: 277, col: 27, offset: 10641},
-					val:        "*",
-					ignoreCase: false,
+				pos: position{line: 356, col: 18, offset: 14619},
+				run: (*parser).callonUnaryOperator1,
+				expr: &choiceExpr{
+					pos: position{line: 356, col: 19, offset: 14620},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 356, col: 19, offset: 14620},
+							val:        "-",
+							ignoreCase: false,
+							want:       "\"-\"",
+						},
+						&litMatcher{
+							pos:        position{line: 356, col: 25, offset: 14626},
+							val:        "\\",
+							ignoreCase: false,
+							want:       "\"\\\\\"",
+						},
+					},
 				},
 			},
 		},
 		{
-			name: "UnaryExpr",
-			pos:  position{line: 282, col: 1, offset: 10765},
+			name: "PrimaryExpr",
+			pos:  position{line: 381, col: 1, offset: 15577},
 			expr: &choiceExpr{
-				pos: position{line: 282, col: 14, offset: 10778},
+				pos: position{line: 381, col: 16, offset: 15592},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 282, col: 14, offset: 10778},
-						run: (*parser).callonUnaryExpr2,
+						pos: position{line: 381, col: 16, offset: 15592},
+						run: (*parser).callonPrimaryExpr2,
+						expr: &seqExpr{
+							pos: position{line: 381, col: 16, offset: 15592},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 381, col: 16, offset: 15592},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 381, col: 20, offset: 15596},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 381, col: 25, offset: 15601},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 381, col: 27, offset: 15603},
+										name: "AdditiveExpr",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 381, col: 40, offset: 15616},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 381, col: 45, offset: 15621},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 383, col: 5, offset: 15698},
+						run: (*parser).callonPrimaryExpr10,
 						expr: &seqExpr{
-							pos: position{line: 282, col: 14, offset: 10778},
+							pos: position{line: 383, col: 5, offset: 15698},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 282, col: 14, offset: 10778},
-									label: "o",
+									pos:   position{line: 383, col: 5, offset: 15698},
+									label: "f",
 									expr: &ruleRefExpr{
-										pos:  position{line: 282, col: 16, offset: 10780},
-										name: "UnaryOperator",
+										pos:  position{line: 383, col: 7, offset: 15700},
+										name: "UnaryFunction",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 282, col: 30, offset: 10794},
+									pos:  position{line: 383, col: 21, offset: 15714},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 383, col: 26, offset: 15719},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 383, col: 30, offset: 15723},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 282, col: 35, offset: 10799},
-									label: "e",
+									pos:   position{line: 383, col: 35, offset: 15728},
+									label: "a",
 									expr: &ruleRefExpr{
-										pos:  position{line: 282, col: 37, offset: 10801},
-										name: "PrimaryExpr",
+										pos:  position{line: 383, col: 37, offset: 15730},
+										name: "AdditiveExpr",
 									},
 								},
+								&ruleRefExpr{
+									pos:  position{line: 383, col: 50, offset: 15743},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 383, col: 55, offset: 15748},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 295, col: 5, offset: 11167},
-						run: (*parser).callonUnaryExpr9,
-						expr: &labeledExpr{
-							pos:   position{line: 295, col: 5, offset: 11167},
-							label: "e",
-							expr: &ruleRefExpr{
-								pos:  position{line: 295, col: 7, offset: 11169},
-								name: "PrimaryExpr",
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name: "UnaryOperator",
-			pos:  position{line: 300, col: 1, offset: 11295},
-			expr: &actionExpr{
-				pos: position{line: 300, col: 18, offset: 11312},
-				run: (*parser).callonUnaryOperator1,
-				expr: &litMatcher{
-					pos:        position{line: 300, col: 18, offset: 11312},
-					val:        "-",
-					ignoreCase: false,
-				},
-			},
-		},
-		{
-			name: "PrimaryExpr",
-			pos:  position{line: 305, col: 1, offset: 11450},
-			expr: &choiceExpr{
-				pos: position{line: 305, col: 16, offset: 11465},
-				alternatives: []interface{}{
-					&actionExpr{
-						pos: position{line: 305, col: 16, offset: 11465},
-						run: (*parser).callonPrimaryExpr2,
+						pos: position{line: 385, col: 5, offset: 15804},
+						run: (*parser).callonPrimaryExpr21,
 						expr: &seqExpr{
-							pos: position{line: 305, col: 16, offset: 11465},
+							pos: position{line: 385, col: 5, offset: 15804},
 							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 385, col: 5, offset: 15804},
+									label: "f",
+									expr: &ruleRefExpr{
+										pos:  position{line: 385, col: 7, offset: 15806},
+										name: "BinaryFunction",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 385, col: 22, offset: 15821},
+									name: "Skip",
+								},
 								&litMatcher{
-									pos:        position{line: 305, col: 16, offset: 11465},
+									pos:        position{line: 385, col: 27, offset: 15826},
 									val:        "(",
 									ignoreCase: false,
+									want:       "\"(\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 305, col: 20, offset: 11469},
+									pos:  position{line: 385, col: 31, offset: 15830},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 305, col: 25, offset: 11474},
-									label: "a",
+									pos:   position{line: 385, col: 36, offset: 15835},
+									label: "a1",
+									expr: &ruleRefExpr{
+										pos:  position{line: 385, col: 39, offset: 15838},
+										name: "AdditiveExpr",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 385, col: 52, offset: 15851},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 385, col: 57, offset: 15856},
+									val:        ",",
+									ignoreCase: false,
+									want:       "\",\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 385, col: 61, offset: 15860},
+									name: "Skip",
+								},
+								&labeledExpr{
+									pos:   position{line: 385, col: 66, offset: 15865},
+									label: "a2",
 									expr: &ruleRefExpr{
-										pos:  position{line: 305, col: 27, offset: 11476},
+										pos:  position{line: 385, col: 69, offset: 15868},
 										name: "AdditiveExpr",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 305, col: 40, offset: 11489},
+									pos:  position{line: 385, col: 82, offset: 15881},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 305, col: 45, offset: 11494},
+									pos:        position{line: 385, col: 87, offset: 15886},
 									val:        ")",
 									ignoreCase: false,
+									want:       "\")\"",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 307, col: 5, offset: 11571},
-						run: (*parser).callonPrimaryExpr10,
+						pos: position{line: 387, col: 5, offset: 15947},
+						run: (*parser).callonPrimaryExpr37,
 						expr: &labeledExpr{
-							pos:   position{line: 307, col: 5, offset: 11571},
+							pos:   position{line: 387, col: 5, offset: 15947},
 							label: "n",
 							expr: &ruleRefExpr{
-								pos:  position{line: 307, col: 7, offset: 11573},
+								pos:  position{line: 387, col: 7, offset: 15949},
 								name: "Numeral",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 309, col: 5, offset: 11652},
-						run: (*parser).callonPrimaryExpr13,
+						pos: position{line: 389, col: 5, offset: 16028},
+						run: (*parser).callonPrimaryExpr40,
 						expr: &labeledExpr{
-							pos:   position{line: 309, col: 5, offset: 11652},
+							pos:   position{line: 389, col: 5, offset: 16028},
 							label: "v",
 							expr: &ruleRefExpr{
-								pos:  position{line: 309, col: 7, offset: 11654},
+								pos:  position{line: 389, col: 7, offset: 16030},
 								name: "Variable",
 							},
 						},
@@ -974,44 +1709,95 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "UnaryFunction",
+			pos:  position{line: 395, col: 1, offset: 16239},
+			expr: &actionExpr{
+				pos: position{line: 395, col: 18, offset: 16256},
+				run: (*parser).callonUnaryFunction1,
+				expr: &choiceExpr{
+					pos: position{line: 395, col: 19, offset: 16257},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 395, col: 19, offset: 16257},
+							val:        "abs",
+							ignoreCase: false,
+							want:       "\"abs\"",
+						},
+						&litMatcher{
+							pos:        position{line: 395, col: 27, offset: 16265},
+							val:        "sign",
+							ignoreCase: false,
+							want:       "\"sign\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "BinaryFunction",
+			pos:  position{line: 400, col: 1, offset: 16407},
+			expr: &actionExpr{
+				pos: position{line: 400, col: 19, offset: 16425},
+				run: (*parser).callonBinaryFunction1,
+				expr: &choiceExpr{
+					pos: position{line: 400, col: 20, offset: 16426},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 400, col: 20, offset: 16426},
+							val:        "min",
+							ignoreCase: false,
+							want:       "\"min\"",
+						},
+						&litMatcher{
+							pos:        position{line: 400, col: 28, offset: 16434},
+							val:        "max",
+							ignoreCase: false,
+							want:       "\"max\"",
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "TermList",
-			pos:  position{line: 314, col: 1, offset: 11777},
+			pos:  position{line: 363, col: 1, offset: 14476},
 			expr: &choiceExpr{
-				pos: position{line: 314, col: 13, offset: 11789},
+				pos: position{line: 363, col: 13, offset: 14488},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 314, col: 13, offset: 11789},
+						pos: position{line: 363, col: 13, offset: 14488},
 						run: (*parser).callonTermList2,
 						expr: &seqExpr{
-							pos: position{line: 314, col: 13, offset: 11789},
+							pos: position{line: 363, col: 13, offset: 14488},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 314, col: 13, offset: 11789},
+									pos:   position{line: 363, col: 13, offset: 14488},
 									label: "t",
 									expr: &ruleRefExpr{
-										pos:  position{line: 314, col: 15, offset: 11791},
+										pos:  position{line: 363, col: 15, offset: 14490},
 										name: "Term",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 314, col: 20, offset: 11796},
+									pos:  position{line: 363, col: 20, offset: 14495},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 314, col: 25, offset: 11801},
+									pos:        position{line: 363, col: 25, offset: 14500},
 									val:        ",",
 									ignoreCase: false,
+									want:       "\",\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 314, col: 29, offset: 11805},
+									pos:  position{line: 363, col: 29, offset: 14504},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 314, col: 34, offset: 11810},
+									pos:   position{line: 363, col: 34, offset: 14509},
 									label: "ts",
 									expr: &ruleRefExpr{
-										pos:  position{line: 314, col: 37, offset: 11813},
+										pos:  position{line: 363, col: 37, offset: 14512},
 										name: "TermList",
 									},
 								},
@@ -1019,13 +1805,13 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 316, col: 5, offset: 11890},
+						pos: position{line: 365, col: 5, offset: 14589},
 						run: (*parser).callonTermList11,
 						expr: &labeledExpr{
-							pos:   position{line: 316, col: 5, offset: 11890},
+							pos:   position{line: 365, col: 5, offset: 14589},
 							label: "t",
 							expr: &ruleRefExpr{
-								pos:  position{line: 316, col: 7, offset: 11892},
+								pos:  position{line: 365, col: 7, offset: 14591},
 								name: "Term",
 							},
 						},
@@ -1035,34 +1821,34 @@ var g = &grammar{
 		},
 		{
 			name: "Term",
-			pos:  position{line: 321, col: 1, offset: 12005},
+			pos:  position{line: 370, col: 1, offset: 14704},
 			expr: &actionExpr{
-				pos: position{line: 321, col: 9, offset: 12013},
+				pos: position{line: 370, col: 9, offset: 14712},
 				run: (*parser).callonTerm1,
 				expr: &labeledExpr{
-					pos:   position{line: 321, col: 9, offset: 12013},
+					pos:   position{line: 370, col: 9, offset: 14712},
 					label: "child",
 					expr: &choiceExpr{
-						pos: position{line: 321, col: 16, offset: 12020},
+						pos: position{line: 370, col: 16, offset: 14719},
 						alternatives: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 321, col: 16, offset: 12020},
+								pos:  position{line: 370, col: 16, offset: 14719},
 								name: "Numeral",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 321, col: 26, offset: 12030},
+								pos:  position{line: 370, col: 26, offset: 14729},
 								name: "Structure",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 321, col: 38, offset: 12042},
+								pos:  position{line: 370, col: 38, offset: 14741},
 								name: "Atom",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 321, col: 45, offset: 12049},
+								pos:  position{line: 370, col: 45, offset: 14748},
 								name: "Variable",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 321, col: 56, offset: 12060},
+								pos:  position{line: 370, col: 56, offset: 14759},
 								name: "List",
 							},
 						},
@@ -1072,97 +1858,127 @@ var g = &grammar{
 		},
 		{
 			name: "List",
-			pos:  position{line: 327, col: 1, offset: 12230},
+			pos:  position{line: 376, col: 1, offset: 14929},
 			expr: &choiceExpr{
-				pos: position{line: 327, col: 9, offset: 12238},
+				pos: position{line: 376, col: 9, offset: 14937},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 327, col: 9, offset: 12238},
+						pos: position{line: 376, col: 9, offset: 14937},
 						run: (*parser).callonList2,
 						expr: &seqExpr{
-							pos: position{line: 327, col: 9, offset: 12238},
+							pos: position{line: 376, col: 9, offset: 14937},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 327, col: 9, offset: 12238},
+									pos:        position{line: 376, col: 9, offset: 14937},
 									val:        "[",
 									ignoreCase: false,
+									want:       "\"[\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 327, col: 13, offset: 12242},
+									pos:  position{line: 376, col: 13, offset: 14941},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 327, col: 18, offset: 12247},
+									pos:   position{line: 376, col: 18, offset: 14946},
 									label: "h",
 									expr: &ruleRefExpr{
-										pos:  position{line: 327, col: 20, offset: 12249},
+										pos:  position{line: 376, col: 20, offset: 14948},
 										name: "TermList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 327, col: 29, offset: 12258},
+									pos:  position{line: 376, col: 29, offset: 14957},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 327, col: 34, offset: 12263},
+									pos:        position{line: 376, col: 34, offset: 14962},
 									val:        "|",
 									ignoreCase: false,
+									want:       "\"|\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 327, col: 38, offset: 12267},
+									pos:  position{line: 376, col: 38, offset: 14966},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 327, col: 43, offset: 12272},
+									pos:   position{line: 376, col: 43, offset: 14971},
 									label: "t",
 									expr: &ruleRefExpr{
-										pos:  position{line: 327, col: 45, offset: 12274},
+										pos:  position{line: 376, col: 45, offset: 14973},
 										name: "ListTail",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 327, col: 54, offset: 12283},
+									pos:  position{line: 376, col: 54, offset: 14982},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 327, col: 59, offset: 12288},
+									pos:        position{line: 376, col: 59, offset: 14987},
 									val:        "]",
 									ignoreCase: false,
+									want:       "\"]\"",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 330, col: 5, offset: 12385},
+						pos: position{line: 379, col: 5, offset: 15084},
 						run: (*parser).callonList15,
 						expr: &seqExpr{
-							pos: position{line: 330, col: 5, offset: 12385},
+							pos: position{line: 379, col: 5, offset: 15084},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 330, col: 5, offset: 12385},
+									pos:        position{line: 379, col: 5, offset: 15084},
 									val:        "[",
 									ignoreCase: false,
+									want:       "\"[\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 330, col: 9, offset: 12389},
+									pos:  position{line: 379, col: 9, offset: 15088},
 									name: "Skip",
 								},
 								&labeledExpr{
-									pos:   position{line: 330, col: 14, offset: 12394},
+									pos:   position{line: 379, col: 14, offset: 15093},
 									label: "h",
 									expr: &ruleRefExpr{
-										pos:  position{line: 330, col: 16, offset: 12396},
+										pos:  position{line: 379, col: 16, offset: 15095},
 										name: "TermList",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 330, col: 25, offset: 12405},
+									pos:  position{line: 379, col: 25, offset: 15104},
+									name: "Skip",
+								},
+								&litMatcher{
+									pos:        position{line: 379, col: 30, offset: 15109},
+									val:        "]",
+									ignoreCase: false,
+									want:       "\"]\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 382, col: 5, offset: 15205},
+						run: (*parser).callonList23,
+						expr: &seqExpr{
+							pos: position{line: 382, col: 5, offset: 15205},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 382, col: 5, offset: 15205},
+									val:        "[",
+									ignoreCase: false,
+									want:       "\"[\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 382, col: 9, offset: 15209},
 									name: "Skip",
 								},
 								&litMatcher{
-									pos:        position{line: 330, col: 30, offset: 12410},
+									pos:        position{line: 382, col: 14, offset: 15214},
 									val:        "]",
 									ignoreCase: false,
+									want:       "\"]\"",
 								},
 							},
 						},
@@ -1172,15 +1988,15 @@ var g = &grammar{
 		},
 		{
 			name: "ListTail",
-			pos:  position{line: 336, col: 1, offset: 12568},
+			pos:  position{line: 388, col: 1, offset: 15370},
 			expr: &actionExpr{
-				pos: position{line: 336, col: 13, offset: 12580},
+				pos: position{line: 388, col: 13, offset: 15382},
 				run: (*parser).callonListTail1,
 				expr: &labeledExpr{
-					pos:   position{line: 336, col: 13, offset: 12580},
+					pos:   position{line: 388, col: 13, offset: 15382},
 					label: "v",
 					expr: &ruleRefExpr{
-						pos:  position{line: 336, col: 15, offset: 12582},
+						pos:  position{line: 388, col: 15, offset: 15384},
 						name: "Variable",
 					},
 				},
@@ -1188,50 +2004,52 @@ var g = &grammar{
 		},
 		{
 			name: "Structure",
-			pos:  position{line: 341, col: 1, offset: 12704},
+			pos:  position{line: 393, col: 1, offset: 15506},
 			expr: &actionExpr{
-				pos: position{line: 341, col: 14, offset: 12717},
+				pos: position{line: 393, col: 14, offset: 15519},
 				run: (*parser).callonStructure1,
 				expr: &seqExpr{
-					pos: position{line: 341, col: 14, offset: 12717},
+					pos: position{line: 393, col: 14, offset: 15519},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 341, col: 14, offset: 12717},
+							pos:   position{line: 393, col: 14, offset: 15519},
 							label: "a",
 							expr: &ruleRefExpr{
-								pos:  position{line: 341, col: 16, offset: 12719},
+								pos:  position{line: 393, col: 16, offset: 15521},
 								name: "Atom",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 341, col: 21, offset: 12724},
+							pos:  position{line: 393, col: 21, offset: 15526},
 							name: "Skip",
 						},
 						&litMatcher{
-							pos:        position{line: 341, col: 26, offset: 12729},
+							pos:        position{line: 393, col: 26, offset: 15531},
 							val:        "(",
 							ignoreCase: false,
+							want:       "\"(\"",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 341, col: 30, offset: 12733},
+							pos:  position{line: 393, col: 30, offset: 15535},
 							name: "Skip",
 						},
 						&labeledExpr{
-							pos:   position{line: 341, col: 35, offset: 12738},
+							pos:   position{line: 393, col: 35, offset: 15540},
 							label: "ts",
 							expr: &ruleRefExpr{
-								pos:  position{line: 341, col: 38, offset: 12741},
+								pos:  position{line: 393, col: 38, offset: 15543},
 								name: "TermList",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 341, col: 47, offset: 12750},
+							pos:  position{line: 393, col: 47, offset: 15552},
 							name: "Skip",
 						},
 						&litMatcher{
-							pos:        position{line: 341, col: 52, offset: 12755},
+							pos:        position{line: 393, col: 52, offset: 15557},
 							val:        ")",
 							ignoreCase: false,
+							want:       "\")\"",
 						},
 					},
 				},
@@ -1239,19 +2057,19 @@ var g = &grammar{
 		},
 		{
 			name: "Variable",
-			pos:  position{line: 346, col: 1, offset: 12871},
+			pos:  position{line: 398, col: 1, offset: 15673},
 			expr: &actionExpr{
-				pos: position{line: 346, col: 13, offset: 12883},
+				pos: position{line: 398, col: 13, offset: 15685},
 				run: (*parser).callonVariable1,
 				expr: &seqExpr{
-					pos: position{line: 346, col: 13, offset: 12883},
+					pos: position{line: 398, col: 13, offset: 15685},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 346, col: 13, offset: 12883},
+							pos:  position{line: 398, col: 13, offset: 15685},
 							name: "Uppercase_letter",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 346, col: 30, offset: 12900},
+							pos:  position{line: 398, col: 30, offset: 15702},
 							name: "Symbol_trailer",
 						},
 					},
@@ -1260,23 +2078,23 @@ var g = &grammar{
 		},
 		{
 			name: "Atom",
-			pos:  position{line: 351, col: 1, offset: 13025},
+			pos:  position{line: 403, col: 1, offset: 15827},
 			expr: &choiceExpr{
-				pos: position{line: 351, col: 9, offset: 13033},
+				pos: position{line: 403, col: 9, offset: 15835},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 351, col: 9, offset: 13033},
+						pos: position{line: 403, col: 9, offset: 15835},
 						run: (*parser).callonAtom2,
 						expr: &ruleRefExpr{
-							pos:  position{line: 351, col: 9, offset: 13033},
+							pos:  position{line: 403, col: 9, offset: 15835},
 							name: "Small_atom",
 						},
 					},
 					&actionExpr{
-						pos: position{line: 353, col: 5, offset: 13111},
+						pos: position{line: 405, col: 5, offset: 15913},
 						run: (*parser).callonAtom4,
 						expr: &ruleRefExpr{
-							pos:  position{line: 353, col: 5, offset: 13111},
+							pos:  position{line: 405, col: 5, offset: 15913},
 							name: "Single_quoted_string",
 						},
 					},
@@ -1285,50 +2103,53 @@ var g = &grammar{
 		},
 		{
 			name: "Single_quoted_string",
-			pos:  position{line: 364, col: 1, offset: 13355},
+			pos:  position{line: 416, col: 1, offset: 16157},
 			expr: &seqExpr{
-				pos: position{line: 364, col: 25, offset: 13379},
+				pos: position{line: 416, col: 25, offset: 16181},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 364, col: 25, offset: 13379},
+						pos:        position{line: 416, col: 25, offset: 16181},
 						val:        "'",
 						ignoreCase: false,
+						want:       "\"'\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 364, col: 29, offset: 13383},
+						pos: position{line: 416, col: 29, offset: 16185},
 						expr: &ruleRefExpr{
-							pos:  position{line: 364, col: 29, offset: 13383},
+							pos:  position{line: 416, col: 29, offset: 16185},
 							name: "Single_quoted_string_char",
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 364, col: 56, offset: 13410},
+						pos:        position{line: 416, col: 56, offset: 16212},
 						val:        "'",
 						ignoreCase: false,
+						want:       "\"'\"",
 					},
 				},
 			},
 		},
 		{
 			name: "Single_quoted_string_char",
-			pos:  position{line: 366, col: 1, offset: 13415},
+			pos:  position{line: 418, col: 1, offset: 16217},
 			expr: &choiceExpr{
-				pos: position{line: 366, col: 30, offset: 13444},
+				pos: position{line: 418, col: 30, offset: 16246},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 366, col: 30, offset: 13444},
+						pos:  position{line: 418, col: 30, offset: 16246},
 						name: "Character",
 					},
 					&seqExpr{
-						pos: position{line: 366, col: 42, offset: 13456},
+						pos: position{line: 418, col: 42, offset: 16258},
 						exprs: []interface{}{
 							&litMatcher{
-								pos:        position{line: 366, col: 42, offset: 13456},
+								pos:        position{line: 418, col: 42, offset: 16258},
 								val:        "\\",
 								ignoreCase: false,
+								want:       "\"\\\\\"",
 							},
 							&anyMatcher{
-								line: 366, col: 47, offset: 13461,
+								line: 418, col: 47, offset: 16263,
 							},
 						},
 					},
@@ -1337,19 +2158,19 @@ var g = &grammar{
 		},
 		{
 			name: "Small_atom",
-			pos:  position{line: 368, col: 1, offset: 13464},
+			pos:  position{line: 420, col: 1, offset: 16266},
 			expr: &actionExpr{
-				pos: position{line: 368, col: 15, offset: 13478},
+				pos: position{line: 420, col: 15, offset: 16280},
 				run: (*parser).callonSmall_atom1,
 				expr: &seqExpr{
-					pos: position{line: 368, col: 15, offset: 13478},
+					pos: position{line: 420, col: 15, offset: 16280},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 368, col: 15, offset: 13478},
+							pos:  position{line: 420, col: 15, offset: 16280},
 							name: "Lowercase_letter",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 368, col: 32, offset: 13495},
+							pos:  position{line: 420, col: 32, offset: 16297},
 							name: "Symbol_trailer",
 						},
 					},
@@ -1358,22 +2179,22 @@ var g = &grammar{
 		},
 		{
 			name: "Symbol_trailer",
-			pos:  position{line: 372, col: 1, offset: 13550},
+			pos:  position{line: 424, col: 1, offset: 16352},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 372, col: 19, offset: 13568},
+				pos: position{line: 424, col: 19, offset: 16370},
 				expr: &choiceExpr{
-					pos: position{line: 372, col: 20, offset: 13569},
+					pos: position{line: 424, col: 20, offset: 16371},
 					alternatives: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 372, col: 20, offset: 13569},
+							pos:  position{line: 424, col: 20, offset: 16371},
 							name: "Lowercase_letter",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 372, col: 39, offset: 13588},
+							pos:  position{line: 424, col: 39, offset: 16390},
 							name: "Uppercase_letter",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 372, col: 58, offset: 13607},
+							pos:  position{line: 424, col: 58, offset: 16409},
 							name: "Digit",
 						},
 					},
@@ -1382,24 +2203,24 @@ var g = &grammar{
 		},
 		{
 			name: "Character",
-			pos:  position{line: 374, col: 1, offset: 13616},
+			pos:  position{line: 426, col: 1, offset: 16418},
 			expr: &choiceExpr{
-				pos: position{line: 374, col: 14, offset: 13629},
+				pos: position{line: 426, col: 14, offset: 16431},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 374, col: 14, offset: 13629},
+						pos:  position{line: 426, col: 14, offset: 16431},
 						name: "Lowercase_letter",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 374, col: 33, offset: 13648},
+						pos:  position{line: 426, col: 33, offset: 16450},
 						name: "Uppercase_letter",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 374, col: 52, offset: 13667},
+						pos:  position{line: 426, col: 52, offset: 16469},
 						name: "Digit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 374, col: 60, offset: 13675},
+						pos:  position{line: 426, col: 60, offset: 16477},
 						name: "Not_single_quote",
 					},
 				},
@@ -1407,9 +2228,9 @@ var g = &grammar{
 		},
 		{
 			name: "Lowercase_letter",
-			pos:  position{line: 376, col: 1, offset: 13693},
+			pos:  position{line: 428, col: 1, offset: 16495},
 			expr: &charClassMatcher{
-				pos:        position{line: 376, col: 21, offset: 13713},
+				pos:        position{line: 428, col: 21, offset: 16515},
 				val:        "[\\p{Ll}]",
 				classes:    []*unicode.RangeTable{rangeTable("Ll")},
 				ignoreCase: false,
@@ -1418,9 +2239,9 @@ var g = &grammar{
 		},
 		{
 			name: "Uppercase_letter",
-			pos:  position{line: 378, col: 1, offset: 13723},
+			pos:  position{line: 430, col: 1, offset: 16525},
 			expr: &charClassMatcher{
-				pos:        position{line: 378, col: 21, offset: 13743},
+				pos:        position{line: 430, col: 21, offset: 16545},
 				val:        "[\\p{Lu}_]",
 				chars:      []rune{'_'},
 				classes:    []*unicode.RangeTable{rangeTable("Lu")},
@@ -1430,9 +2251,9 @@ var g = &grammar{
 		},
 		{
 			name: "Digit",
-			pos:  position{line: 380, col: 1, offset: 13754},
+			pos:  position{line: 432, col: 1, offset: 16556},
 			expr: &charClassMatcher{
-				pos:        position{line: 380, col: 10, offset: 13763},
+				pos:        position{line: 432, col: 10, offset: 16565},
 				val:        "[\\p{Nd}]",
 				classes:    []*unicode.RangeTable{rangeTable("Nd")},
 				ignoreCase: false,
@@ -1441,9 +2262,9 @@ var g = &grammar{
 		},
 		{
 			name: "Whitespace",
-			pos:  position{line: 382, col: 1, offset: 13773},
+			pos:  position{line: 434, col: 1, offset: 16575},
 			expr: &charClassMatcher{
-				pos:        position{line: 382, col: 15, offset: 13787},
+				pos:        position{line: 434, col: 15, offset: 16589},
 				val:        "[\\p{Zs}\\n\\r\\t]",
 				chars:      []rune{'\n', '\r', '\t'},
 				classes:    []*unicode.RangeTable{rangeTable("Zs")},
@@ -1453,19 +2274,20 @@ var g = &grammar{
 		},
 		{
 			name: "One_line_comment",
-			pos:  position{line: 384, col: 1, offset: 13803},
+			pos:  position{line: 436, col: 1, offset: 16605},
 			expr: &seqExpr{
-				pos: position{line: 384, col: 21, offset: 13823},
+				pos: position{line: 436, col: 21, offset: 16625},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 384, col: 21, offset: 13823},
+						pos:        position{line: 436, col: 21, offset: 16625},
 						val:        "%",
 						ignoreCase: false,
+						want:       "\"%\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 384, col: 25, offset: 13827},
+						pos: position{line: 436, col: 25, offset: 16629},
 						expr: &charClassMatcher{
-							pos:        position{line: 384, col: 25, offset: 13827},
+							pos:        position{line: 436, col: 25, offset: 16629},
 							val:        "[^\\n\\r]",
 							chars:      []rune{'\n', '\r'},
 							ignoreCase: false,
@@ -1473,61 +2295,66 @@ var g = &grammar{
 						},
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 384, col: 34, offset: 13836},
+						pos: position{line: 436, col: 34, offset: 16638},
 						expr: &litMatcher{
-							pos:        position{line: 384, col: 34, offset: 13836},
+							pos:        position{line: 436, col: 34, offset: 16638},
 							val:        "\r",
 							ignoreCase: false,
+							want:       "\"\\r\"",
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 384, col: 40, offset: 13842},
+						pos:        position{line: 436, col: 40, offset: 16644},
 						val:        "\n",
 						ignoreCase: false,
+						want:       "\"\\n\"",
 					},
 				},
 			},
 		},
 		{
 			name: "Multi_line_comment",
-			pos:  position{line: 386, col: 1, offset: 13848},
+			pos:  position{line: 438, col: 1, offset: 16650},
 			expr: &seqExpr{
-				pos: position{line: 386, col: 23, offset: 13870},
+				pos: position{line: 438, col: 23, offset: 16672},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 386, col: 23, offset: 13870},
+						pos:        position{line: 438, col: 23, offset: 16672},
 						val:        "/*",
 						ignoreCase: false,
+						want:       "\"/*\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 386, col: 28, offset: 13875},
+						pos: position{line: 438, col: 28, offset: 16677},
 						expr: &choiceExpr{
-							pos: position{line: 386, col: 29, offset: 13876},
+							pos: position{line: 438, col: 29, offset: 16678},
 							alternatives: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 386, col: 29, offset: 13876},
+									pos:  position{line: 438, col: 29, offset: 16678},
 									name: "Multi_line_comment",
 								},
 								&seqExpr{
-									pos: position{line: 386, col: 50, offset: 13897},
+									pos: position{line: 438, col: 50, offset: 16699},
 									exprs: []interface{}{
 										&litMatcher{
-											pos:        position{line: 386, col: 50, offset: 13897},
+											pos:        position{line: 438, col: 50, offset: 16699},
 											val:        "*",
 											ignoreCase: false,
+											want:       "\"*\"",
 										},
 										&notExpr{
-											pos: position{line: 386, col: 54, offset: 13901},
+											pos: position{line: 438, col: 54, offset: 16703},
 											expr: &litMatcher{
-												pos:        position{line: 386, col: 55, offset: 13902},
+												pos:        position{line: 438, col: 55, offset: 16704},
 												val:        "/",
 												ignoreCase: false,
+												want:       "\"/\"",
 											},
 										},
 									},
 								},
 								&charClassMatcher{
-									pos:        position{line: 386, col: 61, offset: 13908},
+									pos:        position{line: 438, col: 61, offset: 16710},
 									val:        "[^*]",
 									chars:      []rune{'*'},
 									ignoreCase: false,
@@ -1537,31 +2364,32 @@ var g = &grammar{
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 386, col: 68, offset: 13915},
+						pos:        position{line: 438, col: 68, offset: 16717},
 						val:        "*/",
 						ignoreCase: false,
+						want:       "\"*/\"",
 					},
 				},
 			},
 		},
 		{
 			name: "Skip",
-			pos:  position{line: 389, col: 1, offset: 13998},
+			pos:  position{line: 441, col: 1, offset: 16800},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 389, col: 9, offset: 14006},
+				pos: position{line: 441, col: 9, offset: 16808},
 				expr: &choiceExpr{
-					pos: position{line: 389, col: 10, offset: 14007},
+					pos: position{line: 441, col: 10, offset: 16809},
 					alternatives: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 389, col: 10, offset: 14007},
+							pos:  position{line: 441, col: 10, offset: 16809},
 							name: "Whitespace",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 389, col: 23, offset: 14020},
+							pos:  position{line: 441, col: 23, offset: 16822},
 							name: "One_line_comment",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 389, col: 42, offset: 14039},
+							pos:  position{line: 441, col: 42, offset: 16841},
 							name: "Multi_line_comment",
 						},
 					},
@@ -1570,24 +2398,38 @@ var g = &grammar{
 		},
 		{
 			name: "Numeral",
-			pos:  position{line: 392, col: 1, offset: 14104},
+			pos:  position{line: 444, col: 1, offset: 16906},
 			expr: &actionExpr{
-				pos: position{line: 392, col: 12, offset: 14115},
+				pos: position{line: 444, col: 12, offset: 16917},
 				run: (*parser).callonNumeral1,
-				expr: &oneOrMoreExpr{
-					pos: position{line: 392, col: 12, offset: 14115},
-					expr: &ruleRefExpr{
-						pos:  position{line: 392, col: 12, offset: 14115},
-						name: "Digit",
+				expr: &seqExpr{
+					pos: position{line: 444, col: 12, offset: 16917},
+					exprs: []interface{}{
+						&zeroOrOneExpr{
+							pos: position{line: 444, col: 12, offset: 16917},
+							expr: &litMatcher{
+								pos:        position{line: 444, col: 12, offset: 16917},
+								val:        "-",
+								ignoreCase: false,
+								want:       "\"-\"",
+							},
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 444, col: 17, offset: 16922},
+							expr: &ruleRefExpr{
+								pos:  position{line: 444, col: 17, offset: 16922},
+								name: "Digit",
+							},
+						},
 					},
 				},
 			},
 		},
 		{
 			name: "Not_single_quote",
-			pos:  position{line: 406, col: 1, offset: 14436},
+			pos:  position{line: 458, col: 1, offset: 17238},
 			expr: &charClassMatcher{
-				pos:        position{line: 406, col: 21, offset: 14456},
+				pos:        position{line: 458, col: 21, offset: 17258},
 				val:        "[^']",
 				chars:      []rune{'\''},
 				ignoreCase: false,
@@ -1596,11 +2438,11 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 408, col: 1, offset: 14462},
+			pos:  position{line: 460, col: 1, offset: 17264},
 			expr: &notExpr{
-				pos: position{line: 408, col: 8, offset: 14469},
+				pos: position{line: 460, col: 8, offset: 17271},
 				expr: &anyMatcher{
-					line: 408, col: 9, offset: 14470,
+					line: 460, col: 9, offset: 17272,
 				},
 			},
 		},
@@ -1631,14 +2473,19 @@ func (p *parser) callonProgram14() (interface{}, error) {
 }
 
 func (c *current) onQuery1(ps interface{}) (interface{}, error) {
-	// Acquire a list of all variables that appear in the query.
+	// Acquire a list of all variables that appear in the query in order
+	// of first appearance.
 	vSet := make(map[string]Empty)
+	vList := make([]string, 0)
 	for _, v := range ps.(*ASTNode).FindByType(VariableType) {
+		if v.Value.(string) == "_" {
+			continue // Anonymous variables are never reported.
+		}
+		if _, seen := vSet[v.Value.(string)]; seen {
+			continue
+		}
 		vSet[v.Value.(string)] = Empty{}
-	}
-	vList := make([]string, 0, len(vSet))
-	for v := range vSet {
-		vList = append(vList, v)
+		vList = append(vList, v.Value.(string))
 	}
 
 	// Insert a head predicate so we can process the query as if it were a
@@ -1689,14 +2536,65 @@ func (p *parser) callonClauseList2() (interface{}, error) {
 	return p.cur.onClauseList2(stack["cl"], stack["cls"])
 }
 
-func (c *current) onClauseList9(cl interface{}) (interface{}, error) {
+func (c *current) onClauseList11(cl interface{}) (interface{}, error) {
 	return c.ConstructList(ClauseListType, nil, cl, nil), nil
 }
 
-func (p *parser) callonClauseList9() (interface{}, error) {
+func (p *parser) callonClauseList11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onClauseList11(stack["cl"])
+}
+
+func (c *current) onDirective2(a, ds interface{}) (interface{}, error) {
+	return c.ConstructList(DirectiveType, a.(*ASTNode).Value, a, ds), nil
+}
+
+func (p *parser) callonDirective2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDirective2(stack["a"], stack["ds"])
+}
+
+func (c *current) onDirective17(a interface{}) (interface{}, error) {
+	return c.ConstructList(DirectiveType, a.(*ASTNode).Value, a, nil), nil
+}
+
+func (p *parser) callonDirective17() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDirective17(stack["a"])
+}
+
+func (c *current) onDirectiveArgList2(d, ds interface{}) (interface{}, error) {
+	return c.ConstructList(TermListType, nil, d, ds), nil
+}
+
+func (p *parser) callonDirectiveArgList2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDirectiveArgList2(stack["d"], stack["ds"])
+}
+
+func (c *current) onDirectiveArgList11(d interface{}) (interface{}, error) {
+	return c.ConstructList(TermListType, nil, d, nil), nil
+}
+
+func (p *parser) callonDirectiveArgList11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDirectiveArgList11(stack["d"])
+}
+
+func (c *current) onPredIndicator1(a, n interface{}) (interface{}, error) {
+	name := fmt.Sprintf("%s/%d", a.(*ASTNode).Value, n.(*ASTNode).Value)
+	return c.ConstructList(PredIndicatorType, name, nil, nil), nil
+}
+
+func (p *parser) callonPredIndicator1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onClauseList9(stack["cl"])
+	return p.cur.onPredIndicator1(stack["a"], stack["n"])
 }
 
 func (c *current) onClause2(p, ps interface{}) (interface{}, error) {
@@ -1745,34 +2643,87 @@ func (p *parser) callonPredicateList11() (interface{}, error) {
 	return p.cur.onPredicateList11(stack["p"])
 }
 
-func (c *current) onPredicate2(r interface{}) (interface{}, error) {
-	return c.ConstructList(PredicateType, nil, r, nil), nil
+func (c *current) onPredicate2(g interface{}) (interface{}, error) {
+	n := c.ConstructList(NegationType, nil, g, nil)
+	return c.ConstructList(PredicateType, nil, n, nil), nil
 }
 
 func (p *parser) callonPredicate2() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPredicate2(stack["r"])
+	return p.cur.onPredicate2(stack["g"])
+}
+
+func (c *current) onPredicate8(r interface{}) (interface{}, error) {
+	return c.ConstructList(PredicateType, nil, r, nil), nil
+}
+
+func (p *parser) callonPredicate8() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPredicate8(stack["r"])
+}
+
+func (c *current) onPredicate11(d interface{}) (interface{}, error) {
+	return c.ConstructList(PredicateType, nil, d, nil), nil
+}
+
+func (p *parser) callonPredicate11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPredicate11(stack["d"])
 }
 
-func (c *current) onPredicate5(a, ts interface{}) (interface{}, error) {
+func (c *current) onPredicate19(a, ts interface{}) (interface{}, error) {
 	return c.ConstructList(PredicateType, nil, a, ts), nil
 }
 
-func (p *parser) callonPredicate5() (interface{}, error) {
+func (p *parser) callonPredicate19() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPredicate5(stack["a"], stack["ts"])
+	return p.cur.onPredicate19(stack["a"], stack["ts"])
 }
 
-func (c *current) onPredicate16(a interface{}) (interface{}, error) {
+func (c *current) onPredicate30(a interface{}) (interface{}, error) {
 	return c.ConstructList(PredicateType, nil, a, nil), nil
 }
 
-func (p *parser) callonPredicate16() (interface{}, error) {
+func (p *parser) callonPredicate30() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPredicate30(stack["a"])
+}
+
+func (c *current) onDisjunction2(b, d interface{}) (interface{}, error) {
+	return c.ConstructList(DisjunctionType, nil, b, d), nil
+}
+
+func (p *parser) callonDisjunction2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDisjunction2(stack["b"], stack["d"])
+}
+
+func (c *current) onDisjunction11(b interface{}) (interface{}, error) {
+	return c.ConstructList(DisjunctionType, nil, b, nil), nil
+}
+
+func (p *parser) callonDisjunction11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDisjunction11(stack["b"])
+}
+
+func (c *current) onBranch2(cnd, thn interface{}) (interface{}, error) {
+	node := c.ConstructList(IfThenType, nil, cnd, nil)
+	node.Children = append(node.Children, thn.(*ASTNode))
+	return node, nil
+}
+
+func (p *parser) callonBranch2() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPredicate16(stack["a"])
+	return p.cur.onBranch2(stack["cnd"], stack["thn"])
 }
 
 func (c *current) onRelation2(e1, o, e2 interface{}) (interface{}, error) {
@@ -1815,36 +2766,14 @@ func (p *parser) callonEqualityOperator1() (interface{}, error) {
 	return p.cur.onEqualityOperator1()
 }
 
-func (c *current) onAdditiveExpr2(e1, o, e2 interface{}) (interface{}, error) {
-	kids := []*ASTNode{
-		e1.(*ASTNode),
-		o.(*ASTNode),
-		e2.(*ASTNode),
-	}
-	node := ASTNode{
-		Type:     AdditiveExprType,
-		Text:     string(c.text),
-		Value:    kids[1].Value,
-		Pos:      c.pos,
-		Children: kids,
-	}
-	return &node, nil
-}
-
-func (p *parser) callonAdditiveExpr2() (interface{}, error) {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onAdditiveExpr2(stack["e1"], stack["o"], stack["e2"])
-}
-
-func (c *current) onAdditiveExpr12(e interface{}) (interface{}, error) {
-	return c.ConstructList(AdditiveExprType, "", e, nil), nil
+func (c *current) onAdditiveExpr1(e1, rest interface{}) (interface{}, error) {
+	return c.FoldLeft(AdditiveExprType, e1, rest), nil
 }
 
-func (p *parser) callonAdditiveExpr12() (interface{}, error) {
+func (p *parser) callonAdditiveExpr1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onAdditiveExpr12(stack["e"])
+	return p.cur.onAdditiveExpr1(stack["e1"], stack["rest"])
 }
 
 func (c *current) onAdditiveOperator1() (interface{}, error) {
@@ -1857,36 +2786,14 @@ func (p *parser) callonAdditiveOperator1() (interface{}, error) {
 	return p.cur.onAdditiveOperator1()
 }
 
-func (c *current) onMultiplicativeExpr2(e1, o, e2 interface{}) (interface{}, error) {
-	kids := []*ASTNode{
-		e1.(*ASTNode),
-		o.(*ASTNode),
-		e2.(*ASTNode),
-	}
-	node := ASTNode{
-		Type:     MultiplicativeExprType,
-		Text:     string(c.text),
-		Value:    kids[1].Value,
-		Pos:      c.pos,
-		Children: kids,
-	}
-	return &node, nil
-}
-
-func (p *parser) callonMultiplicativeExpr2() (interface{}, error) {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onMultiplicativeExpr2(stack["e1"], stack["o"], stack["e2"])
-}
-
-func (c *current) onMultiplicativeExpr12(e interface{}) (interface{}, error) {
-	return c.ConstructList(MultiplicativeExprType, "", e, nil), nil
+func (c *current) onMultiplicativeExpr1(e1, rest interface{}) (interface{}, error) {
+	return c.FoldLeft(MultiplicativeExprType, e1, rest), nil
 }
 
-func (p *parser) callonMultiplicativeExpr12() (interface{}, error) {
+func (p *parser) callonMultiplicativeExpr1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onMultiplicativeExpr12(stack["e"])
+	return p.cur.onMultiplicativeExpr1(stack["e1"], stack["rest"])
 }
 
 func (c *current) onMultiplicativeOperator1() (interface{}, error) {
@@ -1950,24 +2857,64 @@ func (p *parser) callonPrimaryExpr2() (interface{}, error) {
 	return p.cur.onPrimaryExpr2(stack["a"])
 }
 
-func (c *current) onPrimaryExpr10(n interface{}) (interface{}, error) {
-	return c.ConstructList(PrimaryExprType, "", n, nil), nil
+func (c *current) onPrimaryExpr10(f, a interface{}) (interface{}, error) {
+	return c.ConstructFunction(f, a), nil
 }
 
 func (p *parser) callonPrimaryExpr10() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimaryExpr10(stack["n"])
+	return p.cur.onPrimaryExpr10(stack["f"], stack["a"])
+}
+
+func (c *current) onPrimaryExpr21(f, a1, a2 interface{}) (interface{}, error) {
+	return c.ConstructFunction(f, a1, a2), nil
+}
+
+func (p *parser) callonPrimaryExpr21() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimaryExpr21(stack["f"], stack["a1"], stack["a2"])
 }
 
-func (c *current) onPrimaryExpr13(v interface{}) (interface{}, error) {
+func (c *current) onPrimaryExpr37(n interface{}) (interface{}, error) {
+	return c.ConstructList(PrimaryExprType, "", n, nil), nil
+}
+
+func (p *parser) callonPrimaryExpr37() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimaryExpr37(stack["n"])
+}
+
+func (c *current) onPrimaryExpr40(v interface{}) (interface{}, error) {
 	return c.ConstructList(PrimaryExprType, "", v, nil), nil
 }
 
-func (p *parser) callonPrimaryExpr13() (interface{}, error) {
+func (p *parser) callonPrimaryExpr40() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimaryExpr40(stack["v"])
+}
+
+func (c *current) onUnaryFunction1() (interface{}, error) {
+	return c.ConstructList(FunctionOpType, nil, nil, nil), nil
+}
+
+func (p *parser) callonUnaryFunction1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onUnaryFunction1()
+}
+
+func (c *current) onBinaryFunction1() (interface{}, error) {
+	return c.ConstructList(FunctionOpType, nil, nil, nil), nil
+}
+
+func (p *parser) callonBinaryFunction1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimaryExpr13(stack["v"])
+	return p.cur.onBinaryFunction1()
 }
 
 func (c *current) onTermList2(t, ts interface{}) (interface{}, error) {
@@ -2022,6 +2969,17 @@ func (p *parser) callonList15() (interface{}, error) {
 	return p.cur.onList15(stack["h"])
 }
 
+func (c *current) onList23() (interface{}, error) {
+	// Empty list
+	return c.ConstructList(ListType, "[]", nil, nil), nil
+}
+
+func (p *parser) callonList23() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onList23()
+}
+
 func (c *current) onListTail1(v interface{}) (interface{}, error) {
 	return c.ConstructList(ListTailType, nil, v, nil), nil
 }
@@ -2167,18 +3125,17 @@ func Entrypoint(ruleName string) Option {
 //
 // Example usage:
 //
-//     input := "input"
-//     stats := Stats{}
-//     _, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     fmt.Println(string(b))
-//
+//	input := "input"
+//	stats := Stats{}
+//	_, err := parseBytes("input-file", []byte(input), Statistics(&stats, "no match"))
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	fmt.Println(string(b))
 func Statistics(stats *Stats, choiceNoMatch string) Option {
 	return func(p *parser) Option {
 		oldStats := p.Stats
@@ -2288,12 +3245,12 @@ func ParseReader(filename string, r io.Reader, opts ...Option) (interface{}, err
 		return nil, err
 	}
 
-	return Parse(filename, b, opts...)
+	return parseBytes(filename, b, opts...)
 }
 
 // Parse parses the data from b using filename as information in the
 // error messages.
-func Parse(filename string, b []byte, opts ...Option) (interface{}, error) {
+func parseBytes(filename string, b []byte, opts ...Option) (interface{}, error) {
 	return newParser(filename, b, opts...).parse(g)
 }
 
@@ -2303,7 +3260,7 @@ type position struct {
 }
 
 func (p position) String() string {
-	return fmt.Sprintf("%d:%d [%d]", p.line, p.col, p.offset)
+	return strconv.Itoa(p.line) + ":" + strconv.Itoa(p.col) + " [" + strconv.Itoa(p.offset) + "]"
 }
 
 // savepoint stores all state required to go back to this point in the
@@ -2416,6 +3373,7 @@ type litMatcher struct {
 	pos        position
 	val        string
 	ignoreCase bool
+	want       string
 }
 
 type charClassMatcher struct {
@@ -2767,13 +3725,24 @@ type Cloner interface {
 	Clone() interface{}
 }
 
+var statePool = &sync.Pool{
+	New: func() interface{} { return make(storeDict) },
+}
+
+func (sd storeDict) Discard() {
+	for k := range sd {
+		delete(sd, k)
+	}
+	statePool.Put(sd)
+}
+
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
 	if p.debug {
 		defer p.out(p.in("cloneState"))
 	}
 
-	state := make(storeDict, len(p.cur.state))
+	state := statePool.Get().(storeDict)
 	for k, v := range p.cur.state {
 		if c, ok := v.(Cloner); ok {
 			state[k] = c.Clone()
@@ -2790,6 +3759,7 @@ func (p *parser) restoreState(state storeDict) {
 	if p.debug {
 		defer p.out(p.in("restoreState"))
 	}
+	p.cur.state.Discard()
 	p.cur.state = state
 }
 
@@ -2902,7 +3872,7 @@ func listJoin(list []string, sep string, lastSep string) string {
 	case 1:
 		return list[0]
 	default:
-		return fmt.Sprintf("%s %s %s", strings.Join(list[:len(list)-1], sep), lastSep, list[len(list)-1])
+		return strings.Join(list[:len(list)-1], sep) + " " + lastSep + " " + list[len(list)-1]
 	}
 }
 
@@ -3198,11 +4168,6 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
-	ignoreCase := ""
-	if lit.ignoreCase {
-		ignoreCase = "i"
-	}
-	val := fmt.Sprintf("%q%s", lit.val, ignoreCase)
 	start := p.pt
 	for _, want := range lit.val {
 		cur := p.pt.rn
@@ -3210,13 +4175,13 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
 			cur = unicode.ToLower(cur)
 		}
 		if cur != want {
-			p.failAt(false, start.position, val)
+			p.failAt(false, start.position, lit.want)
 			p.restore(start)
 			return nil, false
 		}
 		p.read()
 	}
-	p.failAt(true, start.position, val)
+	p.failAt(true, start.position, lit.want)
 	return p.sliceFrom(start), true
 }
 
@@ -0,0 +1,59 @@
+// Test the parser.
+
+package qaprolog
+
+import "testing"
+
+// parenthesize returns an arithmetic expression with each binary operation
+// enclosed in parentheses.
+func parenthesize(a *ASTNode) string {
+	switch len(a.Children) {
+	case 0:
+		return a.Text
+	case 3:
+		return "(" + parenthesize(a.Children[0]) + " " + a.Children[1].Text + " " + parenthesize(a.Children[2]) + ")"
+	default:
+		return parenthesize(a.Children[0])
+	}
+}
+
+// findBinary returns the outermost binary operation of a given type.
+func findBinary(a *ASTNode, t ASTNodeType) *ASTNode {
+	if a.Type == t && len(a.Children) == 3 {
+		return a
+	}
+	for _, c := range a.Children {
+		if b := findBinary(c, t); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// TestAssociativity ensures that arithmetic operators of equal precedence
+// associate to the left.
+func TestAssociativity(t *testing.T) {
+	tests := []struct {
+		expr string
+		t    ASTNodeType
+		want string
+	}{
+		{"5 - 3 - 1", AdditiveExprType, "((5 - 3) - 1)"},
+		{"1 + 2 - 3 + 4", AdditiveExprType, "(((1 + 2) - 3) + 4)"},
+		{"2 * 3 * 4", MultiplicativeExprType, "((2 * 3) * 4)"},
+		{"9 - 2 * 3 - 4", AdditiveExprType, "((9 - (2 * 3)) - 4)"},
+	}
+	for _, tt := range tests {
+		ast, err := parseBytes("test.pl", []byte("p(X) :- X = "+tt.expr+".\n"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		b := findBinary(ast.(*ASTNode), tt.t)
+		if b == nil {
+			t.Fatalf("%s: expected a %s", tt.expr, tt.t)
+		}
+		if got := parenthesize(b); got != tt.want {
+			t.Errorf("Expected %s to parse as %s but saw %s", tt.expr, tt.want, got)
+		}
+	}
+}
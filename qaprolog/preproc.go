@@ -0,0 +1,620 @@
+// Preprocess an AST before generating code.
+
+package qaprolog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BitsNeeded reports the number of bits needed to represent a given
+// nonnegative integer.
+func BitsNeeded(n int) uint {
+	b := uint(0)
+	for ; n > 0; n >>= 1 {
+		b++
+	}
+	return b
+}
+
+// ProcessDirectives applies all directives (e.g., ":- max_depth(path/2, 5).")
+// that appear in the AST then removes them from the AST.
+func (a *ASTNode) ProcessDirectives(p *Parameters) {
+	p.PredDepths = make(map[string]uint)
+	for _, cl := range a.FindByType(ClauseListType) {
+		kids := make([]*ASTNode, 0, len(cl.Children))
+		for _, d := range cl.Children {
+			if d.Type != DirectiveType {
+				kids = append(kids, d)
+				continue
+			}
+			args := d.Children[1:]
+			switch name := fmt.Sprintf("%s/%d", d.Value, len(args)); name {
+			case "max_depth/2":
+				// Bound the recursion depth of a given predicate.
+				if args[0].Type != PredIndicatorType {
+					parseError(args[0].Pos, "The first argument to max_depth must be of the form <name>/<arity>")
+				}
+				if args[1].Children[0].Type != NumeralType || args[1].Children[0].Value.(int) < 0 {
+					parseError(args[1].Pos, "The second argument to max_depth must be a nonnegative numeral")
+				}
+				p.PredDepths[args[0].Value.(string)] = uint(args[1].Children[0].Value.(int))
+
+			case "signed/0":
+				// Treat integers as signed rather than unsigned.
+				p.Signed = true
+
+			default:
+				parseError(d.Pos, "Unrecognized directive %s", name)
+			}
+		}
+		cl.Children = kids
+	}
+}
+
+// RejectUnimplemented rejects the AST (i.e., aborts the program) if it
+// contains elements we do not currently know how to process.
+func (a *ASTNode) RejectUnimplemented(p *Parameters) {
+	for _, l := range a.FindByType(ListType) {
+		elts, _ := l.listParts()
+		for _, e := range elts {
+			switch e.Children[0].Type {
+			case NumeralType, AtomType, VariableType:
+			default:
+				parseError(e.Pos, "Lists can contain only numerals, atoms, and variables")
+			}
+		}
+	}
+	for _, s := range a.FindByType(StructureType) {
+		for _, e := range s.Children[1:] {
+			switch e.Children[0].Type {
+			case NumeralType, AtomType, VariableType:
+			default:
+				parseError(e.Pos, "Structures can contain only numerals, atoms, and variables")
+			}
+		}
+	}
+}
+
+// functorName returns a structure's functor in "<name>/<arity>" format.
+func (a *ASTNode) functorName() string {
+	return fmt.Sprintf("%s/%d", a.Children[0].Value, len(a.Children)-1)
+}
+
+// isAnonymousVar reports whether a variable name was assigned to an anonymous
+// variable by RenameAnonymousVars.
+func isAnonymousVar(nm string) bool {
+	if len(nm) < 2 || nm[0] != '_' {
+		return false
+	}
+	for _, c := range nm[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RenameAnonymousVars gives each anonymous variable ("_") in the AST a unique
+// name.  Otherwise, "[_, _]" would match only lists of two equal elements.
+func (a *ASTNode) RenameAnonymousVars() {
+	n := 0
+	var walker func(n *ASTNode)
+	rename := func(v *ASTNode) bool {
+		if v.Type != VariableType || v.Value.(string) != "_" {
+			return false
+		}
+		n++
+		v.Value = fmt.Sprintf("_%d", n)
+		v.Text = v.Value.(string)
+		return true
+	}
+	walker = func(c *ASTNode) {
+		if c.Type == TermType && rename(c.Children[0]) {
+			// Keep the term's text consistent with its variable.
+			c.Value = c.Children[0].Value
+			c.Text = c.Children[0].Text
+			return
+		}
+		if rename(c) {
+			return
+		}
+		for _, cc := range c.Children {
+			walker(cc)
+		}
+	}
+	walker(a)
+}
+
+// listParts splits a list into its explicitly specified elements and its tail
+// variable.  The tail is nil for lists of bounded extent.
+func (a *ASTNode) listParts() (elts []*ASTNode, tail *ASTNode) {
+	if len(a.Children) == 0 {
+		return nil, nil // Empty list
+	}
+	elts = a.Children[0].Children
+	if len(a.Children) > 1 {
+		tail = a.Children[1]
+	}
+	return
+}
+
+// FindByType walks an AST and returns a list of all nodes of a given type.
+func (a *ASTNode) FindByType(t ASTNodeType) []*ASTNode {
+	nodes := make([]*ASTNode, 0, 8)
+	var walker func(n *ASTNode)
+	walker = func(n *ASTNode) {
+		if n.Type == t {
+			nodes = append(nodes, n)
+		}
+		for _, c := range n.Children {
+			walker(c)
+		}
+	}
+	walker(a)
+	return nodes
+}
+
+// StoreAtomNames stores both a forward and reverse map between all atoms named
+// in an AST (except predicate names) and integers.
+func (a *ASTNode) StoreAtomNames(p *Parameters) {
+	// Construct a map from integers to symbols.
+	nmSet := make(map[string]Empty)
+	a.uniqueAtomNames(nmSet, false)
+	p.IntToSym = make([]string, 0, len(nmSet))
+	for nm := range nmSet {
+		p.IntToSym = append(p.IntToSym, nm)
+	}
+	sort.Strings(p.IntToSym)
+
+	// Construct a map from symbols to integers.
+	p.SymToInt = make(map[string]int, len(p.IntToSym))
+	for i, s := range p.IntToSym {
+		p.SymToInt[s] = i
+	}
+	p.SymBits = BitsNeeded(len(p.IntToSym) - 1)
+	if p.SymBits == 0 {
+		p.SymBits = 1 // Need at least one bit
+	}
+}
+
+// StoreFunctorNames stores both a forward and reverse map between all functors
+// named in an AST and integers.  It also records the maximum functor arity.
+func (a *ASTNode) StoreFunctorNames(p *Parameters) {
+	// Construct a map from integers to functors.
+	fSet := make(map[string]Empty)
+	p.MaxArity = 0
+	for _, s := range a.FindByType(StructureType) {
+		fSet[s.functorName()] = Empty{}
+		if ar := uint(len(s.Children) - 1); ar > p.MaxArity {
+			p.MaxArity = ar
+		}
+	}
+	p.IntToFunctor = make([]string, 0, len(fSet))
+	for f := range fSet {
+		p.IntToFunctor = append(p.IntToFunctor, f)
+	}
+	sort.Strings(p.IntToFunctor)
+
+	// Construct a map from functors to integers.
+	p.FunctorToInt = make(map[string]int, len(p.IntToFunctor))
+	for i, f := range p.IntToFunctor {
+		p.FunctorToInt[f] = i
+	}
+	p.FunctorBits = BitsNeeded(len(p.IntToFunctor) - 1)
+	if p.FunctorBits == 0 {
+		p.FunctorBits = 1 // Need at least one bit
+	}
+}
+
+// uniqueAtomNames constructs a set of all atoms named in an AST except
+// predicate names.  It performs most of the work for AtomNames.
+func (a *ASTNode) uniqueAtomNames(names map[string]Empty, skip1 bool) {
+	// Process the current AST node.
+	if a.Type == AtomType {
+		nm, ok := a.Value.(string)
+		if !ok {
+			fatalf("Internal error parsing %#v", *a)
+		}
+		names[nm] = Empty{}
+	}
+
+	// Recursively process the current node's children.  If the current
+	// node is a clause or a query, skip its first child's first child (the
+	// name of the clause/query itself).
+	kids := a.Children
+	if skip1 {
+		kids = kids[1:]
+	}
+	skip1 = (a.Type == ClauseType || a.Type == QueryType)
+	for _, aa := range kids {
+		aa.uniqueAtomNames(names, skip1)
+		skip1 = false
+	}
+}
+
+// SignedBitsNeeded reports the number of bits needed to represent a given
+// integer in two's-complement form.
+func SignedBitsNeeded(n int) uint {
+	if n < 0 {
+		n = -n - 1
+	}
+	return BitsNeeded(n) + 1
+}
+
+// numeralBits returns the number of bits needed to store a given integer
+// literal.
+func (p *Parameters) numeralBits(v int) uint {
+	if p.Signed {
+		return SignedBitsNeeded(v)
+	}
+	return BitsNeeded(v)
+}
+
+// AdjustIntBits increments the integer width to accommodate both the
+// maximum-valued numeric literal and the number of symbol literals.  This
+// function assumes that StoreAtomNames has already been called.
+func (a *ASTNode) AdjustIntBits(p *Parameters) {
+	// Ensure we can store every integer literal.  Negative literals are
+	// allowed only if integers are signed.
+	for _, n := range a.FindByType(NumeralType) {
+		v := n.Value.(int)
+		if v < 0 && !p.Signed {
+			parseError(n.Pos, "Negative integers require --signed or \":- signed.\"")
+		}
+		if b := p.numeralBits(v); p.IntBits < b {
+			p.IntBits = b
+		}
+	}
+
+	// We can't handle 0-bit integers so round up to 1 if necessary.
+	if p.IntBits == 0 {
+		p.IntBits = 1
+	}
+}
+
+// AdjustListLen increases the maximum list length to accommodate the longest
+// list that appears in the program and computes the number of bits needed to
+// represent a list's length.
+func (a *ASTNode) AdjustListLen(p *Parameters) {
+	// Ensure we can store the longest list.
+	for _, l := range a.FindByType(ListType) {
+		elts, _ := l.listParts()
+		if uint(len(elts)) > p.MaxListLen {
+			p.MaxListLen = uint(len(elts))
+		}
+	}
+
+	// Lengths range from 0 to MaxListLen, inclusive.
+	p.ListLenBits = BitsNeeded(int(p.MaxListLen))
+	if p.ListLenBits == 0 {
+		p.ListLenBits = 1
+	}
+}
+
+// BinClauses groups all of the clauses in the program by name and arity.  The
+// function returns a map with keys are of the form "<name>/<arity>" and values
+// being the corresponding lists of clauses.
+func (a *ASTNode) BinClauses(p *Parameters) {
+	bins := make(map[string][]*ASTNode, 8)
+	csAndQs := append(a.FindByType(ClauseType), a.FindByType(QueryType)...)
+	for _, cl := range csAndQs {
+		// Perform a lot of error-checking as we search for the clause
+		// name.
+		if len(cl.Children) == 0 {
+			fatal("Internal error: Clause with no children")
+		}
+		pr := cl.Children[0]
+		if pr.Type != PredicateType {
+			fatal("Internal error: Clause with no predicate first child")
+		}
+		if len(pr.Children) == 0 {
+			fatal("Internal error: Predicate with no children")
+		}
+
+		// Extract the symbol name (<name>/<arity>).
+		nm := pr.Children[0].Value
+		ar := len(pr.Children[1:])
+		sym := fmt.Sprintf("%s/%d", nm, ar)
+
+		// Associate the current clause with the symbol name.
+		bins[sym] = append(bins[sym], cl)
+	}
+	p.TopLevel = bins
+}
+
+// CheckNegations ensures that every negated goal can be decided by our
+// circuit representation.  Negated goals include both explicit negations and
+// the conditions of if-then branches, which are implicitly negated by all
+// subsequent branches.  Every variable in a negated goal must already be bound
+// by a non-negated goal that precedes it in the clause body.  A clause's
+// arguments do not count as bound because its caller may pass it unbound
+// variables.  Anonymous variables therefore can never appear in a negated
+// goal.  Furthermore, no clause reachable from a negated goal can introduce
+// variables of its own.  Otherwise, the negation would be applied to an
+// existentially quantified goal, which is not what Prolog's negation as
+// failure means.  As an exception, a condition may bind a variable that
+// appears only in its branch by equating it to a term (e.g., "( X = f(Y) ->
+// ... ; ... )").  Such equalities are moved ahead of the disjunction.
+func (a *ASTNode) CheckNegations(p *Parameters) {
+	// Define a function that reports the first variable introduced by a
+	// clause group or anything it calls.
+	type local struct {
+		Clause string // Name of the clause that introduces a variable
+		Var    string // Name of the variable introduced
+	}
+	checked := make(map[string]local, len(p.TopLevel))
+	var findLocal func(nm string) local
+	findLocal = func(nm string) local {
+		if loc, seen := checked[nm]; seen {
+			return loc
+		}
+		checked[nm] = local{} // Break cycles.
+		for _, cl := range p.TopLevel[nm] {
+			hVars := cl.Children[0].allVariables()
+			for _, ch := range cl.Children[1:] {
+				for v := range ch.allVariables() {
+					if _, ok := hVars[v]; !ok {
+						checked[nm] = local{nm, v}
+						return checked[nm]
+					}
+				}
+			}
+			for _, pr := range cl.calls() {
+				if loc := findLocal(pr.calleeName(p)); loc.Var != "" {
+					checked[nm] = loc
+					return loc
+				}
+			}
+		}
+		return local{}
+	}
+
+	// Define a function that ensures that a negated goal contains only
+	// bound variables and calls only clauses that introduce no variables.
+	var where string
+	checkNegated := func(g *ASTNode, desc string, bound map[string]Empty) {
+		for _, v := range g.FindByType(VariableType) {
+			nm := v.Value.(string)
+			if _, ok := bound[nm]; ok {
+				continue
+			}
+			if isAnonymousVar(nm) {
+				parseError(v.Pos, "Anonymous variable \"_\" cannot appear in %s because it is bound nowhere else", desc)
+			}
+			parseError(v.Pos, "Variable %s in %s must first appear in a preceding, non-negated goal in the %s", nm, desc, where)
+		}
+		for _, pr := range g.FindByType(PredicateType) {
+			if len(pr.Children) <= 1 {
+				continue
+			}
+			if loc := findLocal(pr.calleeName(p)); loc.Var != "" {
+				what := "variable " + loc.Var
+				if isAnonymousVar(loc.Var) {
+					what = `anonymous variable "_"`
+				}
+				parseError(g.Pos, "The %s is not supported because %s introduces %s", desc, loc.Clause, what)
+			}
+		}
+	}
+
+	// Define a function that walks a list of goals in execution order,
+	// checking each negated goal against the variables bound so far, and
+	// returns the variables bound once every goal has succeeded.  A
+	// variable is bound by a disjunction only if it is bound by every
+	// branch.
+	var cl *ASTNode
+	var walk func(list *ASTNode, first int, bound map[string]Empty) map[string]Empty
+	walk = func(list *ASTNode, first int, bound map[string]Empty) map[string]Empty {
+		bound = copyVarSet(bound)
+		for i := first; i < len(list.Children); i++ {
+			g := list.Children[i]
+			switch c := g.Children[0]; c.Type {
+			case NegationType:
+				checkNegated(c, fmt.Sprintf("negated goal %q", c.Children[0].Text), bound)
+
+			case DisjunctionType:
+				if defs := cl.hoistDefinitions(c, bound); len(defs) > 0 {
+					// Walk the definitions moved ahead
+					// of the disjunction, then the
+					// disjunction itself.
+					kids := make([]*ASTNode, 0, len(list.Children)+len(defs))
+					kids = append(kids, list.Children[:i]...)
+					kids = append(kids, defs...)
+					list.Children = append(kids, list.Children[i:]...)
+					i--
+					continue
+				}
+				var after map[string]Empty
+				for j, b := range c.Children {
+					var bb map[string]Empty
+					switch {
+					case b.Type != IfThenType:
+						bb = walk(b, 0, bound)
+					case j < len(c.Children)-1:
+						// The condition is negated by
+						// all subsequent branches.
+						checkNegated(b.Children[0], fmt.Sprintf("condition %q", b.Children[0].Text), bound)
+						bb = walk(b.Children[1], 0, walk(b.Children[0], 0, bound))
+					default:
+						bb = walk(b.Children[1], 0, walk(b.Children[0], 0, bound))
+					}
+					if after == nil {
+						after = bb
+						continue
+					}
+					for v := range after {
+						if _, ok := bb[v]; !ok {
+							delete(after, v)
+						}
+					}
+				}
+				bound = after
+
+			default:
+				for v := range g.allVariables() {
+					bound[v] = Empty{}
+				}
+			}
+		}
+		return bound
+	}
+
+	// Check each negation in each clause and in the query.
+	for _, cl = range append(a.FindByType(ClauseType), a.FindByType(QueryType)...) {
+		where = "clause body"
+		if cl.Type == QueryType {
+			where = "query"
+		}
+		walk(cl, 1, nil)
+	}
+}
+
+// hoistDefinitions removes from the conditions of a disjunction's if-then
+// branches each equality that binds a variable appearing nowhere else in the
+// clause but that branch to a term whose variables are already bound, and it
+// returns the equalities it removed so they can be proved ahead of the
+// disjunction.  Because such an equality always succeeds, this does not
+// change what the clause means, but it lets the rest of the condition be
+// negated by subsequent branches, which requires every variable to be bound.
+// A condition left empty always holds, so its branch replaces all
+// subsequent branches.
+func (a *ASTNode) hoistDefinitions(d *ASTNode, bound map[string]Empty) []*ASTNode {
+	defs := make([]*ASTNode, 0, 2)
+	for i := 0; i < len(d.Children)-1; i++ {
+		b := d.Children[i]
+		if b.Type != IfThenType {
+			continue
+		}
+
+		// Find the variables that appear outside the branch.
+		inside := make(map[*ASTNode]bool)
+		for _, v := range b.FindByType(VariableType) {
+			inside[v] = true
+		}
+		outside := make(map[string]Empty)
+		cs := a.Children
+		if a.Type == QueryType {
+			// A query's head merely lists the variables to
+			// report.
+			cs = cs[1:]
+		}
+		for _, c := range cs {
+			for _, v := range c.FindByType(VariableType) {
+				if !inside[v] {
+					outside[v.Value.(string)] = Empty{}
+				}
+			}
+		}
+
+		// Define a function that returns the variable an equality
+		// binds or nil if the equality is not a definition.
+		defined := copyVarSet(bound)
+		definedVar := func(g *ASTNode) *ASTNode {
+			r := g.Children[0]
+			if r.Type != RelationType || r.Value.(string) != "=" {
+				return nil
+			}
+			for _, e := range [][2]*ASTNode{{r.Children[0], r.Children[2]}, {r.Children[2], r.Children[0]}} {
+				v := loneVariable(e[0])
+				if v == nil || !isTermExpr(e[1]) {
+					continue
+				}
+				nm := v.Value.(string)
+				_, isBound := defined[nm]
+				_, isOutside := outside[nm]
+				if isBound || isOutside {
+					continue
+				}
+				ok := true
+				for tv := range e[1].allVariables() {
+					if _, found := defined[tv]; !found {
+						ok = false
+					}
+				}
+				if ok {
+					return v
+				}
+			}
+			return nil
+		}
+
+		// Move each definition out of the condition.
+		cond := b.Children[0]
+		kept := make([]*ASTNode, 0, len(cond.Children))
+		for _, g := range cond.Children {
+			if v := definedVar(g); v != nil {
+				defined[v.Value.(string)] = Empty{}
+				defs = append(defs, g)
+				continue
+			}
+			kept = append(kept, g)
+		}
+		cond.Children = kept
+		if len(kept) == 0 {
+			d.Children[i] = b.Children[1]
+			d.Children = d.Children[:i+1]
+		}
+	}
+	return defs
+}
+
+// isTermExpr reports whether an expression is a term rather than the result
+// of an arithmetic operation.
+func isTermExpr(a *ASTNode) bool {
+	for a.Type != TermType && len(a.Children) == 1 {
+		a = a.Children[0]
+	}
+	switch a.Type {
+	case TermType, NumeralType, AtomType, VariableType:
+		return true
+	default:
+		return false
+	}
+}
+
+// copyVarSet returns a copy of a set of variable names.
+func copyVarSet(vs map[string]Empty) map[string]Empty {
+	c := make(map[string]Empty, len(vs))
+	for v := range vs {
+		c[v] = Empty{}
+	}
+	return c
+}
+
+// numToVerVar converts a parameter number from 0-701 (e.g., 5) to a
+// lettered Verilog variable (e.g., "E").
+func numToVerVar(n int) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	const nChars = len(chars)
+	switch {
+	case n < nChars:
+		return chars[n : n+1]
+	case n < nChars*(nChars+1):
+		n0 := n % nChars
+		n1 := n / nChars
+		return chars[n1-1:n1] + chars[n0:n0+1]
+	default:
+		fatal("Too many parameters")
+	}
+	return "" // Will never get here.
+}
+
+// augmentVerilogVars associates Verilog variables with Prolog variables unless
+// they already appear in the given map.
+func (a *ASTNode) augmentVerilogVars(minN int, p2v map[string]string) map[string]string {
+	varNodes := a.FindByType(VariableType)
+	newP2v := make(map[string]string, len(varNodes))
+	for _, pv := range varNodes {
+		pVar := pv.Text
+		if _, seen := p2v[pVar]; seen {
+			continue
+		}
+		if _, seen := newP2v[pVar]; seen {
+			continue
+		}
+		newP2v[pVar] = numToVerVar(minN)
+		minN++
+	}
+	return newP2v
+}
@@ -0,0 +1,526 @@
+// Package qaprolog implements a compiler for Quantum-Annealing Prolog.  It
+// accepts a small subset of Prolog and generates weights for a Hamiltonian
+// expression, which can be fed to a quantum annealer such as the D-Wave
+// supercomputer.
+//
+// A program is compiled in stages: Parse, TypeCheck, Compile (or
+// CompileThrough, EmitVerilog, or one of the other Emit functions), and
+// Solve.  Alternatively, Resume picks up from a previously compiled artifact
+// and is followed directly by Solve.  Each stage returns an error, which is
+// of type *Error, rather than aborting the calling program.
+package qaprolog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The generated parser's Parse function is renamed to parseBytes so as not to
+// conflict with the Parse function defined below.
+//go:generate sh -c "pigeon parser.peg | sed -e 's/\\bParse(/parseBytes(/g' > parser.go"
+//go:generate stringer -type=ASTNodeType
+
+// Empty is used to treat maps as sets.
+type Empty struct{}
+
+// BaseName returns a file path with the directory and extension removed.
+func BaseName(filename string) string {
+	return path.Base(strings.TrimSuffix(filename, path.Ext(filename)))
+}
+
+// Parameters encapsulates all command-line parameters as well as various
+// global values computed from the AST.
+type Parameters struct {
+	// Command-line parameters
+	ProgName      string      // Name of the calling program
+	InFileName    string      // Name of the input file
+	WorkDir       string      // Directory for holding intermediate files
+	CacheDir      string      // Directory for caching EDIF and QMASM files ("" to disable caching)
+	IntBits       uint        // Number of bits to use for each program integer
+	Arith         string      // Semantics of integer arithmetic ("exact", "modular", or "checked")
+	Signed        bool        // Whether integers are signed (two's-complement) rather than unsigned
+	MaxListLen    uint        // Maximum number of elements in a list
+	MaxDepth      uint        // Default maximum depth of recursion
+	Verbose       bool        // Whether to output verbose execution information
+	Log           *log.Logger // Destination for informational messages and warnings (nil to discard)
+	Query         string      // Query to apply to the program
+	Backend       string      // Method for converting the program to QMASM ("yosys" or "native")
+	Solver        string      // Method for solving the program (one of SolverNames)
+	SolverCommand []string    // Command and arguments for the external solver
+	Sweeps        uint        // Number of simulated-annealing sweeps per read
+	Reads         uint        // Number of simulated-annealing reads
+	Schedule      string      // Simulated-annealing temperature schedule
+	BetaMin       float64     // Initial inverse temperature for simulated annealing
+	BetaMax       float64     // Final inverse temperature for simulated annealing
+	Replicas      uint        // Number of parallel-tempering replicas
+	Seed          int64       // Random-number seed for simulated annealing
+	Verify        bool        // Whether to check each solution classically
+	Emit          string      // Artifact to write instead of solving ("" to solve)
+	StopAfter     string      // Stage after which to stop (one of Stages or "" to solve)
+	Output        string      // File to which to write the final artifact ("-" for standard output)
+	From          string      // Stage whose artifact is the input ("verilog", "edif", "qmasm", or "" for Prolog source)
+	Format        string      // Output format for solutions ("text", "json", or "csv")
+	QmasmArgs     []string    // Additional qmasm command-line arguments
+
+	// Computed values
+	SymToInt      map[string]int               // Map from a symbol to an integer
+	IntToSym      []string                     // Map from an integer to a symbol
+	TopLevel      map[string][]*ASTNode        // Top-level clauses, grouped by name and arity
+	SymBits       uint                         // Number of bits to use for each symbol
+	ListLenBits   uint                         // Number of bits to use for each list length
+	FunctorToInt  map[string]int               // Map from a functor's name and arity to an integer
+	IntToFunctor  []string                     // Map from an integer to a functor's name and arity
+	FunctorTypes  map[string]ArgTypes          // Argument types for each functor
+	FunctorBits   uint                         // Number of bits to use for each functor tag
+	MaxArity      uint                         // Maximum number of arguments to any functor
+	PredDepths    map[string]uint              // Per-predicate maximum depth of recursion
+	CallLevels    map[*ASTNode]uint            // Recursion level invoked by each call to a recursive predicate
+	ArgBits       map[string][]uint            // Number of bits used for each integer argument of each clause group
+	VarBits       map[*ASTNode]map[string]uint // Number of bits used for each integer variable of each clause
+	RelWidths     map[*ASTNode]RelWidth        // Widths at which to evaluate relations that could overflow
+	ArithChecks   map[*ASTNode]RelWidth        // Widths at which to check arithmetic for overflow (Arith == "checked")
+	DivWidths     map[*ASTNode]DivWidth        // Widths at which to evaluate divisions and shifts (unless Arith == "modular")
+	OutFileBase   string                       // Base name (no path or extension) for output files
+	DeleteWorkDir bool                         // Whether the caller should delete WorkDir when finished
+}
+
+// verbosePrintf outputs a message only if verbose output is enabled.
+func verbosePrintf(p *Parameters, fmt string, args ...interface{}) {
+	if !p.Verbose || p.Log == nil {
+		return
+	}
+	p.Log.Printf("INFO: "+fmt, args...)
+}
+
+// An Error is returned by each compilation stage.  If Line is nonzero, the
+// error is associated with a position in the input file.
+type Error struct {
+	File string // Name of the input file
+	Line int    // Line number (1-based) or 0 if not applicable
+	Col  int    // Column number (1-based) or 0 if not applicable
+	Msg  string // Description of the error
+}
+
+// Error returns an error message, prefixed with a source position if known.
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Msg)
+	}
+	return e.Msg
+}
+
+// fatal aborts the current compilation stage with an error message.
+func fatal(args ...interface{}) {
+	panic(&Error{Msg: fmt.Sprint(args...)})
+}
+
+// fatalf aborts the current compilation stage with a formatted error message.
+func fatalf(format string, args ...interface{}) {
+	panic(&Error{Msg: fmt.Sprintf(format, args...)})
+}
+
+// checkError aborts the current compilation stage if an error value is
+// non-nil.
+func checkError(err error) {
+	if err != nil {
+		panic(&Error{Msg: err.Error()})
+	}
+}
+
+// parseError aborts the current compilation stage with an error message
+// associated with a given position in the input file.
+func parseError(pos position, format string, args ...interface{}) {
+	panic(&Error{
+		Line: pos.line,
+		Col:  pos.col,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// recoverError converts a panic raised by fatal, fatalf, checkError, or
+// parseError into an error return value.  It must be deferred.  Any other
+// panic is propagated.
+func recoverError(p *Parameters, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e, ok := r.(*Error)
+	if !ok {
+		panic(r)
+	}
+	if e.Line > 0 {
+		e.File = p.InFileName
+	}
+	*err = e
+}
+
+// convertParseError converts an error returned by the generated parser to
+// an *Error, retaining the position of the first syntax error.
+func convertParseError(p *Parameters, err error) error {
+	if el, ok := err.(errList); ok && len(el) > 0 {
+		err = el[0]
+	}
+	pe, ok := err.(*parserError)
+	if !ok {
+		return &Error{Msg: err.Error()}
+	}
+	return &Error{
+		File: p.InFileName,
+		Line: pe.pos.line,
+		Col:  pe.pos.col,
+		Msg:  pe.Inner.Error(),
+	}
+}
+
+// A Program represents a Prolog program and query at some stage of
+// compilation.
+type Program struct {
+	AST    *ASTNode    // Abstract syntax tree of the program and query
+	Params *Parameters // Parameters that control compilation
+
+	nm2tys    map[string]ArgTypes   // Argument types of each clause
+	clVarTys  map[*ASTNode]TypeInfo // Variable types of each clause
+	queryTys  TypeInfo              // Variable types of the query
+	queryVars []string              // Names of the query variables in order of appearance
+	netlist   *Netlist              // Netlist produced by the native backend
+	qmasmFile string                // Absolute name of the QMASM file produced by CompileThrough
+	typed     bool                  // Whether TypeCheck has been performed
+}
+
+// Parse parses a Prolog program, appending p.Query if non-empty, and
+// preprocesses the resulting AST.  p.InFileName is used for error messages.
+func Parse(p *Parameters, r io.Reader) (prog *Program, err error) {
+	defer recoverError(p, &err)
+
+	// If a query was specified, append it to the input file.
+	if p.Query != "" {
+		q := "?- " + p.Query
+		if !strings.HasSuffix(q, ".") {
+			q += "."
+		}
+		r = io.MultiReader(r, strings.NewReader(q))
+	}
+
+	// Parse the input file into an AST.
+	verbosePrintf(p, "Parsing %s as Prolog code", p.InFileName)
+	a, err := ParseReader(p.InFileName, r)
+	if err != nil {
+		return nil, convertParseError(p, err)
+	}
+	ast := a.(*ASTNode)
+
+	// Preprocess the AST.
+	if len(ast.FindByType(QueryType)) == 0 {
+		fatal("A query must be specified")
+	}
+	ast.ProcessDirectives(p)
+	ast.RejectUnimplemented(p)
+	ast.RenameAnonymousVars()
+	ast.StoreAtomNames(p)
+	ast.StoreFunctorNames(p)
+	ast.AdjustIntBits(p)
+	ast.AdjustListLen(p)
+	ast.BinClauses(p)
+	ast.CheckNegations(p)
+	verbosePrintf(p, "Representing symbols with %d bit(s) and integers with %d bit(s)", p.SymBits, p.IntBits)
+	if len(ast.FindByType(ListType)) > 0 {
+		verbosePrintf(p, "Representing lists with up to %d element(s)", p.MaxListLen)
+	}
+	p.OutFileBase = BaseName(p.InFileName)
+	return &Program{AST: ast, Params: p}, nil
+}
+
+// TypeCheck performs type inference on a parsed program, unrolls recursive
+// predicates into a fixed number of levels, chooses the width of each integer
+// argument and variable, checks the program's arithmetic for overflow, and
+// ensures that every query variable's value can be reported.
+// Unless p.Arith is "modular", in which case every integer is IntBits bits
+// wide and arithmetic wraps around, arithmetic is never allowed to overflow.
+func TypeCheck(prog *Program) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if prog.typed {
+		return nil
+	}
+	prog.nm2tys, prog.clVarTys = prog.AST.PerformTypeInference(p)
+	prog.AST.UnrollRecursion(p, prog.nm2tys, prog.clVarTys)
+	switch p.Arith {
+	case "", "exact", "checked":
+		prog.AST.InferIntWidths(p, prog.nm2tys, prog.clVarTys)
+	case "modular":
+	default:
+		fatalf("Unrecognized arithmetic semantics %q", p.Arith)
+	}
+	prog.AST.CheckOverflow(p, prog.clVarTys)
+	prog.AST.CheckQueryWidths(p, prog.nm2tys)
+	q := prog.AST.FindByType(QueryType)[0]
+	prog.queryTys = prog.clVarTys[q]
+	for _, v := range q.Children[0].FindByType(VariableType) {
+		prog.queryVars = append(prog.queryVars, v.Value.(string))
+	}
+	prog.typed = true
+	return nil
+}
+
+// EmitVerilog writes a type-checked program as Verilog code.
+func EmitVerilog(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("EmitVerilog requires a type-checked program")
+	}
+	prog.needSource("EmitVerilog")
+	prog.AST.WriteVerilog(w, p, prog.nm2tys, prog.clVarTys)
+	return nil
+}
+
+// buildNetlist lowers a type-checked program to a netlist with the native
+// backend, reusing the netlist from a previous call if there is one.
+func (prog *Program) buildNetlist() *Netlist {
+	if prog.netlist == nil {
+		prog.needSource("The native backend")
+		p := prog.Params
+		nl := prog.AST.BuildNetlist(p, prog.nm2tys, prog.clVarTys)
+		verbosePrintf(p, "Reduced the program to %d gate(s) over %d net(s)", len(nl.Gates), nl.NumNets)
+		prog.netlist = nl
+	}
+	return prog.netlist
+}
+
+// EmitCNF writes a type-checked program as a DIMACS CNF formula that is
+// satisfiable exactly when the query succeeds, and writes a map from the
+// query's ports to the formula's variables to m.
+func EmitCNF(prog *Program, w, m io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("EmitCNF requires a type-checked program")
+	}
+	nl := prog.buildNetlist()
+	nl.CNF(map[string]bool{"Query.Valid": true}).WriteDIMACS(w, p)
+	nl.WriteVarMap(m, p)
+	return nil
+}
+
+// EmitQUBO writes a type-checked program's Hamiltonian, with the query
+// asserted, as a QUBO in qbsolv's plain-text format.
+func EmitQUBO(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("EmitQUBO requires a type-checked program")
+	}
+	prog.buildNetlist().WriteQUBO(w, p, map[string]bool{"Query.Valid": true})
+	return nil
+}
+
+// EmitBQPJSON writes a type-checked program's Hamiltonian, with the query
+// asserted, as an Ising model in bqpjson format.
+func EmitBQPJSON(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("EmitBQPJSON requires a type-checked program")
+	}
+	prog.buildNetlist().WriteBQPJSON(w, p, map[string]bool{"Query.Valid": true})
+	return nil
+}
+
+// EmitSMT2 writes a type-checked program as an SMT-LIB 2 script over
+// bit vectors that asserts the query.
+func EmitSMT2(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("EmitSMT2 requires a type-checked program")
+	}
+	prog.needSource("EmitSMT2")
+	prog.AST.WriteSMT2(w, p, prog.nm2tys, prog.clVarTys)
+	return nil
+}
+
+// Stages lists, in order, the stages of compilation after which the
+// compiler can stop and leave behind an artifact.
+var Stages = []string{"parse", "types", "verilog", "edif", "qmasm"}
+
+// Compile converts a type-checked program to QMASM code in p.WorkDir,
+// creating a temporary directory if p.WorkDir is empty.  In that case,
+// p.WorkDir is set to the new directory and p.DeleteWorkDir is set to true.
+func Compile(prog *Program) error {
+	_, err := CompileThrough(prog, "qmasm")
+	return err
+}
+
+// CompileThrough is like Compile but stops after a given stage: "verilog",
+// "edif", or "qmasm".  It returns the name of the file produced by that
+// stage.  The native backend produces no EDIF netlist.  The metadata that
+// Resume needs is written alongside each stage's output.  The yosys backend
+// reuses EDIF and QMASM files from p.CacheDir when the Verilog code and the
+// external tools are unchanged.
+func CompileThrough(prog *Program, stage string) (fName string, err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("Compile requires a type-checked program")
+	}
+	prog.needSource("Compile")
+	switch stage {
+	case "verilog", "edif", "qmasm":
+	default:
+		fatalf("Compilation cannot stop after stage %q", stage)
+	}
+	p.WorkDir, p.DeleteWorkDir = createWorkDir(p)
+	qName, err := filepath.Abs(filepath.Join(p.WorkDir, p.OutFileBase+".qmasm"))
+	checkError(err)
+	prog.writeMetadataFile(qName)
+
+	if p.Backend == "native" && stage != "verilog" {
+		if stage == "edif" {
+			fatal("Producing an EDIF netlist requires the yosys backend")
+		}
+
+		// Convert the AST directly to QMASM code.
+		nl := prog.buildNetlist()
+		qf, err := os.Create(qName)
+		checkError(err)
+		verbosePrintf(p, "Writing QMASM code to %s", qName)
+		nl.WriteQMASM(qf, p)
+		checkError(qf.Close())
+		prog.qmasmFile = qName
+		return qName, nil
+	}
+
+	// Output Verilog code.
+	vName := filepath.Join(p.WorkDir, p.OutFileBase+".v")
+	vf, err := os.Create(vName)
+	checkError(err)
+	verbosePrintf(p, "Writing Verilog code to %s", vName)
+	prog.AST.WriteVerilog(vf, p, prog.nm2tys, prog.clVarTys)
+	checkError(vf.Close())
+	if stage == "verilog" {
+		return vName, nil
+	}
+
+	// Compile the Verilog code to an EDIF netlist unless a previous
+	// compilation already did so.
+	cache := newCompileCache(p, vName)
+	eName := filepath.Join(p.WorkDir, p.OutFileBase+".edif")
+	if !cache.fetch(".edif", eName) {
+		createYosysScript(p)
+		verbosePrintf(p, "Converting Verilog code to an EDIF netlist")
+		runCommand(p, "yosys", "-q", "-s", p.OutFileBase+".ys",
+			"-b", "edif", "-o", p.OutFileBase+".edif", p.OutFileBase+".v")
+		cache.store(".edif", eName)
+	}
+	if stage == "edif" {
+		return eName, nil
+	}
+
+	// Compile the EDIF netlist to QMASM code unless a previous compilation
+	// already did so.
+	if !cache.fetch(".qmasm", qName) {
+		verbosePrintf(p, "Converting the EDIF netlist to QMASM code")
+		runCommand(p, "edif2qmasm", "-o", p.OutFileBase+".qmasm", p.OutFileBase+".edif")
+		cache.store(".qmasm", qName)
+	}
+	prog.qmasmFile = qName
+	return qName, nil
+}
+
+// WriteTypes writes the inferred argument types of each clause group and the
+// inferred types of each clause's variables, sorted by clause-group name.
+func WriteTypes(prog *Program, w io.Writer) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("WriteTypes requires a type-checked program")
+	}
+	prog.needSource("WriteTypes")
+	names := make([]string, 0, len(p.TopLevel))
+	for nm := range p.TopLevel {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	for _, nm := range names {
+		tys := prog.nm2tys[nm]
+		tyStrs := make([]string, len(tys))
+		for i, ty := range tys {
+			tyStrs[i] = ty.String()
+		}
+		fmt.Fprintf(w, "%s(%s)\n", nm, strings.Join(tyStrs, ", "))
+		for i, cl := range p.TopLevel[nm] {
+			vTys := prog.clVarTys[cl]
+			vars := make([]string, 0, len(vTys))
+			for v := range vTys {
+				vars = append(vars, v)
+			}
+			sort.Strings(vars)
+			for j, v := range vars {
+				vars[j] = v + ": " + vTys[v].String()
+			}
+			if len(vars) == 0 {
+				fmt.Fprintf(w, "  clause %d: (no variables)\n", i+1)
+			} else {
+				fmt.Fprintf(w, "  clause %d: %s\n", i+1, strings.Join(vars, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Solve solves a type-checked program with the solver named by p.Solver and
+// returns the solutions found, discarding those that fail verification if
+// p.Verify is set.  All solvers except "reference" require that the program
+// first be compiled.
+func Solve(prog *Program) ([]Solution, error) {
+	s, err := NewSolver(prog.Params)
+	if err != nil {
+		return nil, err
+	}
+	return SolveWith(prog, s)
+}
+
+// SolveWith is like Solve but uses a caller-provided Solver.
+func SolveWith(prog *Program, s Solver) (sols []Solution, err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	if !prog.typed {
+		fatal("Solve requires a type-checked program")
+	}
+	if p.Verify {
+		prog.needSource("Verification")
+	}
+	sols, err = s.Solve(prog.problem())
+	checkError(err)
+	if p.Verify {
+		sols = prog.AST.verifySolutions(p, prog.clVarTys, sols)
+	}
+	return sols, nil
+}
+
+// WriteSolutions writes a list of solutions in the format named by p.Format.
+// In text format, an empty list of solutions is reported as an error.
+func WriteSolutions(prog *Program, w io.Writer, sols []Solution) (err error) {
+	p := prog.Params
+	defer recoverError(p, &err)
+	tys := prog.queryTypes()
+	switch p.Format {
+	case "json":
+		p.writeJSON(w, queryVarNames(prog.queryVars, tys), tys, sols)
+	case "csv":
+		p.writeCSV(w, queryVarNames(prog.queryVars, tys), tys, sols)
+	default:
+		if len(sols) == 0 {
+			fatal("No solutions were found")
+		}
+		p.writeText(w, tys, sols)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Test the compiler end to end on small programs.
+
+package qaprolog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testParams returns the parameters the command line uses by default, with a
+// given query.
+func testParams(query string) *Parameters {
+	return &Parameters{
+		ProgName:   "qa-prolog",
+		InFileName: "test.pl",
+		Query:      query,
+		Arith:      "exact",
+		Backend:    "native",
+		Solver:     "reference",
+		Format:     "text",
+	}
+}
+
+// typeCheck parses and type-checks a program.
+func typeCheck(p *Parameters, src string) (*Program, error) {
+	prog, err := Parse(p, strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	return prog, TypeCheck(prog)
+}
+
+// solveText solves a type-checked program with a given solver and returns its
+// solutions in text format, sorted so that solvers can be compared.
+func solveText(t *testing.T, prog *Program, solver string) string {
+	t.Helper()
+	var s Solver
+	switch solver {
+	case "reference":
+		s = referenceSolver{}
+	case "sat":
+		prog.buildNetlist()
+		s = satSolver{}
+	case "sa":
+		prog.buildNetlist()
+		s = annealSolver{}
+	default:
+		t.Fatalf("Unsupported solver %q", solver)
+	}
+	sols, err := SolveWith(prog, s)
+	if err != nil {
+		t.Fatalf("--solver=%s: %v", solver, err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSolutions(prog, &buf, sols); err != nil {
+		return err.Error()
+	}
+	sols1 := strings.Split(strings.TrimSpace(buf.String()), "\n\n")
+	sort.Strings(sols1)
+	return strings.Join(sols1, "\n\n")
+}
+
+// TestWideQuery ensures that a query variable too wide for its value to be
+// reported is rejected rather than silently decoded as the wrong value.
+func TestWideQuery(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{
+			"p([1,2,3]).\nq([1000,2000,3000,4000,5000]).\n",
+			[]string{"Query variable X requires 68 bits, but at most 63 are supported", "5000 on line 2"},
+		},
+		{
+			"p(f(1,2,3,4,5)).\nq(g(60000)).\n",
+			[]string{"Query variable X requires 81 bits, but at most 63 are supported"},
+		},
+	}
+	for _, tt := range tests {
+		_, err := typeCheck(testParams("p(X)"), tt.src)
+		if err == nil {
+			t.Errorf("%q: expected the query to be rejected", tt.src)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%q: expected an error containing %q but saw %q", tt.src, want, err)
+			}
+		}
+	}
+}
+
+// TestRoundTrip ensures that compound values are encoded and decoded
+// identically by the reference and SAT solvers.
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"p([1,2,3]).\np([]).\np([7]).\n", "X = [1, 2, 3]\n\nX = [7]\n\nX = []"},
+		{"p(f(a,2)).\np(g(3)).\np(f(b,0)).\n", "X = f(a, 2)\n\nX = f(b, 0)\n\nX = g(3)"},
+	}
+	for _, tt := range tests {
+		for _, solver := range []string{"reference", "sat"} {
+			prog, err := typeCheck(testParams("p(X)"), tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := solveText(t, prog, solver); got != tt.want {
+				t.Errorf("%q --solver=%s: expected %q but saw %q", tt.src, solver, tt.want, got)
+			}
+		}
+	}
+}
+
+// TestExamples ensures that the reference and SAT solvers find the same
+// solutions to each example program.  Modular arithmetic can admit more
+// solutions than exact arithmetic, so only the latter's are counted.
+func TestExamples(t *testing.T) {
+	examples := []struct {
+		file  string
+		query string
+		nSols int
+	}{
+		{"friends.pl", "friends(P1, P2)", 2},
+		{"light-meal.pl", "light_meal(A, M, D)", 6},
+		{"reachable.pl", "reachable(albuquerque, X)", 5},
+		{"potions.pl", "bottles(A, B, C, D, E, F, G)", 1},
+		{"potions-list.pl", "bottles(X)", 1},
+	}
+	for _, ex := range examples {
+		src, err := os.ReadFile(filepath.Join("..", "examples", ex.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, arith := range []string{"exact", "modular", "checked"} {
+			var sols [2]string
+			for i, solver := range []string{"reference", "sat"} {
+				p := testParams(ex.query)
+				p.InFileName = ex.file
+				p.Arith = arith
+				prog, err := typeCheck(p, string(src))
+				if err != nil {
+					t.Fatalf("%s --arith=%s: %v", ex.file, arith, err)
+				}
+				sols[i] = solveText(t, prog, solver)
+			}
+			if sols[0] != sols[1] {
+				t.Fatalf("%s --arith=%s: reference solver found\n%s\nbut SAT solver found\n%s",
+					ex.file, arith, sols[0], sols[1])
+			}
+			if n := strings.Count(sols[0], "\n\n") + 1; n != ex.nSols && arith != "modular" {
+				t.Fatalf("%s --arith=%s: expected %d solution(s) but saw %d", ex.file, arith, ex.nSols, n)
+			}
+		}
+	}
+}
+
+// TestNegationErrors ensures that negated goals whose variables are not bound
+// by a preceding goal are rejected with an error naming the variable as
+// written.
+func TestNegationErrors(t *testing.T) {
+	tests := []struct {
+		src   string
+		query string
+		want  string
+	}{
+		{
+			"hates(a, b).\nperson(a).\nlikes(X) :- person(X), \\+ hates(X, _).\n", "likes(a)",
+			`test.pl:3:36: Anonymous variable "_" cannot appear in negated goal "hates(X, _)"`,
+		},
+		{
+			"hates(a, b).\nlikes(X) :- \\+ hates(X, b).\n", "likes(a)",
+			`test.pl:2:22: Variable X in negated goal "hates(X, b)" must first appear in a preceding, non-negated goal in the clause body`,
+		},
+		{
+			"hates(a, b).\nperson(a).\nlikes(X) :- \\+ hates(X, b), person(X).\n", "likes(a)",
+			`test.pl:3:22: Variable X in negated goal "hates(X, b)" must first appear`,
+		},
+		{
+			"p(a).\nr(b).\nq(Y) :- (p(X) ; r(Y)), \\+ p(X).\n", "q(b)",
+			`test.pl:3:29: Variable X in negated goal "p(X)" must first appear`,
+		},
+		{
+			"hates(a, b).\n", "\\+ hates(a, Z)",
+			`Variable Z in negated goal "hates(a, Z)" must first appear in a preceding, non-negated goal in the query`,
+		},
+		{
+			"h(a, b).\nk(X) :- h(X, _).\nl(X) :- h(X, X), \\+ k(X).\n", "l(a)",
+			`test.pl:3:18: The negated goal "k(X)" is not supported because k/1 introduces anonymous variable "_"`,
+		},
+	}
+	for _, tt := range tests {
+		_, err := typeCheck(testParams(tt.query), tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q but saw %v", tt.query, tt.want, err)
+		}
+	}
+
+	// Variables bound by preceding goals are accepted.
+	src := "hates(a, b).\nperson(a).\nperson(b).\nlikes(X, Y) :- person(X), person(Y), \\+ hates(X, Y).\n"
+	want := "X = a\nY = a\n\nX = b\nY = a\n\nX = b\nY = b"
+	for _, solver := range []string{"reference", "sat"} {
+		prog, err := typeCheck(testParams("likes(X, Y)"), src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := solveText(t, prog, solver); got != want {
+			t.Errorf("--solver=%s: expected %q but saw %q", solver, want, got)
+		}
+	}
+}
+
+// TestConditionBindings ensures that the condition of an if-then branch can
+// bind variables for use by the rest of the condition and by the branch.
+func TestConditionBindings(t *testing.T) {
+	src := `color(red).
+color(green).
+color(blue).
+warm(red).
+label(C, T) :- color(C), ( W = C, warm(W) -> T = W ; T = cool ).
+first(Y) :- ( X = a -> Y = X ; Y = b ).
+`
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"label(C, T)", "C = blue\nT = cool\n\nC = green\nT = cool\n\nC = red\nT = red"},
+		{"first(Y)", "Y = a"},
+		{"( X = a -> Y = X ; Y = b )", "X = a\nY = a"},
+	}
+	for _, tt := range tests {
+		for _, solver := range []string{"reference", "sat"} {
+			prog, err := typeCheck(testParams(tt.query), src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := solveText(t, prog, solver); got != tt.want {
+				t.Errorf("%s --solver=%s: expected %q but saw %q", tt.query, solver, tt.want, got)
+			}
+		}
+	}
+
+	// Variables used outside their branch cannot be bound by a
+	// condition.
+	_, err := typeCheck(testParams("last(Y)"), src+"last(Y) :- ( X = a -> Y = X ; Y = X ).\n")
+	want := `test.pl:7:14: Variable X in condition "X = a" must first appear`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected an error containing %q but saw %v", want, err)
+	}
+}
@@ -0,0 +1,251 @@
+// Output a netlist's Hamiltonian in solver-neutral formats.
+
+package qaprolog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A spinModel is an Ising Hamiltonian over the free nets of a netlist.  Nets
+// whose values are fixed, either because they are constant or because they
+// represent a pinned port, are substituted out, with their contribution moved
+// to the model's linear terms and offset.
+type spinModel struct {
+	H      map[Net]float64    // Linear coefficients
+	J      map[[2]Net]float64 // Quadratic coefficients
+	Offset float64            // Constant energy term
+	Ports  map[string]Net     // Spin that represents each free port
+	Fixed  map[string]bool    // Value of each fixed port
+}
+
+// spinModel substitutes the given port values into a netlist's Hamiltonian.
+// As in WriteQMASM, every free port is represented by a spin, even if it is
+// unconstrained.
+func (n *Netlist) spinModel(pins map[string]bool) *spinModel {
+	// Determine which nets have a fixed value.
+	fixed := map[Net]bool{NetFalse: false, NetTrue: true}
+	for nm, b := range pins {
+		v, ok := n.Ports[nm]
+		if !ok {
+			fatalf("Cannot pin nonexistent port %s", nm)
+		}
+		if v != NetFalse && v != NetTrue {
+			fixed[v] = b
+		}
+	}
+	spin := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return -1
+	}
+
+	// Substitute the fixed nets into the Hamiltonian.
+	ham := n.Hamiltonian()
+	m := &spinModel{
+		H:     make(map[Net]float64, len(ham.H)),
+		J:     make(map[[2]Net]float64, len(ham.J)),
+		Ports: make(map[string]Net, len(n.Ports)),
+		Fixed: make(map[string]bool, len(pins)),
+	}
+	for v, w := range ham.H {
+		if b, ok := fixed[v]; ok {
+			m.Offset += w * spin(b)
+		} else {
+			m.H[v] += w
+		}
+	}
+	for vs, w := range ham.J {
+		b0, ok0 := fixed[vs[0]]
+		b1, ok1 := fixed[vs[1]]
+		switch {
+		case ok0 && ok1:
+			m.Offset += w * spin(b0) * spin(b1)
+		case ok0:
+			m.H[vs[1]] += w * spin(b0)
+		case ok1:
+			m.H[vs[0]] += w * spin(b1)
+		default:
+			m.J[vs] += w
+		}
+	}
+
+	// Associate each port with either a spin or a fixed value.
+	for nm, v := range n.Ports {
+		if b, ok := fixed[v]; ok {
+			m.Fixed[nm] = b
+			continue
+		}
+		m.Ports[nm] = v
+		if _, ok := m.H[v]; !ok {
+			m.H[v] = 0
+		}
+	}
+	return m
+}
+
+// spins returns the model's spins in increasing order.
+func (m *spinModel) spins() []Net {
+	vs := make([]Net, 0, len(m.H))
+	for v := range m.H {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i] < vs[j] })
+	return vs
+}
+
+// couplers returns the model's coupled pairs of spins in increasing order.
+func (m *spinModel) couplers() [][2]Net {
+	js := make([][2]Net, 0, len(m.J))
+	for vs := range m.J {
+		js = append(js, vs)
+	}
+	sort.Slice(js, func(i, j int) bool {
+		if js[i][0] != js[j][0] {
+			return js[i][0] < js[j][0]
+		}
+		return js[i][1] < js[j][1]
+	})
+	return js
+}
+
+// portNames returns the names of all ports in a map in sorted order.
+func portNames(ports map[string]Net, fixed map[string]bool) []string {
+	names := make([]string, 0, len(ports)+len(fixed))
+	for nm := range ports {
+		names = append(names, nm)
+	}
+	for nm := range fixed {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteQUBO writes a netlist's Hamiltonian, with the given ports pinned, as a
+// QUBO in the plain-text format used by qbsolv.  Variables are 0/1-valued and
+// are numbered by net.  Comments map each port to a variable or a fixed value
+// and give the constant energy offset.
+func (n *Netlist) WriteQUBO(w io.Writer, p *Parameters, pins map[string]bool) {
+	// Convert from spins in {-1, +1} to Booleans in {0, 1} by
+	// substituting 2x - 1 for each spin.
+	m := n.spinModel(pins)
+	diag := make(map[Net]float64, len(m.H))
+	offset := m.Offset
+	for v, h := range m.H {
+		diag[v] += 2 * h
+		offset -= h
+	}
+	off := make(map[[2]Net]float64, len(m.J))
+	for vs, j := range m.J {
+		off[vs] += 4 * j
+		diag[vs[0]] -= 2 * j
+		diag[vs[1]] -= 2 * j
+		offset += j
+	}
+
+	// Output some header comments, including the variable-name table.
+	vs := m.spins()
+	js := m.couplers()
+	fmt.Fprintf(w, "c QUBO version of Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "c Conversion by %s, written by Scott Pakin <pakin@lanl.gov>\n", p.ProgName)
+	fmt.Fprintln(w, "c")
+	fmt.Fprintf(w, "c Add %g to each energy to obtain the Ising energy.\n", offset)
+	fmt.Fprintln(w, "c")
+	for _, nm := range portNames(m.Ports, m.Fixed) {
+		if b, ok := m.Fixed[nm]; ok {
+			fmt.Fprintf(w, "c %s := %v\n", nm, b)
+		} else {
+			fmt.Fprintf(w, "c %s = %d\n", nm, m.Ports[nm])
+		}
+	}
+
+	// Output the coefficients.
+	maxID := 0
+	if len(vs) > 0 {
+		maxID = int(vs[len(vs)-1]) + 1
+	}
+	fmt.Fprintf(w, "p qubo 0 %d %d %d\n", maxID, len(vs), len(js))
+	for _, v := range vs {
+		fmt.Fprintf(w, "%d %d %g\n", v, v, diag[v])
+	}
+	for _, vs := range js {
+		fmt.Fprintf(w, "%d %d %g\n", vs[0], vs[1], off[vs])
+	}
+}
+
+// A bqpTerm is a linear term in a bqpjson file.
+type bqpTerm struct {
+	ID    int     `json:"id"`    // Variable ID
+	Coeff float64 `json:"coeff"` // Coefficient
+}
+
+// A bqpCoupler is a quadratic term in a bqpjson file.
+type bqpCoupler struct {
+	Tail  int     `json:"id_tail"` // Lower-numbered variable ID
+	Head  int     `json:"id_head"` // Higher-numbered variable ID
+	Coeff float64 `json:"coeff"`   // Coefficient
+}
+
+// A bqpMetadata holds the QA Prolog-specific portion of a bqpjson file.
+type bqpMetadata struct {
+	Program string          `json:"program"`     // Name of the Prolog program
+	Creator string          `json:"creator"`     // Name of the generating program
+	Ports   map[string]int  `json:"ports"`       // Variable ID of each free port
+	Fixed   map[string]bool `json:"fixed_ports"` // Value of each fixed port
+}
+
+// A bqpFile is the top-level object of a bqpjson file.
+type bqpFile struct {
+	Version   string       `json:"version"`         // bqpjson format version
+	ID        int          `json:"id"`              // Problem ID
+	Metadata  bqpMetadata  `json:"metadata"`        // Program-specific information
+	VarIDs    []int        `json:"variable_ids"`    // IDs of all variables
+	VarDomain string       `json:"variable_domain"` // Domain of each variable ("spin")
+	Scale     float64      `json:"scale"`           // Factor by which to multiply the energy
+	Offset    float64      `json:"offset"`          // Constant energy term
+	Linear    []bqpTerm    `json:"linear_terms"`    // Linear coefficients
+	Quadratic []bqpCoupler `json:"quadratic_terms"` // Quadratic coefficients
+}
+
+// WriteBQPJSON writes a netlist's Hamiltonian, with the given ports pinned,
+// in bqpjson format over spin variables numbered by net.  The metadata
+// maps each port to a variable or a fixed value.
+func (n *Netlist) WriteBQPJSON(w io.Writer, p *Parameters, pins map[string]bool) {
+	m := n.spinModel(pins)
+	f := bqpFile{
+		Version: "1.0.0",
+		Metadata: bqpMetadata{
+			Program: p.InFileName,
+			Creator: p.ProgName,
+			Ports:   make(map[string]int, len(m.Ports)),
+			Fixed:   m.Fixed,
+		},
+		VarDomain: "spin",
+		Scale:     1,
+		Offset:    m.Offset,
+		Quadratic: make([]bqpCoupler, 0, len(m.J)),
+	}
+	for nm, v := range m.Ports {
+		f.Metadata.Ports[nm] = int(v)
+	}
+	vs := m.spins()
+	f.VarIDs = make([]int, len(vs))
+	f.Linear = make([]bqpTerm, 0, len(vs))
+	for i, v := range vs {
+		f.VarIDs[i] = int(v)
+		if h := m.H[v]; h != 0 {
+			f.Linear = append(f.Linear, bqpTerm{ID: int(v), Coeff: h})
+		}
+	}
+	for _, vs := range m.couplers() {
+		f.Quadratic = append(f.Quadratic, bqpCoupler{Tail: int(vs[0]), Head: int(vs[1]), Coeff: m.J[vs]})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(f)
+	checkError(err)
+}
@@ -0,0 +1,1502 @@
+// Infer the number of bits needed for each integer argument and variable.
+
+package qaprolog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rangeLimit bounds the magnitude of every interval endpoint.  Interval
+// arithmetic saturates at ±rangeLimit rather than overflowing.
+const rangeLimit = int64(1) << 61
+
+// maxRangeRounds is the maximum number of times the goals in a clause body
+// are applied to the clause's variables.
+const maxRangeRounds = 8
+
+// An interval is the set of integers from Lo to Hi inclusive.  An interval
+// with Lo > Hi is empty.
+type interval struct {
+	Lo int64 // Smallest value in the interval
+	Hi int64 // Largest value in the interval
+}
+
+// emptyInterval is the canonical interval that contains no values.
+var emptyInterval = interval{Lo: 1, Hi: 0}
+
+// storageRange returns the interval of values that fit in a given number of
+// bits.
+func (p *Parameters) storageRange(bits uint) interval {
+	switch {
+	case bits >= 61 && p.Signed:
+		return interval{Lo: -rangeLimit, Hi: rangeLimit}
+	case bits >= 61:
+		return interval{Lo: 0, Hi: rangeLimit}
+	case p.Signed:
+		return interval{Lo: -(int64(1) << (bits - 1)), Hi: int64(1)<<(bits-1) - 1}
+	default:
+		return interval{Lo: 0, Hi: int64(1)<<bits - 1}
+	}
+}
+
+// bitsFor returns the number of bits needed to represent every nonnegative
+// integer up to a given value.  At least one bit is always used.
+func bitsFor(n int64) uint {
+	b := uint(1)
+	for b < 62 && n >= int64(1)<<b {
+		b++
+	}
+	return b
+}
+
+// intSignedness returns "signed" or "unsigned" to describe the program's
+// integers.
+func (p *Parameters) intSignedness() string {
+	if p.Signed {
+		return "signed"
+	}
+	return "unsigned"
+}
+
+// rangeBits returns the number of bits needed to represent every value in an
+// interval.  At least one bit is always used.
+func (p *Parameters) rangeBits(r interval) uint {
+	switch {
+	case r.empty():
+		return 1
+	case !p.Signed:
+		return bitsFor(r.Hi)
+	}
+
+	return signedBits(r)
+}
+
+// signedBits returns the number of bits needed to represent every value in a
+// nonempty interval as a two's-complement integer.
+func signedBits(r interval) uint {
+	// Signed integers need one more bit than the magnitude of the
+	// nonnegative value or of the one's complement of the negative value.
+	b := uint(1)
+	for _, n := range []int64{r.Lo, r.Hi} {
+		if n < 0 {
+			n = -n - 1
+		}
+		if n > 0 && bitsFor(n)+1 > b {
+			b = bitsFor(n) + 1
+		}
+	}
+	return b
+}
+
+// clampRange saturates a value at ±rangeLimit.
+func clampRange(n int64) int64 {
+	switch {
+	case n > rangeLimit:
+		return rangeLimit
+	case n < -rangeLimit:
+		return -rangeLimit
+	default:
+		return n
+	}
+}
+
+// mulRange multiplies two values, saturating at ±rangeLimit.
+func mulRange(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	ma, mb := a, b
+	if ma < 0 {
+		ma = -ma
+	}
+	if mb < 0 {
+		mb = -mb
+	}
+	if ma > rangeLimit/mb {
+		if (a < 0) != (b < 0) {
+			return -rangeLimit
+		}
+		return rangeLimit
+	}
+	return a * b
+}
+
+// empty reports whether an interval contains no values.
+func (r interval) empty() bool {
+	return r.Lo > r.Hi
+}
+
+// String formats an interval as "[lo, hi]".
+func (r interval) String() string {
+	if r.empty() {
+		return "[]"
+	}
+	return fmt.Sprintf("[%d, %d]", r.Lo, r.Hi)
+}
+
+// meet returns the intersection of two intervals.
+func (r interval) meet(s interval) interval {
+	if s.Lo > r.Lo {
+		r.Lo = s.Lo
+	}
+	if s.Hi < r.Hi {
+		r.Hi = s.Hi
+	}
+	if r.empty() {
+		return emptyInterval
+	}
+	return r
+}
+
+// join returns the smallest interval that contains two intervals.
+func (r interval) join(s interval) interval {
+	switch {
+	case r.empty():
+		return s
+	case s.empty():
+		return r
+	}
+	if s.Lo < r.Lo {
+		r.Lo = s.Lo
+	}
+	if s.Hi > r.Hi {
+		r.Hi = s.Hi
+	}
+	return r
+}
+
+// add returns the interval of all sums of values from two intervals.
+func (r interval) add(s interval) interval {
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	return interval{Lo: clampRange(r.Lo + s.Lo), Hi: clampRange(r.Hi + s.Hi)}
+}
+
+// neg returns the interval of the negations of an interval's values.
+func (r interval) neg() interval {
+	if r.empty() {
+		return emptyInterval
+	}
+	return interval{Lo: -r.Hi, Hi: -r.Lo}
+}
+
+// mul returns the interval of all products of values from two intervals.
+func (r interval) mul(s interval) interval {
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	ps := [4]int64{
+		mulRange(r.Lo, s.Lo),
+		mulRange(r.Lo, s.Hi),
+		mulRange(r.Hi, s.Lo),
+		mulRange(r.Hi, s.Hi),
+	}
+	m := interval{Lo: ps[0], Hi: ps[0]}
+	for _, v := range ps[1:] {
+		m = m.join(interval{Lo: v, Hi: v})
+	}
+	return m
+}
+
+// not returns the interval of the bitwise complements of an interval's
+// values.
+func (r interval) not() interval {
+	if r.empty() {
+		return emptyInterval
+	}
+	return interval{Lo: -r.Hi - 1, Hi: -r.Lo - 1}
+}
+
+// bitwiseRange returns the interval of all values that have as many bits as
+// the widest value in either of two intervals.  Bitwise operations on such
+// values produce values that also lie in the interval.  If both intervals
+// are nonnegative, the result is nonnegative.
+func bitwiseRange(r, s interval) interval {
+	if r.Lo >= 0 && s.Lo >= 0 {
+		return interval{Lo: 0, Hi: clampRange(int64(1)<<bitsFor(r.join(s).Hi) - 1)}
+	}
+	b := signedBits(r.join(s))
+	if b > 62 {
+		return interval{Lo: -rangeLimit, Hi: rangeLimit}
+	}
+	return interval{Lo: -(int64(1) << (b - 1)), Hi: int64(1)<<(b-1) - 1}
+}
+
+// and returns the interval of all bitwise conjunctions of values from two
+// intervals.
+func (r interval) and(s interval) interval {
+	switch {
+	case r.empty() || s.empty():
+		return emptyInterval
+	case r.Lo >= 0 && s.Lo >= 0:
+		return interval{Lo: 0, Hi: r.Hi}.meet(interval{Lo: 0, Hi: s.Hi})
+	case r.Lo >= 0:
+		return interval{Lo: 0, Hi: r.Hi}
+	case s.Lo >= 0:
+		return interval{Lo: 0, Hi: s.Hi}
+	default:
+		return bitwiseRange(r, s)
+	}
+}
+
+// or returns the interval of all bitwise disjunctions of values from two
+// intervals.
+func (r interval) or(s interval) interval {
+	switch {
+	case r.empty() || s.empty():
+		return emptyInterval
+	case r.Lo >= 0 && s.Lo >= 0:
+		hi := bitwiseRange(r, s).Hi
+		return interval{Lo: r.Lo, Hi: hi}.meet(interval{Lo: s.Lo, Hi: hi})
+	default:
+		return bitwiseRange(r, s)
+	}
+}
+
+// xor returns the interval of all bitwise exclusive ors of values from two
+// intervals.
+func (r interval) xor(s interval) interval {
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	return bitwiseRange(r, s)
+}
+
+// pow2Range returns 2 raised to a nonnegative power, saturating at
+// rangeLimit.
+func pow2Range(n int64) int64 {
+	if n >= 61 {
+		return rangeLimit
+	}
+	return int64(1) << uint(n)
+}
+
+// shl returns the interval of all values from one interval shifted left by
+// nonnegative values from another.  Negative shift amounts contribute no
+// values.
+func (r interval) shl(s interval) interval {
+	s = s.meet(interval{Lo: 0, Hi: rangeLimit})
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	return r.mul(interval{Lo: pow2Range(s.Lo), Hi: pow2Range(s.Hi)})
+}
+
+// shr returns the interval of all values from one interval shifted
+// arithmetically right by nonnegative values from another.  Negative shift
+// amounts contribute no values.
+func (r interval) shr(s interval) interval {
+	s = s.meet(interval{Lo: 0, Hi: rangeLimit})
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+
+	// The result is monotonic in both operands, so its extremes lie at
+	// the intervals' endpoints.
+	q := emptyInterval
+	for _, n := range []int64{r.Lo, r.Hi} {
+		for _, k := range []int64{s.Lo, s.Hi} {
+			if k > 63 {
+				k = 63
+			}
+			q = q.join(interval{Lo: n >> uint(k), Hi: n >> uint(k)})
+		}
+	}
+	return q
+}
+
+// nonzero splits an interval into its negative and its positive values.
+func (r interval) nonzero() (neg, pos interval) {
+	neg = r.meet(interval{Lo: -rangeLimit, Hi: -1})
+	pos = r.meet(interval{Lo: 1, Hi: rangeLimit})
+	return
+}
+
+// quo returns the interval of all quotients, truncated toward zero, of values
+// from two intervals.  Division by zero contributes no values.
+func (r interval) quo(s interval) interval {
+	q := emptyInterval
+	if r.empty() {
+		return q
+	}
+
+	// Within each sign of the divisor, the quotient is monotonic in both
+	// operands, so its extremes lie at the intervals' endpoints.
+	neg, pos := s.nonzero()
+	for _, d := range []interval{neg, pos} {
+		if d.empty() {
+			continue
+		}
+		for _, n := range []int64{r.Lo, r.Hi} {
+			for _, m := range []int64{d.Lo, d.Hi} {
+				q = q.join(interval{Lo: n / m, Hi: n / m})
+			}
+		}
+	}
+	return q
+}
+
+// rem returns the interval of all remainders of dividing values from one
+// interval by nonzero values from another.  A remainder takes the sign of the
+// dividend and is smaller in magnitude than the divisor.
+func (r interval) rem(s interval) interval {
+	neg, pos := s.nonzero()
+	m := int64(-1) // Largest magnitude of any remainder
+	if !neg.empty() {
+		m = -neg.Lo - 1
+	}
+	if !pos.empty() && pos.Hi-1 > m {
+		m = pos.Hi - 1
+	}
+	if r.empty() || m < 0 {
+		return emptyInterval
+	}
+	rm := interval{}
+	if r.Lo < 0 {
+		rm.Lo = r.Lo
+		if rm.Lo < -m {
+			rm.Lo = -m
+		}
+	}
+	if r.Hi > 0 {
+		rm.Hi = r.Hi
+		if rm.Hi > m {
+			rm.Hi = m
+		}
+	}
+	return rm
+}
+
+// mod returns the interval of all moduli of dividing values from one interval
+// by nonzero values from another.  A modulus takes the sign of the divisor
+// and is smaller in magnitude than the divisor.
+func (r interval) mod(s interval) interval {
+	m := emptyInterval
+	if r.empty() {
+		return m
+	}
+	neg, pos := s.nonzero()
+	if !pos.empty() {
+		hi := pos.Hi - 1
+		if r.Lo >= 0 && r.Hi < hi {
+			hi = r.Hi
+		}
+		m = m.join(interval{Lo: 0, Hi: hi})
+	}
+	if !neg.empty() {
+		lo := neg.Lo + 1
+		if r.Hi <= 0 && r.Lo > lo {
+			lo = r.Lo
+		}
+		m = m.join(interval{Lo: lo, Hi: 0})
+	}
+	return m
+}
+
+// abs returns the interval of the absolute values of an interval's values.
+func (r interval) abs() interval {
+	switch {
+	case r.empty():
+		return emptyInterval
+	case r.Lo >= 0:
+		return r
+	case r.Hi <= 0:
+		return r.neg()
+	}
+	hi := r.Hi
+	if -r.Lo > hi {
+		hi = -r.Lo
+	}
+	return interval{Lo: 0, Hi: hi}
+}
+
+// sign returns the interval of the signs (-1, 0, or 1) of an interval's
+// values.
+func (r interval) sign() interval {
+	if r.empty() {
+		return emptyInterval
+	}
+	s := interval{Lo: -1, Hi: 1}
+	switch {
+	case r.Lo > 0:
+		s.Lo = 1
+	case r.Lo == 0:
+		s.Lo = 0
+	}
+	switch {
+	case r.Hi < 0:
+		s.Hi = -1
+	case r.Hi == 0:
+		s.Hi = 0
+	}
+	return s
+}
+
+// min returns the interval of the lesser of two values, one from each of two
+// intervals.
+func (r interval) min(s interval) interval {
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	if s.Lo < r.Lo {
+		r.Lo = s.Lo
+	}
+	if s.Hi < r.Hi {
+		r.Hi = s.Hi
+	}
+	return r
+}
+
+// max returns the interval of the greater of two values, one from each of two
+// intervals.
+func (r interval) max(s interval) interval {
+	if r.empty() || s.empty() {
+		return emptyInterval
+	}
+	if s.Lo > r.Lo {
+		r.Lo = s.Lo
+	}
+	if s.Hi > r.Hi {
+		r.Hi = s.Hi
+	}
+	return r
+}
+
+// exprRange returns the interval of values an arithmetic expression can take
+// given an interval for each of its variables.  It returns false if the
+// expression is not an integer expression.
+func exprRange(a *ASTNode, env map[string]interval) (interval, bool) {
+	switch a.Type {
+	case NumeralType:
+		n := int64(a.Value.(int))
+		return interval{Lo: n, Hi: n}, true
+
+	case VariableType:
+		r, ok := env[a.Value.(string)]
+		return r, ok
+
+	case TermType, PrimaryExprType:
+		if !isFunction(a) {
+			return exprRange(a.Children[0], env)
+		}
+		rs := make([]interval, len(a.Children)-1)
+		for i, c := range a.Children[1:] {
+			var ok bool
+			if rs[i], ok = exprRange(c, env); !ok {
+				return emptyInterval, false
+			}
+		}
+		switch a.Value.(string) {
+		case "abs":
+			return rs[0].abs(), true
+		case "sign":
+			return rs[0].sign(), true
+		case "min":
+			return rs[0].min(rs[1]), true
+		case "max":
+			return rs[0].max(rs[1]), true
+		}
+
+	case UnaryExprType:
+		if len(a.Children) == 1 {
+			return exprRange(a.Children[0], env)
+		}
+		r, ok := exprRange(a.Children[1], env)
+		if a.Children[0].Value.(string) == "\\" {
+			return r.not(), ok
+		}
+		return r.neg(), ok
+
+	case AdditiveExprType, MultiplicativeExprType:
+		if len(a.Children) == 1 {
+			return exprRange(a.Children[0], env)
+		}
+		r1, ok1 := exprRange(a.Children[0], env)
+		r2, ok2 := exprRange(a.Children[2], env)
+		if !ok1 || !ok2 {
+			return emptyInterval, false
+		}
+		switch a.Children[1].Value.(string) {
+		case "+":
+			return r1.add(r2), true
+		case "-":
+			return r1.add(r2.neg()), true
+		case "/\\":
+			return r1.and(r2), true
+		case "\\/":
+			return r1.or(r2), true
+		case "xor":
+			return r1.xor(r2), true
+		case "*":
+			return r1.mul(r2), true
+		case "<<":
+			return r1.shl(r2), true
+		case ">>":
+			return r1.shr(r2), true
+		case "//", "/":
+			return r1.quo(r2), true
+		case "rem":
+			return r1.rem(r2), true
+		case "mod":
+			return r1.mod(r2), true
+		}
+	}
+	return emptyInterval, false
+}
+
+// loneVariable returns the variable an expression consists of or nil if the
+// expression is anything more than a variable.
+func loneVariable(a *ASTNode) *ASTNode {
+	for {
+		switch {
+		case a.Type == VariableType:
+			return a
+		case a.Type == ListType || a.Type == StructureType || len(a.Children) != 1:
+			return nil
+		}
+		a = a.Children[0]
+	}
+}
+
+// intOperand reports whether an arithmetic operand is an integer rather than
+// an atom.
+func intOperand(a *ASTNode, tys TypeInfo) bool {
+	switch a.Type {
+	case NumeralType:
+		return true
+	case VariableType:
+		return tys[a.Value.(string)] == InfNumeral
+	default:
+		return false
+	}
+}
+
+// flipRelation maps a relational operator to the operator that holds when
+// its operands are swapped.
+var flipRelation = map[string]string{
+	"=":   "=",
+	"\\=": "\\=",
+	"is":  "is",
+	"<":   ">",
+	">":   "<",
+	"=<":  ">=",
+	">=":  "=<",
+}
+
+// A RelWidth specifies how a relation between integer expressions, or an
+// arithmetic expression checked for overflow, is evaluated so that it cannot
+// overflow.  Each expression is evaluated with Bits bits after adding Bias,
+// which makes every possible value nonnegative.  With signed integers, Bias
+// is always zero, and comparisons are signed.
+type RelWidth struct {
+	Bits uint // Number of bits with which to evaluate each side
+	Bias int  // Constant to add to each side before comparing
+}
+
+// A DivWidth specifies how a division, modulo, remainder, or shift operation
+// or an evaluable function is evaluated so that neither its operands nor its
+// result can overflow.  All operands are evaluated with Bits bits, and the
+// result is extended to the width of the surrounding expression.  (Shifts
+// multiply or divide by a power of two, and abs, sign, min, and max compare
+// their operands, so they too need exact operands.)
+type DivWidth struct {
+	Bits   uint // Number of bits with which to evaluate each operand
+	Signed bool // Whether to divide or compare two's-complement rather than unsigned integers
+}
+
+// divWidth returns the DivWidth for a division, modulo, remainder, or shift
+// operation or an evaluable function.  Operations not listed in p.DivWidths
+// operate on IntBits-bit integers.
+func (p *Parameters) divWidth(e *ASTNode) DivWidth {
+	if dw, ok := p.DivWidths[e]; ok {
+		return dw
+	}
+	return DivWidth{Bits: p.IntBits, Signed: p.Signed}
+}
+
+// divOps is the set of arithmetic operators that divide (or, in the case of
+// "<<", multiply) one integer by another or by a power of two.
+var divOps = map[string]Empty{
+	"//":  {},
+	"/":   {},
+	"mod": {},
+	"rem": {},
+	"<<":  {},
+	">>":  {},
+}
+
+// isDivision reports whether an AST node is a division, modulo, remainder,
+// or shift operation.
+func isDivision(a *ASTNode) bool {
+	if a.Type != MultiplicativeExprType || len(a.Children) == 1 {
+		return false
+	}
+	_, ok := divOps[a.Children[1].Value.(string)]
+	return ok
+}
+
+// isFunction reports whether an AST node applies an evaluable function (abs,
+// sign, min, or max) to its arguments.
+func isFunction(a *ASTNode) bool {
+	return a.Type == PrimaryExprType && len(a.Children) > 1
+}
+
+// operands returns the operands of a division, modulo, remainder, or shift
+// operation or the arguments of an evaluable function.
+func operands(a *ASTNode) []*ASTNode {
+	if isFunction(a) {
+		return a.Children[1:]
+	}
+	return []*ASTNode{a.Children[0], a.Children[2]}
+}
+
+// divisionNodes returns the division, modulo, remainder, and shift operations
+// that appear in an AST, each preceded by all of the operations nested within
+// it.
+func divisionNodes(a *ASTNode) []*ASTNode {
+	ms := a.FindByType(MultiplicativeExprType)
+	ops := make([]*ASTNode, 0, len(ms))
+	for i := len(ms) - 1; i >= 0; i-- {
+		if isDivision(ms[i]) {
+			ops = append(ops, ms[i])
+		}
+	}
+	return ops
+}
+
+// divWidthNodes returns the operations that appear in an AST and that
+// p.DivWidths can describe, namely division, modulo, remainder, and shift
+// operations and evaluable functions, each preceded by all of the operations
+// nested within it.
+func divWidthNodes(a *ASTNode) []*ASTNode {
+	ops := make([]*ASTNode, 0, 4)
+	var walk func(*ASTNode)
+	walk = func(n *ASTNode) {
+		for _, c := range n.Children {
+			walk(c)
+		}
+		if isDivision(n) || isFunction(n) {
+			ops = append(ops, n)
+		}
+	}
+	walk(a)
+	return ops
+}
+
+// arithNodes returns the arithmetic operations (negations, additions,
+// subtractions, multiplications, divisions, bitwise operations, and
+// evaluable functions) that appear in an AST.
+func arithNodes(a *ASTNode) []*ASTNode {
+	ops := make([]*ASTNode, 0, 4)
+	for _, t := range []ASTNodeType{PrimaryExprType, UnaryExprType, AdditiveExprType, MultiplicativeExprType} {
+		for _, e := range a.FindByType(t) {
+			if len(e.Children) > 1 {
+				ops = append(ops, e)
+			}
+		}
+	}
+	return ops
+}
+
+// sortByPosition sorts AST nodes by their position in the input file.
+func sortByPosition(ns []*ASTNode) {
+	sort.Slice(ns, func(i, j int) bool {
+		pi, pj := ns[i].Pos, ns[j].Pos
+		if pi.line != pj.line {
+			return pi.line < pj.line
+		}
+		return pi.col < pj.col
+	})
+}
+
+// argBits returns the number of bits used for a clause group's ith argument.
+func (p *Parameters) argBits(nm string, i int, ty VarType) uint {
+	if bs := p.ArgBits[nm]; ty == InfNumeral && i < len(bs) && bs[i] > 0 {
+		return bs[i]
+	}
+	return p.typeBits(ty)
+}
+
+// varBits returns the number of bits used for a variable of a given clause.
+func (p *Parameters) varBits(cl *ASTNode, v string, ty VarType) uint {
+	if b, ok := p.VarBits[cl][v]; ok && ty == InfNumeral {
+		return b
+	}
+	return p.typeBits(ty)
+}
+
+// rebound reports whether a clause's ith argument is a variable that must be
+// copied to a variable of a different width.  This happens when a variable
+// that is stored in a list or structure is passed a wider integer.
+func (p *Parameters) rebound(nm string, cl *ASTNode, i int, vTy TypeInfo) bool {
+	v := cl.Children[0].Children[i+1].Children[0]
+	if v.Type != VariableType {
+		return false
+	}
+	ty := vTy[v.Value.(string)]
+	return ty == InfNumeral && p.varBits(cl, v.Value.(string), ty) != p.argBits(nm, i, ty)
+}
+
+// A rangeAnalysis infers the interval of values that each integer argument
+// of each clause group and each integer variable of each clause can take.
+type rangeAnalysis struct {
+	p         *Parameters                      // Global parameters
+	nm2tys    map[string]ArgTypes              // Argument types of each clause group
+	clVarTys  map[*ASTNode]TypeInfo            // Variable types of each clause
+	top       interval                         // Values that fit in IntBits bits
+	unbounded interval                         // Values an unconstrained variable can take
+	order     []string                         // Clause-group names, callers before callees
+	args      map[string][]interval            // Values each clause group's heads accept
+	incoming  map[string][]interval            // Values each clause group's callers pass
+	vars      map[*ASTNode]map[string]interval // Values each clause's variables take
+}
+
+// callersFirst returns the names of all clause groups, each preceded by all
+// of the clause groups that call it.
+func (r *rangeAnalysis) callersFirst() []string {
+	p := r.p
+	names := make([]string, 0, len(p.TopLevel))
+	for nm := range p.TopLevel {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	order := make([]string, 0, len(names))
+	seen := make(map[string]Empty, len(names))
+	var visit func(nm string)
+	visit = func(nm string) {
+		if _, ok := seen[nm]; ok {
+			return
+		}
+		seen[nm] = Empty{}
+		if _, lvl, ok := splitLevel(nm); !ok || lvl > 0 {
+			for _, cl := range p.TopLevel[nm] {
+				for _, pr := range cl.calls() {
+					if c := pr.calleeName(p); p.TopLevel[c] != nil {
+						visit(c)
+					}
+				}
+			}
+		}
+		order = append(order, nm)
+	}
+	for _, nm := range names {
+		visit(nm)
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// forcedVars returns the variables of a clause that appear within a list or
+// a structure.  Such variables always occupy IntBits bits.
+func forcedVars(cl *ASTNode) map[string]Empty {
+	forced := make(map[string]Empty)
+	for _, t := range append(cl.FindByType(ListType), cl.FindByType(StructureType)...) {
+		for _, v := range t.FindByType(VariableType) {
+			forced[v.Value.(string)] = Empty{}
+		}
+	}
+	return forced
+}
+
+// definedVars returns the variables of a clause that a goal in the clause's
+// body equates to an expression or passes to another clause group.  Such
+// variables take their values from the goal rather than defaulting to IntBits
+// bits.
+func definedVars(cl *ASTNode) map[string]Empty {
+	defined := make(map[string]Empty)
+	for _, g := range cl.Children[1:] {
+		if len(g.Children) == 1 {
+			g = g.Children[0]
+		}
+		switch {
+		case g.Type == PredicateType:
+			for _, t := range g.Children[1:] {
+				if v := loneVariable(t); v != nil {
+					defined[v.Value.(string)] = Empty{}
+				}
+			}
+		case g.Type == RelationType && (g.Value == "=" || g.Value == "is"):
+			for _, e := range []*ASTNode{g.Children[0], g.Children[2]} {
+				if v := loneVariable(e); v != nil {
+					defined[v.Value.(string)] = Empty{}
+				}
+			}
+		}
+	}
+	return defined
+}
+
+// narrow limits a variable's interval by a relation with an expression whose
+// values lie in a given interval.  It returns true if the variable's interval
+// changed.
+func narrow(env map[string]interval, v, op string, e interval) bool {
+	cur, ok := env[v]
+	if !ok || cur.empty() {
+		return false
+	}
+	var bound interval
+	switch op {
+	case "=", "is":
+		bound = e
+	case "<":
+		bound = interval{Lo: -rangeLimit, Hi: e.Hi - 1}
+	case "=<":
+		bound = interval{Lo: -rangeLimit, Hi: e.Hi}
+	case ">":
+		bound = interval{Lo: e.Lo + 1, Hi: rangeLimit}
+	case ">=":
+		bound = interval{Lo: e.Lo, Hi: rangeLimit}
+	default:
+		return false
+	}
+	if e.empty() {
+		bound = emptyInterval
+	}
+	next := cur.meet(bound)
+	if next == cur {
+		return false
+	}
+	env[v] = next
+	return true
+}
+
+// constrain limits variables' intervals by a goal that must hold.  It returns
+// true if any interval changed.
+func (r *rangeAnalysis) constrain(g *ASTNode, env map[string]interval) bool {
+	if g.Type == PredicateType && len(g.Children) == 1 {
+		g = g.Children[0]
+	}
+	changed := false
+	switch g.Type {
+	case PredicateType:
+		// A variable passed to another clause group takes only values
+		// that the clause group accepts.
+		hull, ok := r.args[g.calleeName(r.p)]
+		if !ok {
+			break
+		}
+		for j, t := range g.Children[1:] {
+			if v := loneVariable(t); v != nil && narrow(env, v.Value.(string), "=", hull[j]) {
+				changed = true
+			}
+		}
+
+	case RelationType:
+		// A variable compared to an expression takes only values
+		// that satisfy the comparison.
+		op := g.Value.(string)
+		e1, e2 := g.Children[0], g.Children[2]
+		if v := loneVariable(e1); v != nil {
+			if e, ok := exprRange(e2, env); ok && narrow(env, v.Value.(string), op, e) {
+				changed = true
+			}
+		}
+		if v := loneVariable(e2); v != nil {
+			if e, ok := exprRange(e1, env); ok && narrow(env, v.Value.(string), flipRelation[op], e) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// clauseRanges returns the interval of values each integer variable in a
+// clause can take.
+func (r *rangeAnalysis) clauseRanges(nm string, cl *ASTNode) map[string]interval {
+	// Variables that are neither defined by the clause body nor passed
+	// in by a caller default to IntBits bits.
+	vTy := r.clVarTys[cl]
+	forced := forcedVars(cl)
+	defined := definedVars(cl)
+	env := make(map[string]interval)
+	for _, v := range cl.FindByType(VariableType) {
+		vn := v.Value.(string)
+		if vTy[vn] != InfNumeral {
+			continue
+		}
+		env[vn] = r.unbounded
+		_, isDef := defined[vn]
+		_, isForced := forced[vn]
+		if !isDef || isForced {
+			env[vn] = r.top
+		}
+	}
+
+	// Head arguments that the body does not define take only the values
+	// that callers pass in.
+	if in, called := r.incoming[nm]; called {
+		for i, t := range cl.Children[0].Children[1:] {
+			v := t.Children[0]
+			if v.Type != VariableType {
+				continue
+			}
+			vn := v.Value.(string)
+			if _, isDef := defined[vn]; !isDef {
+				narrow(env, vn, "=", in[i])
+			}
+		}
+	}
+
+	// Apply each goal in the clause body until no interval changes.
+	for n := 0; n < maxRangeRounds; n++ {
+		changed := false
+		for _, g := range cl.Children[1:] {
+			if r.constrain(g, env) {
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Limit variables that are still unbounded to IntBits bits.
+	for vn, rg := range env {
+		if rg.Hi >= rangeLimit || rg.Lo <= -rangeLimit {
+			env[vn] = rg.meet(r.top)
+		}
+	}
+	return env
+}
+
+// storageBits returns the number of bits used to store each integer variable
+// in a clause.  Head variables are stored in the clause group's arguments, and
+// variables stored in lists or structures use IntBits bits.
+func (r *rangeAnalysis) storageBits(nm string, cl *ASTNode) map[string]uint {
+	p := r.p
+	env := r.vars[cl]
+	forced := forcedVars(cl)
+	bits := make(map[string]uint, len(env))
+	for i, t := range cl.Children[0].Children[1:] {
+		v := t.Children[0]
+		if v.Type != VariableType {
+			continue
+		}
+		vn := v.Value.(string)
+		if _, ok := env[vn]; !ok {
+			continue
+		}
+		if _, seen := bits[vn]; !seen {
+			bits[vn] = p.ArgBits[nm][i]
+		}
+	}
+	for vn, rg := range env {
+		_, isForced := forced[vn]
+		switch _, seen := bits[vn]; {
+		case isForced:
+			bits[vn] = p.IntBits
+		case !seen:
+			bits[vn] = p.rangeBits(rg)
+		}
+	}
+	return bits
+}
+
+// pass performs one pass of the analysis over all clause groups, callers
+// before callees.  It returns true if any clause group's argument intervals
+// changed.
+func (r *rangeAnalysis) pass() bool {
+	p := r.p
+	args := make(map[string][]interval, len(r.order))
+	r.incoming = make(map[string][]interval, len(r.order))
+	p.ArgBits = make(map[string][]uint, len(r.order))
+	p.VarBits = make(map[*ASTNode]map[string]uint, len(r.vars))
+	for _, nm := range r.order {
+		// Determine the values each clause's head accepts.  The lowest
+		// level of a recursive clause group accepts nothing.
+		tys := r.nm2tys[nm]
+		cls := p.TopLevel[nm]
+		_, lvl, recursive := splitLevel(nm)
+		live := !recursive || lvl > 0
+		hull := make([]interval, len(tys))
+		for i := range hull {
+			hull[i] = emptyInterval
+		}
+		for _, cl := range cls {
+			if !live {
+				break
+			}
+			env := r.clauseRanges(nm, cl)
+			r.vars[cl] = env
+			for i, t := range cl.Children[0].Children[1:] {
+				if rg, ok := exprRange(t, env); ok {
+					hull[i] = hull[i].join(rg)
+				}
+			}
+		}
+		args[nm] = hull
+
+		// Make each integer argument wide enough for both the values
+		// the clause group accepts and the values its callers pass.
+		in, called := r.incoming[nm]
+		bits := make([]uint, len(tys))
+		for i, ty := range tys {
+			if ty != InfNumeral {
+				continue
+			}
+			rg := hull[i]
+			if called {
+				rg = rg.join(in[i])
+			}
+			bits[i] = p.rangeBits(rg)
+		}
+		p.ArgBits[nm] = bits
+		if !live {
+			continue
+		}
+
+		// Arguments stored in lists or structures need at least
+		// IntBits bits.
+		for _, cl := range cls {
+			forced := forcedVars(cl)
+			for i, t := range cl.Children[0].Children[1:] {
+				if _, isForced := forced[t.Children[0].Text]; isForced && bits[i] < p.IntBits {
+					bits[i] = p.IntBits
+				}
+			}
+		}
+
+		// Determine each variable's width and the values each call
+		// passes to its callee.
+		for _, cl := range cls {
+			vBits := r.storageBits(nm, cl)
+			p.VarBits[cl] = vBits
+			for _, pr := range cl.calls() {
+				cNm := pr.calleeName(p)
+				cTys, ok := r.nm2tys[cNm]
+				if !ok || p.TopLevel[cNm] == nil {
+					continue
+				}
+				cIn, ok := r.incoming[cNm]
+				if !ok {
+					cIn = make([]interval, len(cTys))
+					for j := range cIn {
+						cIn[j] = emptyInterval
+					}
+					r.incoming[cNm] = cIn
+				}
+				for j, t := range pr.Children[1:] {
+					if cTys[j] != InfNumeral {
+						continue
+					}
+					switch c := t.Children[0]; c.Type {
+					case NumeralType:
+						n := int64(c.Value.(int))
+						cIn[j] = cIn[j].join(interval{Lo: n, Hi: n})
+					case VariableType:
+						if b, ok := vBits[c.Value.(string)]; ok {
+							cIn[j] = cIn[j].join(p.storageRange(b))
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Report whether any argument interval changed.
+	changed := false
+	for nm, hull := range args {
+		old, ok := r.args[nm]
+		if !ok {
+			changed = true
+			break
+		}
+		for i := range hull {
+			if hull[i] != old[i] {
+				changed = true
+			}
+		}
+	}
+	r.args = args
+	return changed
+}
+
+// relWidths determines how to evaluate each relation between integer
+// expressions in a clause so that it cannot overflow.  A relation needs an
+// entry only if its operands' widths are insufficient or if either side of an
+// unsigned relation can be negative.
+func (r *rangeAnalysis) relWidths(cl *ASTNode) {
+	p := r.p
+	vBits := p.VarBits[cl]
+	env := make(map[string]interval, len(vBits))
+	for vn, b := range vBits {
+		env[vn] = p.storageRange(b)
+	}
+	for _, rel := range cl.FindByType(RelationType) {
+		e1, e2 := rel.Children[0], rel.Children[2]
+		r1, ok1 := exprRange(e1, env)
+		r2, ok2 := exprRange(e2, env)
+		if !ok1 || !ok2 || r1.empty() || r2.empty() {
+			continue
+		}
+
+		// Determine the width of the widest operand.
+		natural := uint(0)
+		if len(rel.FindByType(NumeralType)) > 0 {
+			natural = p.IntBits
+		}
+		for _, v := range rel.FindByType(VariableType) {
+			if b := vBits[v.Value.(string)]; b > natural {
+				natural = b
+			}
+		}
+
+		// Equality is exact modulo any width that can represent the
+		// difference between the two sides.  An unsigned ordering
+		// additionally requires that both sides be made nonnegative.
+		all := r1.join(r2).join(interval{})
+		rw := RelWidth{Bits: bitsFor(all.Hi - all.Lo)}
+		switch op := rel.Value.(string); {
+		case p.Signed:
+			rw.Bits = p.rangeBits(r1.join(r2))
+		case op == "=", op == "is", op == "\\=":
+		default:
+			rw.Bias = int(-all.Lo)
+		}
+		if rw.Bits <= natural && rw.Bias == 0 {
+			continue
+		}
+		if rw.Bits < natural {
+			rw.Bits = natural
+		}
+		p.RelWidths[rel] = rw
+	}
+}
+
+// divWidths determines how to evaluate each division, modulo, remainder, or
+// shift operation and each evaluable function in a clause so that it is
+// exact.  An operation is evaluated with enough bits for the values of its
+// operands and its result and never with fewer bits than its operands'
+// variables, numerals, or nested operations of the same sort.  Operations
+// whose operands or result can be negative operate on two's-complement
+// integers even if the program's integers are unsigned.
+func (r *rangeAnalysis) divWidths(cl *ASTNode) {
+	p := r.p
+	vBits := p.VarBits[cl]
+	env := make(map[string]interval, len(vBits))
+	for vn, b := range vBits {
+		env[vn] = p.storageRange(b)
+	}
+	for _, e := range divWidthNodes(cl) {
+		all, ok := exprRange(e, env)
+		for _, c := range operands(e) {
+			r, okc := exprRange(c, env)
+			all = all.join(r)
+			ok = ok && okc
+		}
+		if !ok {
+			continue
+		}
+		dw := DivWidth{Signed: p.Signed || all.Lo < 0}
+		if dw.Signed {
+			dw.Bits = signedBits(all)
+		} else {
+			dw.Bits = bitsFor(all.Hi)
+		}
+		for _, c := range operands(e) {
+			if len(c.FindByType(NumeralType)) > 0 && p.IntBits > dw.Bits {
+				dw.Bits = p.IntBits
+			}
+			for _, v := range c.FindByType(VariableType) {
+				if b := vBits[v.Value.(string)]; b > dw.Bits {
+					dw.Bits = b
+				}
+			}
+			for _, d := range divWidthNodes(c) {
+				if b := p.DivWidths[d].Bits; b > dw.Bits {
+					dw.Bits = b
+				}
+			}
+		}
+		p.DivWidths[e] = dw
+	}
+}
+
+// InferIntWidths chooses the number of bits with which to represent each
+// integer argument of each clause group and each integer variable of each
+// clause, storing the results in p.ArgBits and p.VarBits.  Widths are
+// derived from an interval analysis over the program's facts, literals, and
+// arithmetic.  Relations whose arithmetic could overflow the widths of their
+// operands are recorded in p.RelWidths and the widths at which to evaluate
+// divisions in p.DivWidths.
+func (a *ASTNode) InferIntWidths(p *Parameters, nm2tys map[string]ArgTypes, clVarTys map[*ASTNode]TypeInfo) {
+	// Iterate until no clause group's argument intervals change.
+	r := &rangeAnalysis{
+		p:         p,
+		nm2tys:    nm2tys,
+		clVarTys:  clVarTys,
+		top:       p.storageRange(p.IntBits),
+		unbounded: p.storageRange(64),
+		args:      make(map[string][]interval),
+		vars:      make(map[*ASTNode]map[string]interval),
+	}
+	r.order = r.callersFirst()
+	for n := 0; n < len(r.order)+2 && r.pass(); n++ {
+	}
+
+	// Widen relations and divisions that could otherwise overflow.
+	p.RelWidths = make(map[*ASTNode]RelWidth)
+	p.DivWidths = make(map[*ASTNode]DivWidth)
+	for cl := range p.VarBits {
+		r.relWidths(cl)
+		r.divWidths(cl)
+	}
+	if !p.Verbose {
+		return
+	}
+
+	// Report the chosen widths.
+	for _, nm := range r.order {
+		strs := make([]string, 0, len(p.ArgBits[nm]))
+		for i, b := range p.ArgBits[nm] {
+			if b > 0 {
+				strs = append(strs, fmt.Sprintf("argument %d: %d bit(s) %v", i+1, b, r.args[nm][i]))
+			}
+		}
+		if len(strs) > 0 {
+			verbosePrintf(p, "Integer widths for %s: %s", nm, strings.Join(strs, ", "))
+		}
+		for i, cl := range p.TopLevel[nm] {
+			vBits := p.VarBits[cl]
+			vars := make([]string, 0, len(vBits))
+			for vn := range vBits {
+				vars = append(vars, vn)
+			}
+			sort.Strings(vars)
+			for j, vn := range vars {
+				vars[j] = fmt.Sprintf("%s: %d bit(s) %v", vn, vBits[vn], r.vars[cl][vn])
+			}
+			if len(vars) > 0 {
+				verbosePrintf(p, "Integer widths for clause %d of %s: %s", i+1, nm, strings.Join(vars, ", "))
+			}
+		}
+	}
+	rels := make([]*ASTNode, 0, len(p.RelWidths))
+	for rel := range p.RelWidths {
+		rels = append(rels, rel)
+	}
+	sortByPosition(rels)
+	var prev position
+	for _, rel := range rels {
+		if rel.Pos == prev {
+			continue // Recursion unrolling copies relations.
+		}
+		prev = rel.Pos
+		rw := p.RelWidths[rel]
+		verbosePrintf(p, "Evaluating %q with %d bit(s) and a bias of %d", rel.Text, rw.Bits, rw.Bias)
+	}
+	divs := make([]*ASTNode, 0, len(p.DivWidths))
+	for e := range p.DivWidths {
+		divs = append(divs, e)
+	}
+	sortByPosition(divs)
+	seen := make(map[string]Empty, len(divs))
+	for _, e := range divs {
+		// Chained divisions share a position, and recursion unrolling
+		// copies divisions, so report each distinct message once.
+		dw := p.DivWidths[e]
+		sign := "unsigned"
+		if dw.Signed {
+			sign = "signed"
+		}
+		msg := fmt.Sprintf("Evaluating %q with %d-bit %s operands", e.Text, dw.Bits, sign)
+		if _, dup := seen[msg]; !dup {
+			seen[msg] = Empty{}
+			verbosePrintf(p, "%s", msg)
+		}
+	}
+}
+
+// storageRanges returns the interval of values each integer variable in a
+// clause can hold.
+func (p *Parameters) storageRanges(cl *ASTNode, vTy TypeInfo) map[string]interval {
+	env := make(map[string]interval, len(vTy))
+	for vn, ty := range vTy {
+		if ty == InfNumeral {
+			env[vn] = p.storageRange(p.varBits(cl, vn, ty))
+		}
+	}
+	return env
+}
+
+// overflowOnly reports whether a relation between an integer expression
+// whose values lie in r and a constant n can hold only if the expression's
+// arithmetic overflows w bits.
+func (p *Parameters) overflowOnly(op string, r interval, n int64, w uint) bool {
+	if r.meet(p.storageRange(w)) == r {
+		return false // The expression cannot overflow.
+	}
+	switch op {
+	case "=", "is":
+		return n < r.Lo || n > r.Hi
+	case "\\=":
+		return r.Lo == n && r.Hi == n
+	case "<":
+		return r.Lo >= n
+	case "=<":
+		return r.Lo > n
+	case ">":
+		return r.Hi <= n
+	case ">=":
+		return r.Hi < n
+	default:
+		return false
+	}
+}
+
+// groundOverflowOnly reports whether a relation between two constant integer
+// expressions holds only if its arithmetic wraps around at IntBits bits.
+func (p *Parameters) groundOverflowOnly(rel *ASTNode) bool {
+	holds := func(arith string) bool {
+		q := *p
+		q.Arith = arith
+		m := newRefMachine(&q, nil)
+		v1, ok1 := m.evalInt(rel.Children[0], nil)
+		v2, ok2 := m.evalInt(rel.Children[2], nil)
+		return ok1 && ok2 && intRelation(rel.Value.(string), v1, v2)
+	}
+	return !holds("exact") && holds("modular")
+}
+
+// CheckOverflow warns about each relation between an integer expression and
+// a constant, or between two constant expressions, that can be satisfied only
+// by arithmetic overflow.  If p.Arith is "checked", it also records in
+// p.ArithChecks each arithmetic operation whose value might not fit in
+// IntBits bits.
+func (a *ASTNode) CheckOverflow(p *Parameters, clVarTys map[*ASTNode]TypeInfo) {
+	p.ArithChecks = make(map[*ASTNode]RelWidth)
+	top := p.storageRange(p.IntBits)
+	warn := make([]*ASTNode, 0)
+	for _, cls := range p.TopLevel {
+		for _, cl := range cls {
+			env := p.storageRanges(cl, clVarTys[cl])
+			for _, rel := range cl.FindByType(RelationType) {
+				// Compare the widest operand's width with the
+				// values a non-constant side can take.
+				e1, e2 := rel.Children[0], rel.Children[2]
+				op := rel.Value.(string)
+				r1, ok1 := exprRange(e1, env)
+				r2, ok2 := exprRange(e2, env)
+				if !ok1 || !ok2 {
+					continue
+				}
+				nv1 := len(e1.FindByType(VariableType))
+				nv2 := len(e2.FindByType(VariableType))
+				switch {
+				case nv1 == 0 && nv2 == 0:
+					// Evaluate both sides with and
+					// without wrapping around.
+					if p.groundOverflowOnly(rel) {
+						warn = append(warn, rel)
+					}
+					continue
+				case nv1 == 0:
+					e1, r1, r2 = e2, r2, r1
+					op = flipRelation[op]
+				case nv2 > 0:
+					continue
+				}
+				if r1.empty() {
+					continue
+				}
+				natural := p.IntBits
+				for _, v := range e1.FindByType(VariableType) {
+					if b := p.varBits(cl, v.Value.(string), InfNumeral); b > natural {
+						natural = b
+					}
+				}
+				if p.overflowOnly(op, r1, r2.Lo, natural) {
+					warn = append(warn, rel)
+				}
+			}
+			if p.Arith != "checked" {
+				continue
+			}
+
+			// Check every arithmetic operation whose value might
+			// not fit in IntBits bits.
+			for _, e := range arithNodes(cl) {
+				r, ok := exprRange(e, env)
+				if !ok || r.meet(top) == r {
+					continue
+				}
+				ck := RelWidth{}
+				switch {
+				case p.Signed:
+					ck.Bits = p.rangeBits(r.join(top))
+				case r.Lo < 0:
+					ck.Bias = int(-r.Lo)
+					fallthrough
+				default:
+					ck.Bits = bitsFor(r.join(top).Hi + int64(ck.Bias))
+				}
+				p.ArithChecks[e] = ck
+			}
+		}
+	}
+	if p.Log == nil {
+		return
+	}
+
+	// Report each offending relation once, even if recursion unrolling
+	// copied it.
+	sortByPosition(warn)
+	var prev position
+	for _, rel := range warn {
+		if rel.Pos == prev {
+			continue
+		}
+		prev = rel.Pos
+		msg := "will always fail because it could be satisfied only by arithmetic overflow"
+		if p.Arith == "modular" {
+			msg = "can be satisfied only by arithmetic overflow"
+		}
+		p.Log.Printf("%s:%d:%d: Warning: %q %s", p.InFileName, rel.Pos.line, rel.Pos.col, rel.Text, msg)
+	}
+}
+
+// maxValueBits is the maximum width of a query variable.  A solution holds
+// each query variable's value in a Go int.
+const maxValueBits = 63
+
+// widestNumeral describes the first integer literal that requires all IntBits
+// bits, or returns the empty string if IntBits was set some other way.
+func (a *ASTNode) widestNumeral(p *Parameters) string {
+	for _, n := range a.FindByType(NumeralType) {
+		if p.numeralBits(n.Value.(int)) == p.IntBits {
+			return fmt.Sprintf(" to accommodate %s on line %d", n.Text, n.Pos.line)
+		}
+	}
+	return ""
+}
+
+// CheckQueryWidths aborts if any query variable is too wide for its value to
+// be reported.  Every integer in a list or structure is stored with IntBits
+// bits, regardless of the widths inferred for integer variables, so a single
+// large literal can widen every list or structure in the program.  In verbose
+// mode, CheckQueryWidths reports the width of each list or structure in the
+// query so this widening is visible even when it fits.
+func (a *ASTNode) CheckQueryWidths(p *Parameters, nm2tys map[string]ArgTypes) {
+	q := a.FindByType(QueryType)[0]
+	nm := q.Value.(string)
+	_, vArgs := q.args()
+	why := a.widestNumeral(p)
+	for i, v := range vArgs {
+		ty := nm2tys[nm][i]
+		b := p.argBits(nm, i, ty)
+		switch {
+		case b > maxValueBits:
+			parseError(q.Children[0].Children[i+1].Pos,
+				"Query variable %s requires %d bits, but at most %d are supported (each integer in a list or structure takes %d bits%s)",
+				v, b, maxValueBits, p.IntBits, why)
+		case ty >= InfList:
+			verbosePrintf(p, "Query variable %s occupies %d bit(s), with each integer in a list or structure taking %d bit(s)%s",
+				v, b, p.IntBits, why)
+		}
+	}
+}
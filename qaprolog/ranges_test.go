@@ -0,0 +1,178 @@
+// Test interval arithmetic.
+
+package qaprolog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// iv is shorthand for constructing an interval.
+func iv(lo, hi int64) interval {
+	return interval{Lo: lo, Hi: hi}
+}
+
+// TestIntervalUnary tests the interval operations that take one operand.
+func TestIntervalUnary(t *testing.T) {
+	ops := map[string]func(interval) interval{
+		"neg":  interval.neg,
+		"not":  interval.not,
+		"abs":  interval.abs,
+		"sign": interval.sign,
+	}
+	tests := []struct {
+		op   string
+		r    interval
+		want interval
+	}{
+		{"neg", iv(1, 3), iv(-3, -1)},
+		{"neg", iv(-2, 5), iv(-5, 2)},
+		{"neg", emptyInterval, emptyInterval},
+		{"not", iv(0, 3), iv(-4, -1)},
+		{"not", iv(-4, -1), iv(0, 3)},
+		{"abs", iv(2, 5), iv(2, 5)},
+		{"abs", iv(-5, -2), iv(2, 5)},
+		{"abs", iv(-5, 3), iv(0, 5)},
+		{"abs", emptyInterval, emptyInterval},
+		{"sign", iv(-5, 3), iv(-1, 1)},
+		{"sign", iv(0, 3), iv(0, 1)},
+		{"sign", iv(2, 3), iv(1, 1)},
+		{"sign", iv(-3, -2), iv(-1, -1)},
+		{"sign", iv(0, 0), iv(0, 0)},
+	}
+	for _, tt := range tests {
+		if got := ops[tt.op](tt.r); got != tt.want {
+			t.Errorf("%s %v: expected %v but saw %v", tt.op, tt.r, tt.want, got)
+		}
+	}
+}
+
+// TestIntervalBinary tests the interval operations that take two operands.
+func TestIntervalBinary(t *testing.T) {
+	ops := map[string]func(interval, interval) interval{
+		"meet": interval.meet,
+		"join": interval.join,
+		"add":  interval.add,
+		"mul":  interval.mul,
+		"and":  interval.and,
+		"or":   interval.or,
+		"xor":  interval.xor,
+		"shl":  interval.shl,
+		"shr":  interval.shr,
+		"quo":  interval.quo,
+		"rem":  interval.rem,
+		"mod":  interval.mod,
+		"min":  interval.min,
+		"max":  interval.max,
+	}
+	big := iv(rangeLimit, rangeLimit)
+	tests := []struct {
+		op   string
+		r, s interval
+		want interval
+	}{
+		{"meet", iv(1, 5), iv(3, 8), iv(3, 5)},
+		{"meet", iv(1, 2), iv(3, 8), emptyInterval},
+		{"join", iv(1, 2), iv(5, 8), iv(1, 8)},
+		{"join", emptyInterval, iv(5, 8), iv(5, 8)},
+		{"add", iv(1, 3), iv(10, 20), iv(11, 23)},
+		{"add", iv(-3, 3), iv(-1, 0), iv(-4, 3)},
+		{"add", big, iv(1, 1), big},
+		{"add", emptyInterval, iv(1, 1), emptyInterval},
+		{"mul", iv(-2, 3), iv(4, 5), iv(-10, 15)},
+		{"mul", iv(-2, -1), iv(-3, 4), iv(-8, 6)},
+		{"mul", iv(1<<40, 1<<40), iv(-(1 << 40), 1<<40), iv(-rangeLimit, rangeLimit)},
+		{"and", iv(0, 5), iv(0, 3), iv(0, 3)},
+		{"and", iv(-4, -1), iv(0, 6), iv(0, 6)},
+		{"and", iv(-4, -1), iv(-2, 1), iv(-4, 3)},
+		{"or", iv(1, 5), iv(2, 3), iv(2, 7)},
+		{"or", iv(-1, 0), iv(0, 1), iv(-2, 1)},
+		{"xor", iv(0, 5), iv(0, 3), iv(0, 7)},
+		{"xor", iv(-1, 0), iv(0, 1), iv(-2, 1)},
+		{"shl", iv(1, 3), iv(0, 2), iv(1, 12)},
+		{"shl", iv(1, 1), iv(-5, -1), emptyInterval},
+		{"shl", iv(1, 1), iv(70, 70), big},
+		{"shr", iv(-8, 8), iv(1, 2), iv(-4, 4)},
+		{"shr", iv(5, 5), iv(-2, -1), emptyInterval},
+		{"quo", iv(7, 9), iv(2, 3), iv(2, 4)},
+		{"quo", iv(-7, 7), iv(-1, 1), iv(-7, 7)},
+		{"quo", iv(-7, -7), iv(2, 2), iv(-3, -3)},
+		{"quo", iv(1, 5), iv(0, 0), emptyInterval},
+		{"rem", iv(-7, 7), iv(1, 3), iv(-2, 2)},
+		{"rem", iv(0, 1), iv(5, 5), iv(0, 1)},
+		{"rem", iv(-7, -7), iv(-3, -3), iv(-2, 0)},
+		{"rem", iv(1, 5), iv(0, 0), emptyInterval},
+		{"mod", iv(-7, 7), iv(3, 3), iv(0, 2)},
+		{"mod", iv(-7, 7), iv(-3, -3), iv(-2, 0)},
+		{"mod", iv(0, 1), iv(5, 5), iv(0, 1)},
+		{"mod", iv(1, 5), iv(0, 0), emptyInterval},
+		{"min", iv(1, 10), iv(3, 5), iv(1, 5)},
+		{"min", iv(-3, -1), iv(0, 2), iv(-3, -1)},
+		{"max", iv(1, 10), iv(3, 5), iv(3, 10)},
+		{"max", iv(-3, -1), iv(0, 2), iv(0, 2)},
+		{"max", emptyInterval, iv(0, 2), emptyInterval},
+	}
+	for _, tt := range tests {
+		if got := ops[tt.op](tt.r, tt.s); got != tt.want {
+			t.Errorf("%v %s %v: expected %v but saw %v", tt.r, tt.op, tt.s, tt.want, got)
+		}
+	}
+}
+
+// TestRangeBits tests the number of bits needed to store an interval.
+func TestRangeBits(t *testing.T) {
+	tests := []struct {
+		signed bool
+		r      interval
+		want   uint
+	}{
+		{false, iv(0, 0), 1},
+		{false, iv(0, 1), 1},
+		{false, iv(0, 255), 8},
+		{false, iv(0, 256), 9},
+		{false, emptyInterval, 1},
+		{true, iv(0, 0), 1},
+		{true, iv(-1, 0), 1},
+		{true, iv(-128, 127), 8},
+		{true, iv(-129, 127), 9},
+		{true, iv(0, 128), 9},
+	}
+	for _, tt := range tests {
+		p := &Parameters{Signed: tt.signed}
+		if got := p.rangeBits(tt.r); got != tt.want {
+			t.Errorf("%s %v: expected %d bit(s) but saw %d", p.intSignedness(), tt.r, tt.want, got)
+		}
+	}
+}
+
+// TestOverflowWarnings ensures that relations between constant expressions
+// that hold only modulo 2^IntBits are reported.
+func TestOverflowWarnings(t *testing.T) {
+	tests := []struct {
+		src   string
+		arith string
+		want  string
+	}{
+		{"w(X) :- X = 1, 3 + 3 = 2.\n", "exact", `test.pl:1:16: Warning: "3 + 3 = 2" will always fail`},
+		{"w(X) :- X = 1, 3 + 3 = 2.\n", "checked", `test.pl:1:16: Warning: "3 + 3 = 2" will always fail`},
+		{"w(X) :- X = 1, 3 + 3 = 2.\n", "modular", `test.pl:1:16: Warning: "3 + 3 = 2" can be satisfied only by arithmetic overflow`},
+		{"w(X) :- X = 1, 3 * 3 < 3.\n", "modular", `test.pl:1:16: Warning: "3 * 3 < 3" can be satisfied`},
+		{"w(X) :- X = 1, 3 + 3 = 1.\n", "modular", ""},
+		{"w(X) :- X = 1, 3 + 3 = 6.\n", "modular", ""},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p := testParams("w(X)")
+		p.Arith = tt.arith
+		p.Log = log.New(&buf, "", 0)
+		if _, err := typeCheck(p, tt.src); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.TrimSpace(buf.String())
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%q --arith=%s: expected a warning containing %q but saw %q", tt.src, tt.arith, tt.want, got)
+		}
+	}
+}
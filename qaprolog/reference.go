@@ -0,0 +1,984 @@
+// Solve a program classically by resolution with finite-domain labeling over
+// the same bounded domains that the generated Hamiltonian uses.
+
+package qaprolog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A refTerm is a Prolog term manipulated by the reference interpreter.  It is
+// one of refInt, refAtom, *refVar, *refStruct, *refCons, refNil,
+// refOverflow, or refInvalid.
+type refTerm interface{}
+
+// A refInt is an integer.
+type refInt int
+
+// A refAtom is a symbol.
+type refAtom string
+
+// A refVar is a logic variable, which may be bound to another term.
+type refVar struct {
+	Name    string  // Variable name, used only for debugging
+	Ty      VarType // Type of the variable, used for labeling
+	Bounded bool    // Whether the variable can hold only integers from Min to Max
+	Min     int     // Smallest integer the variable can hold
+	Max     int     // Largest integer the variable can hold
+	Ref     refTerm // Term to which the variable is bound or nil if unbound
+}
+
+// A refStruct is a structure with a functor and one or more arguments.
+type refStruct struct {
+	Functor string    // Functor name and arity (e.g., "point/2")
+	Args    []refTerm // Arguments to the functor
+}
+
+// A refCons is a non-empty list.
+type refCons struct {
+	Head refTerm // First element of the list
+	Tail refTerm // Remaining elements of the list
+}
+
+// A refNil is the empty list.
+type refNil struct{}
+
+// A refOverflow is the result of arithmetic that overflowed under
+// --arith=checked, that divided by zero, or that shifted by a negative
+// amount.  It unifies with nothing, not even itself.
+type refOverflow struct{}
+
+// A refInvalid stands for a value whose integer representation, as reported
+// by a solver, does not represent any value of its type.
+type refInvalid struct{}
+
+// A refEnv maps each of a clause's variable names to a variable.
+type refEnv map[string]*refVar
+
+// A refGoal is a goal (predicate) to prove within a given environment.
+type refGoal struct {
+	Node *ASTNode // Predicate to prove
+	Env  refEnv   // Bindings of the predicate's variables
+}
+
+// A refMachine is a reference interpreter for a type-checked program.
+type refMachine struct {
+	p        *Parameters           // Global parameters
+	clVarTys map[*ASTNode]TypeInfo // Variable types of each clause
+	trail    []*refVar             // Variables bound so far, for backtracking
+}
+
+// newRefMachine returns a reference interpreter for a type-checked program.
+func newRefMachine(p *Parameters, clVarTys map[*ASTNode]TypeInfo) *refMachine {
+	return &refMachine{
+		p:        p,
+		clVarTys: clVarTys,
+	}
+}
+
+// queryGoals returns an environment for a query and a list of the query's
+// goals.
+func (m *refMachine) queryGoals(q *ASTNode) (refEnv, []refGoal) {
+	env := m.newEnv(q)
+	goals := make([]refGoal, 0, len(q.Children)-1)
+	for _, c := range q.Children[1:] {
+		goals = append(goals, refGoal{Node: c, Env: env})
+	}
+	return env, goals
+}
+
+// deref follows a chain of variable bindings.
+func deref(t refTerm) refTerm {
+	for {
+		v, ok := t.(*refVar)
+		if !ok || v.Ref == nil {
+			return t
+		}
+		t = v.Ref
+	}
+}
+
+// bind binds an unbound variable to a term, recording the binding so it can
+// later be undone.
+func (m *refMachine) bind(v *refVar, t refTerm) {
+	v.Ref = t
+	m.trail = append(m.trail, v)
+}
+
+// undo unbinds all variables bound since the trail had a given length.
+func (m *refMachine) undo(mark int) {
+	for _, v := range m.trail[mark:] {
+		v.Ref = nil
+	}
+	m.trail = m.trail[:mark]
+}
+
+// bindVar binds an unbound variable to a term, returning false if the term
+// is an integer the variable cannot hold.  When binding two variables, the
+// one that can hold more values is bound to the other.
+func (m *refMachine) bindVar(v *refVar, t refTerm) bool {
+	switch t := t.(type) {
+	case refOverflow:
+		return false
+	case refInt:
+		if v.Bounded && (int(t) < v.Min || int(t) > v.Max) {
+			return false
+		}
+	case *refVar:
+		if v.Bounded && (!t.Bounded || t.Max-t.Min > v.Max-v.Min) {
+			m.bind(t, v)
+			return true
+		}
+	}
+	m.bind(v, t)
+	return true
+}
+
+// unify unifies two terms, returning true on success.  On failure, some
+// variables may remain bound; the caller is expected to undo them.
+func (m *refMachine) unify(t1, t2 refTerm) bool {
+	t1, t2 = deref(t1), deref(t2)
+	if v, ok := t1.(*refVar); ok {
+		if v2, ok := t2.(*refVar); ok && v == v2 {
+			return true
+		}
+		return m.bindVar(v, t2)
+	}
+	if v, ok := t2.(*refVar); ok {
+		return m.bindVar(v, t1)
+	}
+	switch t1 := t1.(type) {
+	case *refStruct:
+		t2, ok := t2.(*refStruct)
+		if !ok || t1.Functor != t2.Functor {
+			return false
+		}
+		for i, a := range t1.Args {
+			if !m.unify(a, t2.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case *refCons:
+		t2, ok := t2.(*refCons)
+		return ok && m.unify(t1.Head, t2.Head) && m.unify(t1.Tail, t2.Tail)
+	case refOverflow:
+		return false
+	default:
+		return t1 == t2
+	}
+}
+
+// unboundVars appends to a list all unbound variables that appear in a term.
+func unboundVars(vs []*refVar, t refTerm) []*refVar {
+	switch t := deref(t).(type) {
+	case *refVar:
+		for _, v := range vs {
+			if v == t {
+				return vs
+			}
+		}
+		return append(vs, t)
+	case *refStruct:
+		for _, a := range t.Args {
+			vs = unboundVars(vs, a)
+		}
+	case *refCons:
+		vs = unboundVars(vs, t.Head)
+		vs = unboundVars(vs, t.Tail)
+	}
+	return vs
+}
+
+// nodeVars returns all unbound variables that appear in an AST.
+func (g refGoal) nodeVars(a *ASTNode) []*refVar {
+	vs := make([]*refVar, 0, 4)
+	for _, v := range a.FindByType(VariableType) {
+		vs = unboundVars(vs, g.Env[v.Value.(string)])
+	}
+	return vs
+}
+
+// newEnv creates a fresh variable for each variable that appears in a clause.
+// Integer variables can hold only the values that fit in the number of bits
+// the generated code uses for them.
+func (m *refMachine) newEnv(cl *ASTNode) refEnv {
+	tys := m.clVarTys[cl]
+	env := make(refEnv)
+	for _, v := range cl.FindByType(VariableType) {
+		nm := v.Value.(string)
+		if _, seen := env[nm]; !seen {
+			rv := &refVar{Name: nm, Ty: tys[nm]}
+			if ty := tys[nm]; ty == InfNumeral {
+				r := m.p.storageRange(m.p.varBits(cl, nm, ty))
+				rv.Bounded, rv.Min, rv.Max = true, int(r.Lo), int(r.Hi)
+			}
+			env[nm] = rv
+		}
+	}
+	return env
+}
+
+// term converts an AST term to a refTerm.
+func (m *refMachine) term(a *ASTNode, env refEnv) refTerm {
+	switch a.Type {
+	case NumeralType:
+		return refInt(a.Value.(int))
+	case AtomType:
+		return refAtom(a.Value.(string))
+	case VariableType:
+		return env[a.Value.(string)]
+	case TermType, ListTailType, PrimaryExprType:
+		if isFunction(a) {
+			return m.eval(a, env)
+		}
+		return m.term(a.Children[0], env)
+	case StructureType:
+		args := make([]refTerm, len(a.Children)-1)
+		for i, c := range a.Children[1:] {
+			args[i] = m.term(c, env)
+		}
+		return &refStruct{Functor: a.functorName(), Args: args}
+	case ListType:
+		elts, tail := a.listParts()
+		var t refTerm = refNil{}
+		if tail != nil {
+			t = m.term(tail, env)
+		}
+		for i := len(elts) - 1; i >= 0; i-- {
+			t = &refCons{Head: m.term(elts[i], env), Tail: t}
+		}
+		return t
+	default:
+		return m.eval(a, env)
+	}
+}
+
+// eval evaluates an arithmetic expression, all of whose variables must be
+// bound.  As in the generated code, arithmetic wraps around at IntBits bits
+// under --arith=modular, yields refOverflow if any operation's value does not
+// fit in IntBits bits under --arith=checked, and is otherwise exact.  Division
+// by zero and shifting by a negative amount always yield refOverflow.
+func (m *refMachine) eval(a *ASTNode, env refEnv) refTerm {
+	switch a.Type {
+	case PrimaryExprType:
+		if !isFunction(a) {
+			return m.eval(a.Children[0], env)
+		}
+		vs := make([]int, len(a.Children)-1)
+		for i, c := range a.Children[1:] {
+			var ok bool
+			if vs[i], ok = m.evalInt(c, env); !ok {
+				return refOverflow{}
+			}
+		}
+		v := vs[0]
+		switch fn := a.Value.(string); fn {
+		case "abs":
+			if v < 0 {
+				v = -v
+			}
+		case "sign":
+			switch {
+			case v < 0:
+				v = -1
+			case v > 0:
+				v = 1
+			}
+		case "min":
+			if vs[1] < v {
+				v = vs[1]
+			}
+		case "max":
+			if vs[1] > v {
+				v = vs[1]
+			}
+		default:
+			fatalf("Internal error: Unexpected function %q", fn)
+		}
+		return m.result(v)
+	case UnaryExprType:
+		if len(a.Children) == 1 {
+			return m.eval(a.Children[0], env)
+		}
+		v, ok := m.evalInt(a.Children[1], env)
+		if !ok {
+			return refOverflow{}
+		}
+		if a.Children[0].Value.(string) == "\\" {
+			return m.result(^v)
+		}
+		return m.result(-v)
+	case AdditiveExprType, MultiplicativeExprType:
+		if len(a.Children) == 1 {
+			return m.eval(a.Children[0], env)
+		}
+		v1, ok1 := m.evalInt(a.Children[0], env)
+		v2, ok2 := m.evalInt(a.Children[2], env)
+		if !ok1 || !ok2 {
+			return refOverflow{}
+		}
+		switch op := a.Children[1].Value.(string); op {
+		case "+":
+			return m.result(v1 + v2)
+		case "-":
+			return m.result(v1 - v2)
+		case "*":
+			return m.result(v1 * v2)
+		case "/\\":
+			return m.result(v1 & v2)
+		case "\\/":
+			return m.result(v1 | v2)
+		case "xor":
+			return m.result(v1 ^ v2)
+		case "//", "/", "rem", "mod", "<<", ">>":
+			return m.divide(op, v1, v2)
+		default:
+			fatalf("Internal error: Unexpected operator %q", op)
+		}
+	default:
+		return deref(m.term(a, env))
+	}
+	return nil // We should never get here.
+}
+
+// divide divides one integer by another or shifts one integer by another.
+// Go's division, like Prolog's "//", truncates toward zero, and Go's
+// remainder, like Prolog's "rem", takes the sign of the dividend.  Prolog's
+// "mod" instead takes the sign of the divisor.  Go's ">>", like Prolog's,
+// shifts in copies of the sign bit.
+func (m *refMachine) divide(op string, v1, v2 int) refTerm {
+	switch {
+	case op == "<<" && v2 >= 0:
+		return m.result(v1 << uint(v2))
+	case op == ">>" && v2 >= 0:
+		return m.result(v1 >> uint(v2))
+	case v2 == 0, op == "<<", op == ">>":
+		return refOverflow{}
+	}
+	switch op {
+	case "rem":
+		return m.result(v1 % v2)
+	case "mod":
+		r := v1 % v2
+		if r != 0 && (r < 0) != (v2 < 0) {
+			r += v2
+		}
+		return m.result(r)
+	default:
+		return m.result(v1 / v2)
+	}
+}
+
+// result converts the value of an arithmetic operation to a term according to
+// the semantics of integer arithmetic.
+func (m *refMachine) result(v int) refTerm {
+	p := m.p
+	switch p.Arith {
+	case "modular":
+		if p.Signed {
+			return refInt(signExtend(v, p.IntBits))
+		}
+		return refInt(v & (1<<p.IntBits - 1))
+	case "checked":
+		if top := p.storageRange(p.IntBits); int64(v) < top.Lo || int64(v) > top.Hi {
+			return refOverflow{}
+		}
+	}
+	return refInt(v)
+}
+
+// evalInt evaluates an arithmetic expression to an integer.  It returns false
+// if the arithmetic overflowed.
+func (m *refMachine) evalInt(a *ASTNode, env refEnv) (int, bool) {
+	switch v := deref(m.eval(a, env)).(type) {
+	case refInt:
+		return int(v), true
+	case refAtom:
+		return m.p.SymToInt[string(v)], true
+	case refOverflow:
+		return 0, false
+	default:
+		fatalf("Internal error: Failed to evaluate %s as an integer", a.Text)
+	}
+	return 0, false // We should never get here.
+}
+
+// domain returns all values of a given type.
+func (m *refMachine) domain(ty VarType) []refTerm {
+	p := m.p
+	switch {
+	case ty == InfAtom:
+		dom := make([]refTerm, len(p.IntToSym))
+		for i, s := range p.IntToSym {
+			dom[i] = refAtom(s)
+		}
+		return dom
+
+	case ty.IsList():
+		// Construct all lists of up to MaxListLen elements.
+		eDom := m.domain(ty.ElemType())
+		dom := []refTerm{refNil{}}
+		prev := dom
+		for n := uint(0); n < p.MaxListLen; n++ {
+			next := make([]refTerm, 0, len(prev)*len(eDom))
+			for _, t := range prev {
+				for _, e := range eDom {
+					next = append(next, &refCons{Head: e, Tail: t})
+				}
+			}
+			dom = append(dom, next...)
+			prev = next
+		}
+		return dom
+
+	case ty == InfStructure:
+		// Construct all structures of all functors.
+		dom := make([]refTerm, 0)
+		for _, f := range p.IntToFunctor {
+			args := [][]refTerm{{}}
+			for _, aTy := range p.FunctorTypes[f] {
+				next := make([][]refTerm, 0)
+				for _, as := range args {
+					for _, e := range m.domain(aTy) {
+						next = append(next, append(append([]refTerm{}, as...), e))
+					}
+				}
+				args = next
+			}
+			for _, as := range args {
+				dom = append(dom, &refStruct{Functor: f, Args: as})
+			}
+		}
+		return dom
+
+	default:
+		top := p.storageRange(p.IntBits)
+		return intDomain(int(top.Lo), int(top.Hi))
+	}
+}
+
+// intDomain returns all integers from min to max.
+func intDomain(min, max int) []refTerm {
+	dom := make([]refTerm, max-min+1)
+	for i := range dom {
+		dom[i] = refInt(min + i)
+	}
+	return dom
+}
+
+// label binds each variable in a list in turn to every value in its domain
+// and invokes a continuation on each complete assignment.  Like the other
+// solving methods, it returns true if the continuation asked to stop.
+func (m *refMachine) label(vs []*refVar, k func() bool) bool {
+	if len(vs) == 0 {
+		return k()
+	}
+	v := vs[0]
+	if deref(v) != refTerm(v) {
+		return m.label(vs[1:], k)
+	}
+	dom := m.domain(v.Ty)
+	if v.Bounded {
+		dom = intDomain(v.Min, v.Max)
+	}
+	for _, t := range dom {
+		mark := len(m.trail)
+		m.bind(v, t)
+		stop := m.label(vs[1:], k)
+		m.undo(mark)
+		if stop {
+			return true
+		}
+	}
+	return false
+}
+
+// relationVars returns the unbound variables that prevent a relation from
+// being decided.  The only relation that can bind variables is equality with
+// a term or lone variable on one side.
+func (m *refMachine) relationVars(g refGoal) []*refVar {
+	a := g.Node
+	e1, e2 := a.Children[0], a.Children[2]
+	vs1, vs2 := g.nodeVars(e1), g.nodeVars(e2)
+	if a.Value.(string) == "=" {
+		switch {
+		case e1.Type == TermType && e2.Type == TermType:
+			return nil // Unify two terms.
+		case len(vs2) == 0 && isLoneVariable(e1):
+			return nil // Bind the variable on the left.
+		case len(vs1) == 0 && isLoneVariable(e2):
+			return nil // Bind the variable on the right.
+		}
+	}
+	for _, v := range vs2 {
+		vs1 = unboundVars(vs1, v)
+	}
+	return vs1
+}
+
+// isLoneVariable reports whether an expression is nothing but a variable.
+func isLoneVariable(a *ASTNode) bool {
+	return loneVariable(a) != nil
+}
+
+// blockingVars returns the unbound variables that must be labeled before a
+// goal can be decided.  Predicate calls never block.
+func (m *refMachine) blockingVars(g refGoal) []*refVar {
+	a := g.Node
+	if a.Type == PredicateType && len(a.Children) == 1 {
+		a = a.Children[0]
+	}
+	switch a.Type {
+	case RelationType:
+		return m.relationVars(refGoal{Node: a, Env: g.Env})
+	case NegationType:
+		return g.nodeVars(a)
+	case DisjunctionType:
+		vs := make([]*refVar, 0, 4)
+		for _, br := range a.Children {
+			if br.Type == IfThenType {
+				for _, v := range g.nodeVars(br.Children[0]) {
+					vs = unboundVars(vs, v)
+				}
+			}
+		}
+		return vs
+	default:
+		return nil
+	}
+}
+
+// solve proves a list of goals, invoking a continuation on each solution.  It
+// returns true if the continuation asked to stop.  Goals that cannot be
+// decided without labeling variables are postponed in favor of goals that
+// can, as the order of goals does not affect the generated Hamiltonian.
+func (m *refMachine) solve(goals []refGoal, k func() bool) bool {
+	if len(goals) == 0 {
+		return k()
+	}
+	for i, g := range goals {
+		if len(m.blockingVars(g)) > 0 {
+			continue
+		}
+		rest := make([]refGoal, 0, len(goals)-1)
+		rest = append(append(rest, goals[:i]...), goals[i+1:]...)
+		return m.prove(g, rest, k)
+	}
+
+	// Every goal is blocked.  Label the first goal's variables.
+	return m.label(m.blockingVars(goals[0]), func() bool {
+		return m.prove(goals[0], goals[1:], k)
+	})
+}
+
+// solveFirst proves a list of goals, invoking a continuation on only the first
+// solution.  It returns whether a solution was found and whether the
+// continuation asked to stop.
+func (m *refMachine) solveFirst(goals []refGoal, k func() bool) (found, stop bool) {
+	m.solve(goals, func() bool {
+		found = true
+		stop = k()
+		return true
+	})
+	return
+}
+
+// prove proves a single goal followed by a list of remaining goals.
+func (m *refMachine) prove(g refGoal, rest []refGoal, k func() bool) bool {
+	a := g.Node
+	switch a.Type {
+	case PredicateType:
+		// Handle predicate AST nodes that are really just wrappers.
+		if len(a.Children) == 1 {
+			c := a.Children[0]
+			if c.Type == AtomType {
+				// Built-in predicates with no arguments
+				switch c.Value.(string) {
+				case "true":
+					return m.solve(rest, k)
+				case "fail", "false":
+					return false
+				}
+				fatalf("Internal error: Unexpected predicate %s/0", c.Value)
+			}
+			return m.prove(refGoal{Node: c, Env: g.Env}, rest, k)
+		}
+
+		// Ignore atom/1 and integer/1, which exist solely for the type
+		// system.
+		if len(a.Children) == 2 {
+			pName := a.Children[0].Value.(string)
+			if pName == "atom" || pName == "integer" {
+				return m.solve(rest, k)
+			}
+		}
+		return m.call(g, rest, k)
+
+	case NegationType:
+		found, _ := m.solveFirst([]refGoal{{Node: a.Children[0], Env: g.Env}}, func() bool { return false })
+		if found {
+			return false
+		}
+		return m.solve(rest, k)
+
+	case DisjunctionType:
+		return m.disjunction(g, rest, k)
+
+	case RelationType:
+		return m.relation(g, rest, k)
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return false // We should never get here.
+}
+
+// call proves a goal by resolving it against each clause in a clause group.
+func (m *refMachine) call(g refGoal, rest []refGoal, k func() bool) bool {
+	// The lowest level of a recursive clause group always fails.
+	nm := g.Node.calleeName(m.p)
+	if _, lvl, ok := splitLevel(nm); ok && lvl == 0 {
+		return false
+	}
+	cls, ok := m.p.TopLevel[nm]
+	if !ok {
+		fatalf("Internal error: Failed to find clause %s", nm)
+	}
+	args := g.Node.Children[1:]
+	for _, cl := range cls {
+		// Unify the goal's arguments with the clause's head.
+		mark := len(m.trail)
+		env := m.newEnv(cl)
+		ok := true
+		for i, h := range cl.Children[0].Children[1:] {
+			if !m.unify(m.term(args[i], g.Env), m.term(h, env)) {
+				ok = false
+				break
+			}
+		}
+
+		// Prove the clause's body followed by the remaining goals.
+		if ok {
+			goals := make([]refGoal, 0, len(cl.Children)-1+len(rest))
+			for _, b := range cl.Children[1:] {
+				goals = append(goals, refGoal{Node: b, Env: env})
+			}
+			if m.solve(append(goals, rest...), k) {
+				m.undo(mark)
+				return true
+			}
+		}
+		m.undo(mark)
+	}
+	return false
+}
+
+// disjunction proves each branch of a disjunction in turn.  An if-then branch
+// commits to the first solution of its condition, and no later branch is
+// tried if the condition succeeds.
+func (m *refMachine) disjunction(g refGoal, rest []refGoal, k func() bool) bool {
+	goals := func(ps *ASTNode, rest []refGoal) []refGoal {
+		gs := make([]refGoal, 0, len(ps.Children)+len(rest))
+		for _, c := range ps.Children {
+			gs = append(gs, refGoal{Node: c, Env: g.Env})
+		}
+		return append(gs, rest...)
+	}
+	for _, br := range g.Node.Children {
+		if br.Type != IfThenType {
+			if m.solve(goals(br, rest), k) {
+				return true
+			}
+			continue
+		}
+		found, stop := m.solveFirst(goals(br.Children[0], nil), func() bool {
+			return m.solve(goals(br.Children[1], rest), k)
+		})
+		if found {
+			return stop
+		}
+	}
+	return false
+}
+
+// relation proves a relation.  Unless the relation is an equality that binds
+// a variable, all of the relation's variables are labeled first.
+func (m *refMachine) relation(g refGoal, rest []refGoal, k func() bool) bool {
+	a := g.Node
+	op := a.Value.(string)
+	e1, e2 := a.Children[0], a.Children[2]
+	mark := len(m.trail)
+	var ok bool
+	switch {
+	case op == "=":
+		ok = m.unify(m.term(e1, g.Env), m.term(e2, g.Env))
+	case op == "\\=" && e1.Type == TermType && e2.Type == TermType:
+		ok = !m.unify(m.term(e1, g.Env), m.term(e2, g.Env))
+		m.undo(mark)
+	default:
+		v1, ok1 := m.evalInt(e1, g.Env)
+		v2, ok2 := m.evalInt(e2, g.Env)
+		ok = ok1 && ok2 && intRelation(op, v1, v2)
+	}
+	stop := ok && m.solve(rest, k)
+	m.undo(mark)
+	return stop
+}
+
+// intRelation reports whether a relation holds between two integers.
+func intRelation(op string, v1, v2 int) bool {
+	switch op {
+	case "=", "is":
+		return v1 == v2
+	case "\\=":
+		return v1 != v2
+	case "<":
+		return v1 < v2
+	case ">":
+		return v1 > v2
+	case "=<":
+		return v1 <= v2
+	case ">=":
+		return v1 >= v2
+	default:
+		fatalf("Internal error: Unexpected relation %q", op)
+	}
+	return false // We should never get here.
+}
+
+// encodeValue converts a ground term to the integer representation used by
+// the generated code.  It returns false if the term cannot be represented.
+func (p *Parameters) encodeValue(ty VarType, t refTerm) (int, bool) {
+	switch t := deref(t).(type) {
+	case refInt:
+		return int(t), true
+
+	case refAtom:
+		v, ok := p.SymToInt[string(t)]
+		return v, ok
+
+	case refNil, *refCons:
+		eTy := ty.ElemType()
+		eBits := p.typeBits(eTy)
+		val, n := 0, uint(0)
+		for ; ; n++ {
+			c, ok := deref(t).(*refCons)
+			if !ok {
+				break
+			}
+			if n == p.MaxListLen {
+				return 0, false
+			}
+			e, ok := p.encodeValue(eTy, c.Head)
+			if !ok {
+				return 0, false
+			}
+			val |= (e & (1<<eBits - 1)) << (n * eBits)
+			t = c.Tail
+		}
+		return val | int(n)<<(p.MaxListLen*eBits), true
+
+	case *refStruct:
+		fBits := p.fieldBits()
+		val := p.FunctorToInt[t.Functor] << (p.MaxArity * fBits)
+		for i, a := range t.Args {
+			aTy := p.FunctorTypes[t.Functor][i]
+			e, ok := p.encodeValue(aTy, a)
+			if !ok {
+				return 0, false
+			}
+			val |= (e & (1<<p.typeBits(aTy) - 1)) << (uint(i) * fBits)
+		}
+		return val, true
+
+	default:
+		return 0, false
+	}
+}
+
+// decodeTerm converts an integer representation of a value of a given type to
+// a ground term.  Each part of the value that does not represent a value of
+// its type (e.g., an out-of-range symbol number) is decoded as refInvalid.
+func (p *Parameters) decodeTerm(ty VarType, val int) refTerm {
+	switch {
+	case ty == InfAtom:
+		// Symbolic value
+		if val < 0 || val >= len(p.IntToSym) {
+			return refInvalid{}
+		}
+		return refAtom(p.IntToSym[val])
+
+	case ty.IsList():
+		// List value
+		eTy := ty.ElemType()
+		eBits := p.typeBits(eTy)
+		n := val >> (p.MaxListLen * eBits)
+		if n > int(p.MaxListLen) {
+			return refInvalid{}
+		}
+		var t refTerm = refNil{}
+		for i := n - 1; i >= 0; i-- {
+			e := p.decodeTerm(eTy, p.fieldValue(eTy, val>>(uint(i)*eBits), eBits))
+			t = &refCons{Head: e, Tail: t}
+		}
+		return t
+
+	case ty == InfStructure:
+		// Structure value
+		fBits := p.fieldBits()
+		tag := val >> (p.MaxArity * fBits)
+		if tag >= len(p.IntToFunctor) {
+			return refInvalid{}
+		}
+		f := p.IntToFunctor[tag]
+		tys := p.FunctorTypes[f]
+		args := make([]refTerm, len(tys))
+		for i, aTy := range tys {
+			args[i] = p.decodeTerm(aTy, p.fieldValue(aTy, val>>(uint(i)*fBits), p.typeBits(aTy)))
+		}
+		return &refStruct{Functor: f, Args: args}
+
+	default:
+		// Numeric value
+		return refInt(val)
+	}
+}
+
+// decodeValue converts an integer representation of a value of a given type
+// to a ground term.  It returns false if the integer is not the canonical
+// representation of any value.
+func (p *Parameters) decodeValue(ty VarType, val int) (refTerm, bool) {
+	// Reject invalid and non-canonical representations (e.g., with
+	// garbage in unused list elements).
+	t := p.decodeTerm(ty, val)
+	if enc, ok := p.encodeValue(ty, t); !ok || enc != val {
+		return nil, false
+	}
+	return t, true
+}
+
+// verifySolution reports whether a solution is consistent with the program.
+// If the query contains variables, the solution's values are substituted
+// into the query, which must then succeed.  Otherwise, the query must succeed
+// if and only if the solution claims it does.
+func (a *ASTNode) verifySolution(p *Parameters, clVarTys map[*ASTNode]TypeInfo, sol Solution) bool {
+	m := newRefMachine(p, clVarTys)
+	q := a.FindByType(QueryType)[0]
+	tys := clVarTys[q]
+	env, goals := m.queryGoals(q)
+	if !hasVariable(tys) {
+		found, _ := m.solveFirst(goals, func() bool { return false })
+		return found == (sol.Values["Valid"] == 1)
+	}
+	if v, ok := sol.Values["Valid"]; ok && v != 1 {
+		return false
+	}
+	for nm, val := range sol.Values {
+		v, ok := env[nm]
+		if !ok || tys[nm] == InfUnknown {
+			continue
+		}
+		t, ok := p.decodeValue(tys[nm], val)
+		if !ok {
+			return false
+		}
+		m.bind(v, t)
+	}
+	found, _ := m.solveFirst(goals, func() bool { return false })
+	return found
+}
+
+// verifySolutions discards all solutions that fail verification and reports
+// the number of samples that were rejected.
+func (a *ASTNode) verifySolutions(p *Parameters, clVarTys map[*ASTNode]TypeInfo, sols []Solution) []Solution {
+	good := make([]Solution, 0, len(sols))
+	nBad, nBadSols := 0, 0
+	verified := true
+	for _, sol := range sols {
+		if a.verifySolution(p, clVarTys, sol) {
+			sol.Verified = &verified
+			good = append(good, sol)
+		} else {
+			nBad += sol.Tally
+			nBadSols++
+		}
+	}
+	if nBadSols > 0 && p.Log != nil {
+		p.Log.Printf("Rejected %d sample(s) representing %d solution(s) that do not satisfy the query", nBad, nBadSols)
+	} else {
+		verbosePrintf(p, "Verified all %d solution(s)", len(sols))
+	}
+	return good
+}
+
+// A referenceSolver solves a program classically by resolution.  Unlike
+// Prolog's SLD resolution, which proves goals strictly from left to right, it
+// postpones goals that cannot be decided until their variables are bound and
+// labels those variables over their finite domains, as a constraint-logic
+// programming system would.  Solutions therefore do not depend on the order
+// of goals, just as they do not in the generated Hamiltonian.  The solver
+// works directly on the AST and therefore does not require that the program
+// be compiled.
+type referenceSolver struct{}
+
+// Solve solves the query by resolution with labeling and returns all of its
+// solutions.  Variables that the program leaves unbound are labeled with
+// every value in their domain, and duplicate solutions are reported only
+// once.
+func (referenceSolver) Solve(prob *Problem) (sols []Solution, err error) {
+	prog := prob.Program
+	p, clVarTys := prog.Params, prog.clVarTys
+	defer recoverError(p, &err)
+	prog.needSource("--solver=reference")
+
+	// Prepare the query for evaluation.
+	m := newRefMachine(p, clVarTys)
+	q := prog.AST.FindByType(QueryType)[0]
+	tys := clVarTys[q]
+	haveVar := hasVariable(tys)
+	env, goals := m.queryGoals(q)
+	names := make([]string, 0, len(env))
+	for nm := range env {
+		if tys[nm] != InfUnknown && !strings.HasPrefix(nm, "_") {
+			names = append(names, nm)
+		}
+	}
+	sort.Strings(names)
+
+	// Find all solutions, labeling any variables left unbound.
+	seen := make(map[string]Empty)
+	sols = make([]Solution, 0)
+	m.solve(goals, func() bool {
+		vs := make([]*refVar, 0, len(names))
+		for _, nm := range names {
+			vs = unboundVars(vs, env[nm])
+		}
+		return m.label(vs, func() bool {
+			vals := make(map[string]int, len(names)+1)
+			for _, nm := range names {
+				val, ok := p.encodeValue(tys[nm], env[nm])
+				if !ok {
+					return false
+				}
+				vals[nm] = val
+			}
+			vals["Valid"] = 1
+			key := fmt.Sprint(vals)
+			if _, dup := seen[key]; !dup {
+				seen[key] = Empty{}
+				sols = append(sols, Solution{Values: vals, Tally: 1})
+			}
+			return !haveVar
+		})
+	})
+	verbosePrintf(p, "Found %d solution(s) by resolution with labeling", len(sols))
+
+	// If the query contains no variables, report whether it succeeded.
+	if !haveVar && len(sols) == 0 {
+		sols = append(sols, Solution{Values: map[string]int{"Valid": 0}, Tally: 1})
+	}
+	return sols, nil
+}
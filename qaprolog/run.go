@@ -0,0 +1,334 @@
+// Execute an external command
+
+package qaprolog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// createWorkDir creates a directory to hold byproducts of Prolog compilation
+// and returns its name.  If the parameter list names a directory, that one is
+// used.  Otherwise, a temporary directory is created and used, and temp is
+// true.  The parameter list itself is not modified.
+func createWorkDir(p *Parameters) (dir string, temp bool) {
+	// Before we return, output the name we chose.
+	if p.Verbose {
+		defer func() {
+			abs, err := filepath.Abs(dir)
+			checkError(err)
+			verbosePrintf(p, "Storing intermediate files in %s", abs)
+		}()
+	}
+
+	// If the user specified a directory, create it if necessary and return.
+	if p.WorkDir != "" {
+		err := os.MkdirAll(p.WorkDir, 0777)
+		checkError(err)
+		return p.WorkDir, false
+	}
+
+	// If the user did not specify a directory, create a random one.
+	nm, err := ioutil.TempDir("", "qap-")
+	checkError(err)
+	return nm, true
+}
+
+// createYosysScript creates a synthesis script for Yosys.
+func createYosysScript(p *Parameters) {
+	// Create a .ys file.
+	yName := p.OutFileBase + ".ys"
+	verbosePrintf(p, "Writing a Yosys synthesis script to %s", yName)
+	ys, err := os.Create(filepath.Join(p.WorkDir, yName))
+	checkError(err)
+	defer ys.Close()
+
+	// Write some boilerplate text to it.
+	fmt.Fprintln(ys, "### Design synthesis")
+	fmt.Fprintf(ys, "### Usage: yosys -s %s.ys -b edif -o %s.edif %s.v\n",
+		p.OutFileBase, p.OutFileBase, p.OutFileBase)
+	_, err = fmt.Fprint(ys, `
+# Check design hierarchy.
+hierarchy -top Query
+
+# Translate processes.
+proc; opt
+
+# Detect and optimize FSM encodings.
+fsm; opt
+
+# Convert to gate logic.
+techmap; opt
+
+# Recast in terms of more gate types.
+abc -g AND,NAND,OR,NOR,XOR,XNOR,MUX,AOI3,OAI3,AOI4,OAI4; opt
+
+# Clean up.
+clean
+`)
+	checkError(err)
+	checkError(ys.Close())
+}
+
+// runCommand executes a given command, aborting on error.
+func runCommand(p *Parameters, name string, arg ...string) {
+	cmd := exec.Command(name, arg...)
+	cmd.Dir = p.WorkDir
+	cmd.Stderr = os.Stderr
+	verbosePrintf(p, "Executing %s %s", name, strings.Join(arg, " "))
+	err := cmd.Run()
+	checkError(err)
+}
+
+// fieldValue extracts a w-bit list element or structure argument of a given
+// type from the low-order bits of a value, sign-extending it if it is a signed
+// integer.
+func (p *Parameters) fieldValue(ty VarType, val int, w uint) int {
+	val &= 1<<w - 1
+	if p.Signed && ty == InfNumeral {
+		val = signExtend(val, w)
+	}
+	return val
+}
+
+// formatValue converts an integer representation of a value of a given type
+// to a string.
+func (p *Parameters) formatValue(ty VarType, val int) string {
+	return formatTerm(p.decodeTerm(ty, val))
+}
+
+// formatTerm converts a decoded term to a string in Prolog syntax.
+func formatTerm(t refTerm) string {
+	switch t := t.(type) {
+	case refInt:
+		return strconv.Itoa(int(t))
+
+	case refAtom:
+		return string(t)
+
+	case refNil, *refCons:
+		elts := make([]string, 0, 8)
+		for c, ok := t.(*refCons); ok; c, ok = c.Tail.(*refCons) {
+			elts = append(elts, formatTerm(c.Head))
+		}
+		return "[" + strings.Join(elts, ", ") + "]"
+
+	case *refStruct:
+		args := make([]string, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = formatTerm(a)
+		}
+		return t.Functor[:strings.LastIndex(t.Functor, "/")] + "(" + strings.Join(args, ", ") + ")"
+
+	default:
+		return "[invalid]"
+	}
+}
+
+// A Solution is a single assignment of values to query variables, as reported
+// by a solver.
+type Solution struct {
+	Values   map[string]int // Value of each query variable and of "Valid"
+	Energy   float64        // Energy of the solution
+	Tally    int            // Number of times the solution was observed
+	Verified *bool          // Whether the solution passed verification (nil if not verified)
+}
+
+// qmasmTally extracts the energy and tally from a QMASM solution header.
+var qmasmTally = regexp.MustCompile(`energy = ([-+.\deE]+), tally = (\d+)`)
+
+// parseQMASMOutputLine is a helper function for parseQMASMOutput that parses a
+// single line of QMASM output and adds it to a list of solutions.  Variables
+// named in signed are decoded as two's-complement integers.
+func parseQMASMOutputLine(sols []Solution, ln string, signed map[string]bool) []Solution {
+	// Start a new solution on each solution header.
+	if len(ln) > 10 && ln[:10] == "Solution #" {
+		sol := Solution{Values: make(map[string]int), Tally: 1}
+		if m := qmasmTally.FindStringSubmatch(ln); m != nil {
+			sol.Energy, _ = strconv.ParseFloat(m[1], 64)
+			sol.Tally, _ = strconv.Atoi(m[2])
+		}
+		return append(sols, sol)
+	}
+
+	// Extract a query variable and decimal value if both are
+	// present.  The binary value that precedes the decimal value
+	// indicates the variable's width.
+	fields := strings.Fields(ln)
+	if len(fields) != 3 || len(sols) == 0 {
+		return sols
+	}
+	if len(fields[0]) < 7 || fields[0][:6] != "Query." {
+		return sols
+	}
+	nm := fields[0][6:]
+	val, err := strconv.Atoi(fields[2])
+	checkError(err)
+	if signed[nm] {
+		val = signExtend(val, uint(len(fields[1])))
+	}
+	sols[len(sols)-1].Values[nm] = val
+	return sols
+}
+
+// writeValue outputs the value of a single query variable or, if the query
+// contains no variables, whether the query succeeded.
+func (p *Parameters) writeValue(w io.Writer, haveVar bool, tys TypeInfo, nm string, val int) {
+	switch {
+	case nm == "Valid":
+		switch {
+		case haveVar:
+		case val == 0:
+			fmt.Fprintln(w, "false")
+		case val == 1:
+			fmt.Fprintln(w, "true")
+		}
+
+	case tys[nm] != InfUnknown:
+		// Output numeric, symbolic, list, and structure values.
+		fmt.Fprintf(w, "%s = %s\n", nm, p.formatValue(tys[nm], val))
+
+	default:
+		// Ignore non-variables.
+	}
+}
+
+// writeText writes a list of solutions in a user-friendly format.
+func (p *Parameters) writeText(w io.Writer, tys TypeInfo, sols []Solution) {
+	// Output each solution's values in order of variable name.
+	haveVar := hasVariable(tys)
+	for i, sol := range sols {
+		if i > 0 {
+			fmt.Fprintln(w, "")
+		}
+		names := make([]string, 0, len(sol.Values))
+		for nm := range sol.Values {
+			names = append(names, nm)
+		}
+		sort.Strings(names)
+		for _, nm := range names {
+			p.writeValue(w, haveVar, tys, nm, sol.Values[nm])
+		}
+	}
+}
+
+// parseQMASMOutput is a helper function for qmasmSolver that parses all of the
+// solutions QMASM reported.
+func parseQMASMOutput(fn string, signed map[string]bool) []Solution {
+	// Open the QMASM output file.
+	r, err := os.Open(fn)
+	checkError(err)
+	rb := bufio.NewReader(r)
+
+	// Parse lines until we reach the end of the file.
+	sols := make([]Solution, 0)
+	for {
+		// Read a line.
+		ln, err := rb.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		checkError(err)
+		sols = parseQMASMOutputLine(sols, ln, signed)
+	}
+	err = r.Close()
+	checkError(err)
+	return sols
+}
+
+// lastLine is a helper function for qmasmSolver that returns the last
+// non-blank line of a file.
+func lastLine(fn string) (string, error) {
+	r, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	rb := bufio.NewReader(r)
+	last := ""
+	for {
+		ln, err := rb.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(ln) != "" {
+			last = strings.TrimSpace(ln)
+		}
+	}
+	return last, r.Close()
+}
+
+// hasVariable reports whether a query contains at least one variable.  If so,
+// we're trying to find valid values for all variables.  Otherwise, we're
+// trying to determine if the arguments represent a true statement.
+func hasVariable(tys TypeInfo) bool {
+	for nm := range tys {
+		if unicode.IsUpper(rune(nm[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+// A qmasmSolver solves a program by running qmasm on its QMASM code.
+type qmasmSolver struct{}
+
+// Solve runs qmasm and returns the solutions it reports.
+func (qmasmSolver) Solve(prob *Problem) (sols []Solution, err error) {
+	p := prob.Program.Params
+	defer recoverError(p, &err)
+	if prob.QMASMFile == "" {
+		fatal("--solver=qmasm requires a compiled program")
+	}
+
+	// Write verbose output to a file in case the user wants to look at it
+	// later.
+	oName := filepath.Join(p.WorkDir, p.OutFileBase+".out")
+	out, err := os.Create(oName)
+	checkError(err)
+
+	// Construct a QMASM argument list.
+	args := make([]string, 0, 4+len(p.QmasmArgs))
+	args = append(args, "--run", "--values=ints") // Mandatory arguments
+	args = append(args, p.QmasmArgs...)           // Additional, user-specified arguments
+	pNames := make([]string, 0, len(prob.Pins))
+	for nm := range prob.Pins {
+		pNames = append(pNames, nm)
+	}
+	sort.Strings(pNames)
+	for _, nm := range pNames {
+		args = append(args, fmt.Sprintf("--pin=%s := %v", nm, prob.Pins[nm]))
+	}
+	args = append(args, prob.QMASMFile)
+
+	// Execute QMASM.
+	cmd := exec.Command("qmasm", args...)
+	cmd.Dir = p.WorkDir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	verbosePrintf(p, "Executing qmasm %s", strings.Join(args, " "))
+	err = cmd.Run()
+	out.Close()
+	if err != nil {
+		// Include the last line of the .out file in the error message.
+		if last, _ := lastLine(oName); last != "" {
+			fatalf("%s (%s)", err, last)
+		}
+		checkError(err)
+	}
+
+	// Parse QMASM's output in terms of the query variables.
+	return parseQMASMOutput(oName, prob.signedVars()), nil
+}
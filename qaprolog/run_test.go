@@ -0,0 +1,44 @@
+// Test the decoding of values reported by the solver.
+
+package qaprolog
+
+import "testing"
+
+// TestFormatValue tests the conversion of integers to values of each type.
+func TestFormatValue(t *testing.T) {
+	p := &Parameters{
+		IntBits:      3,
+		SymBits:      2,
+		MaxListLen:   3,
+		ListLenBits:  2,
+		IntToSym:     []string{"a", "b", "c"},
+		IntToFunctor: []string{"f/2", "g/1"},
+		MaxArity:     2,
+		FunctorTypes: map[string]ArgTypes{
+			"f/2": {InfAtom, InfNumeral},
+			"g/1": {InfNumeral},
+		},
+	}
+	tests := []struct {
+		ty   VarType
+		val  int
+		want string
+	}{
+		{InfNumeral, 5, "5"},
+		{InfAtom, 2, "c"},
+		{InfAtom, 3, "[invalid]"},
+		{InfNumeralList, 0, "[]"},
+		{InfNumeralList, 3<<9 | 3<<6 | 2<<3 | 1, "[1, 2, 3]"},
+		{InfNumeralList, 4 << 9, "[invalid]"},
+		{InfAtomList, 2<<6 | 0<<2 | 1, "[b, a]"},
+		{InfAtomList, 1<<6 | 3, "[[invalid]]"},
+		{InfStructure, 0<<6 | 7<<3 | 1, "f(b, 7)"},
+		{InfStructure, 1<<6 | 4, "g(4)"},
+		{InfStructure, 2 << 6, "[invalid]"},
+	}
+	for _, tt := range tests {
+		if got := p.formatValue(tt.ty, tt.val); got != tt.want {
+			t.Errorf("%s %d: expected %q but saw %q", tt.ty, tt.val, tt.want, got)
+		}
+	}
+}
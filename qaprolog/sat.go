@@ -0,0 +1,482 @@
+// Solve a CNF formula with a conflict-driven clause-learning SAT solver.
+
+package qaprolog
+
+// A satLit is a literal as represented internally by the SAT solver:
+// twice a 0-based variable number, plus 1 if the variable is complemented.
+type satLit int
+
+// satLitOf converts a DIMACS literal to a satLit.
+func satLitOf(l int) satLit {
+	if l < 0 {
+		return satLit(2*(-l-1) + 1)
+	}
+	return satLit(2 * (l - 1))
+}
+
+// v returns a literal's variable.
+func (l satLit) v() int {
+	return int(l >> 1)
+}
+
+// not returns a literal's complement.
+func (l satLit) not() satLit {
+	return l ^ 1
+}
+
+// A cdcl is a conflict-driven clause-learning SAT solver with two watched
+// literals per clause, VSIDS branching, phase saving, and geometric restarts.
+type cdcl struct {
+	clauses  [][]satLit // Original and learned clauses
+	watches  [][]int    // Clauses watching each literal's complement
+	assigns  []int8     // Value of each variable (1, -1, or 0 if unassigned)
+	level    []int      // Decision level at which each variable was assigned
+	reason   []int      // Clause that implied each variable or -1 if none
+	phase    []bool     // Most recent value of each variable
+	activity []float64  // Branching priority of each variable
+	varInc   float64    // Amount by which to bump a variable's activity
+	heap     []int      // Variables ordered by decreasing activity
+	heapPos  []int      // Position of each variable in heap or -1 if absent
+	seen     []bool     // Variables visited during conflict analysis
+	trail    []satLit   // Literals assigned so far, in order
+	trailLim []int      // Length of trail at the start of each decision level
+	qhead    int        // Position in trail of the next literal to propagate
+	ok       bool       // False if the formula is known to be unsatisfiable
+	Conflict int        // Number of conflicts encountered so far
+}
+
+// newCDCL prepares a formula for solving.
+func newCDCL(c *CNF) *cdcl {
+	n := c.NumVars
+	s := &cdcl{
+		clauses:  make([][]satLit, 0, len(c.Clauses)),
+		watches:  make([][]int, 2*n),
+		assigns:  make([]int8, n),
+		level:    make([]int, n),
+		reason:   make([]int, n),
+		phase:    make([]bool, n),
+		activity: make([]float64, n),
+		varInc:   1,
+		heap:     make([]int, 0, n),
+		heapPos:  make([]int, n),
+		seen:     make([]bool, n),
+		trail:    make([]satLit, 0, n),
+		ok:       true,
+	}
+	for v := range s.heapPos {
+		s.heapPos[v] = -1
+		s.heapInsert(v)
+	}
+	for _, cl := range c.Clauses {
+		lits := make([]satLit, len(cl))
+		for i, l := range cl {
+			lits[i] = satLitOf(l)
+		}
+		s.addClause(lits)
+	}
+	return s
+}
+
+// value returns the value of a literal (1, -1, or 0 if unassigned).
+func (s *cdcl) value(l satLit) int8 {
+	a := s.assigns[l.v()]
+	if l&1 == 1 {
+		return -a
+	}
+	return a
+}
+
+// decisionLevel returns the current decision level.
+func (s *cdcl) decisionLevel() int {
+	return len(s.trailLim)
+}
+
+// enqueue assigns true to a literal.
+func (s *cdcl) enqueue(l satLit, from int) {
+	v := l.v()
+	s.assigns[v] = 1
+	if l&1 == 1 {
+		s.assigns[v] = -1
+	}
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = from
+	s.trail = append(s.trail, l)
+}
+
+// attach adds a clause of at least two literals to the clause database and
+// watches its first two literals.  It returns the clause's index.
+func (s *cdcl) attach(lits []satLit) int {
+	ci := len(s.clauses)
+	s.clauses = append(s.clauses, lits)
+	s.watches[lits[0].not()] = append(s.watches[lits[0].not()], ci)
+	s.watches[lits[1].not()] = append(s.watches[lits[1].not()], ci)
+	return ci
+}
+
+// addClause adds a clause at decision level 0, simplifying it against the
+// current assignment.  It records in s.ok whether the formula may still be
+// satisfiable.
+func (s *cdcl) addClause(lits []satLit) {
+	if !s.ok {
+		return
+	}
+	s.cancelUntil(0)
+	kept := make([]satLit, 0, len(lits))
+	for _, l := range lits {
+		switch s.value(l) {
+		case 1:
+			return // Clause is already satisfied.
+		case -1:
+			continue // Literal can never hold.
+		}
+		dup := false
+		for _, k := range kept {
+			if k == l {
+				dup = true
+			} else if k == l.not() {
+				return // Clause is a tautology.
+			}
+		}
+		if !dup {
+			kept = append(kept, l)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		s.ok = false
+	case 1:
+		s.enqueue(kept[0], -1)
+		s.ok = s.propagate() < 0
+	default:
+		s.attach(kept)
+	}
+}
+
+// propagate performs unit propagation on all pending assignments.  It returns
+// the index of a conflicting clause or -1 if there is no conflict.
+func (s *cdcl) propagate() int {
+	for s.qhead < len(s.trail) {
+		p := s.trail[s.qhead]
+		s.qhead++
+		falseLit := p.not()
+		ws := s.watches[p]
+		j := 0
+		for i := 0; i < len(ws); i++ {
+			ci := ws[i]
+			c := s.clauses[ci]
+
+			// Ensure the false literal is c[1].
+			if c[0] == falseLit {
+				c[0], c[1] = c[1], c[0]
+			}
+			if s.value(c[0]) == 1 {
+				ws[j] = ci
+				j++
+				continue
+			}
+
+			// Look for a new literal to watch.
+			found := false
+			for k := 2; k < len(c); k++ {
+				if s.value(c[k]) != -1 {
+					c[1], c[k] = c[k], c[1]
+					s.watches[c[1].not()] = append(s.watches[c[1].not()], ci)
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+
+			// The clause is unit or conflicting.
+			ws[j] = ci
+			j++
+			if s.value(c[0]) == -1 {
+				j += copy(ws[j:], ws[i+1:])
+				s.watches[p] = ws[:j]
+				s.qhead = len(s.trail)
+				return ci
+			}
+			s.enqueue(c[0], ci)
+		}
+		s.watches[p] = ws[:j]
+	}
+	return -1
+}
+
+// analyze derives a learned clause from a conflict using the first unique
+// implication point.  It returns the learned clause, whose first literal is
+// asserting, and the decision level to which to backtrack.
+func (s *cdcl) analyze(confl int) ([]satLit, int) {
+	learnt := []satLit{0}
+	pathC := 0
+	p := satLit(-1)
+	idx := len(s.trail) - 1
+	for {
+		c := s.clauses[confl]
+		start := 0
+		if p >= 0 {
+			start = 1 // c[0] is p itself.
+		}
+		for _, q := range c[start:] {
+			v := q.v()
+			if s.seen[v] || s.level[v] == 0 {
+				continue
+			}
+			s.seen[v] = true
+			s.bump(v)
+			if s.level[v] == s.decisionLevel() {
+				pathC++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+
+		// Select the next literal on the trail to expand.
+		for !s.seen[s.trail[idx].v()] {
+			idx--
+		}
+		p = s.trail[idx]
+		idx--
+		confl = s.reason[p.v()]
+		s.seen[p.v()] = false
+		pathC--
+		if pathC == 0 {
+			break
+		}
+	}
+	learnt[0] = p.not()
+	for _, l := range learnt[1:] {
+		s.seen[l.v()] = false
+	}
+
+	// Backtrack to the highest level among the remaining literals, and
+	// watch a literal from that level.
+	bt := 0
+	for i := 1; i < len(learnt); i++ {
+		if lvl := s.level[learnt[i].v()]; lvl > bt {
+			bt = lvl
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+	return learnt, bt
+}
+
+// cancelUntil undoes all assignments above a given decision level.
+func (s *cdcl) cancelUntil(lvl int) {
+	if s.decisionLevel() <= lvl {
+		return
+	}
+	for i := len(s.trail) - 1; i >= s.trailLim[lvl]; i-- {
+		v := s.trail[i].v()
+		s.phase[v] = s.assigns[v] > 0
+		s.assigns[v] = 0
+		if s.heapPos[v] < 0 {
+			s.heapInsert(v)
+		}
+	}
+	s.trail = s.trail[:s.trailLim[lvl]]
+	s.trailLim = s.trailLim[:lvl]
+	s.qhead = len(s.trail)
+}
+
+// bump increases a variable's branching priority.
+func (s *cdcl) bump(v int) {
+	s.activity[v] += s.varInc
+	if s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.varInc *= 1e-100
+	}
+	if s.heapPos[v] >= 0 {
+		s.heapUp(s.heapPos[v])
+	}
+}
+
+// heapInsert adds a variable to the branching heap.
+func (s *cdcl) heapInsert(v int) {
+	s.heapPos[v] = len(s.heap)
+	s.heap = append(s.heap, v)
+	s.heapUp(s.heapPos[v])
+}
+
+// heapUp moves the variable at a given heap position toward the root.
+func (s *cdcl) heapUp(i int) {
+	v := s.heap[i]
+	for i > 0 {
+		parent := (i - 1) / 2
+		if s.activity[s.heap[parent]] >= s.activity[v] {
+			break
+		}
+		s.heap[i] = s.heap[parent]
+		s.heapPos[s.heap[i]] = i
+		i = parent
+	}
+	s.heap[i] = v
+	s.heapPos[v] = i
+}
+
+// heapPop removes and returns the most active variable in the branching
+// heap.
+func (s *cdcl) heapPop() int {
+	top := s.heap[0]
+	s.heapPos[top] = -1
+	last := s.heap[len(s.heap)-1]
+	s.heap = s.heap[:len(s.heap)-1]
+	if len(s.heap) == 0 {
+		return top
+	}
+	i := 0
+	for {
+		child := 2*i + 1
+		if child >= len(s.heap) {
+			break
+		}
+		if child+1 < len(s.heap) && s.activity[s.heap[child+1]] > s.activity[s.heap[child]] {
+			child++
+		}
+		if s.activity[s.heap[child]] <= s.activity[last] {
+			break
+		}
+		s.heap[i] = s.heap[child]
+		s.heapPos[s.heap[i]] = i
+		i = child
+	}
+	s.heap[i] = last
+	s.heapPos[last] = i
+	return top
+}
+
+// pickBranch returns an unassigned variable to branch on or -1 if all
+// variables are assigned.
+func (s *cdcl) pickBranch() int {
+	for len(s.heap) > 0 {
+		if v := s.heapPop(); s.assigns[v] == 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// solve searches for a satisfying assignment.  It returns true if one was
+// found, in which case the assignment remains in place until the next call
+// to addClause.
+func (s *cdcl) solve() bool {
+	if !s.ok {
+		return false
+	}
+	restart := 100
+	nConf := 0
+	for {
+		confl := s.propagate()
+		if confl >= 0 {
+			// Learn a clause from the conflict and backtrack.
+			s.Conflict++
+			nConf++
+			if s.decisionLevel() == 0 {
+				s.ok = false
+				return false
+			}
+			learnt, bt := s.analyze(confl)
+			s.cancelUntil(bt)
+			if len(learnt) == 1 {
+				s.enqueue(learnt[0], -1)
+			} else {
+				s.enqueue(learnt[0], s.attach(learnt))
+			}
+			s.varInc /= 0.95
+			if nConf >= restart {
+				s.cancelUntil(0)
+				nConf = 0
+				restart += restart / 2
+			}
+			continue
+		}
+
+		// Make a decision, preferring each variable's previous value.
+		v := s.pickBranch()
+		if v < 0 {
+			return true
+		}
+		s.trailLim = append(s.trailLim, len(s.trail))
+		l := satLit(2*v + 1)
+		if s.phase[v] {
+			l = satLit(2 * v)
+		}
+		s.enqueue(l, -1)
+	}
+}
+
+// A satSolver solves a netlist by Tseitin-encoding it as a CNF formula and
+// enumerating the formula's models with a CDCL SAT solver.
+type satSolver struct{}
+
+// Solve returns every distinct assignment to the query's variables for which
+// the query succeeds.  If the query contains no variables, it reports only
+// whether the query can succeed.
+func (satSolver) Solve(prob *Problem) (sols []Solution, err error) {
+	p := prob.Program.Params
+	defer recoverError(p, &err)
+	nl := prob.Netlist
+	if nl == nil {
+		fatal("--solver=sat requires --backend=native")
+	}
+
+	// Assert that the query succeeds.
+	pins := make(map[string]bool, len(prob.Pins)+1)
+	for nm, b := range prob.Pins {
+		pins[nm] = b
+	}
+	pins["Query.Valid"] = true
+	cnf := nl.CNF(pins)
+	s := newCDCL(cnf)
+	verbosePrintf(p, "Solving a CNF formula with %d variable(s) and %d clause(s)",
+		cnf.NumVars, len(cnf.Clauses))
+
+	// Identify the nets whose values distinguish one solution from
+	// another.
+	haveVar := hasVariable(prob.Program.queryTypes())
+	qNets := make([]Net, 0, len(nl.Ports))
+	for pName, v := range nl.Ports {
+		if pName != "Query.Valid" && v != NetFalse && v != NetTrue {
+			qNets = append(qNets, v)
+		}
+	}
+
+	// Enumerate models, blocking each one's assignment to the query's
+	// ports before searching for the next.
+	ham := nl.Hamiltonian()
+	signed := prob.signedVars()
+	sols = make([]Solution, 0)
+	for s.solve() {
+		spins := make([]int8, nl.NumNets)
+		for v, a := range s.assigns {
+			spins[v] = a
+		}
+		sols = append(sols, Solution{Values: decodePorts(nl, spins, signed), Energy: ham.energy(spins), Tally: 1})
+		if !haveVar || len(qNets) == 0 {
+			break
+		}
+		block := make([]satLit, len(qNets))
+		for i, v := range qNets {
+			block[i] = satLitOf(netVar(v))
+			if spins[v] > 0 {
+				block[i] = block[i].not()
+			}
+		}
+		s.addClause(block)
+	}
+	verbosePrintf(p, "Found %d model(s) after %d conflict(s)", len(sols), s.Conflict)
+
+	// If the query contains no variables, report whether it succeeded.
+	if !haveVar {
+		ok := 0
+		if len(sols) > 0 {
+			ok = 1
+		}
+		return []Solution{{Values: map[string]int{"Valid": ok}, Tally: 1}}, nil
+	}
+	return sols, nil
+}
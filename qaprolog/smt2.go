@@ -0,0 +1,986 @@
+// Output an AST as SMT-LIB 2 bit-vector formulas.
+
+package qaprolog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// An smtExpr is an SMT-LIB bit-vector expression and its width in bits.
+type smtExpr struct {
+	Text  string // Textual representation of the expression
+	Width uint   // Number of bits in the expression's value
+}
+
+// smtSort returns the SMT-LIB sort of a bit vector of a given width.
+func smtSort(w uint) string {
+	return fmt.Sprintf("(_ BitVec %d)", w)
+}
+
+// smtConst returns a bit-vector constant.  The value is truncated to the
+// given width.
+func smtConst(w uint, v int) smtExpr {
+	return smtExpr{Text: fmt.Sprintf("(_ bv%d %d)", v&(1<<w-1), w), Width: w}
+}
+
+// smtSymbol quotes a name for use as an SMT-LIB symbol.
+func smtSymbol(nm string) string {
+	return "|" + strings.NewReplacer("|", "_", "\\", "_").Replace(nm) + "|"
+}
+
+// smtAtom returns the name of the SMT-LIB constant that represents an atom.
+func smtAtom(s string) string {
+	return smtSymbol("atom:" + s)
+}
+
+// extract returns a range of bits from a bit-vector expression.
+func (e smtExpr) extract(hi, lo uint) smtExpr {
+	if lo == 0 && hi+1 == e.Width {
+		return e
+	}
+	return smtExpr{Text: fmt.Sprintf("((_ extract %d %d) %s)", hi, lo, e.Text), Width: hi - lo + 1}
+}
+
+// resize zero-extends or truncates a bit-vector expression to a given width.
+func (e smtExpr) resize(w uint) smtExpr {
+	switch {
+	case e.Width == w:
+		return e
+	case e.Width > w:
+		return e.extract(w-1, 0)
+	default:
+		return smtExpr{Text: fmt.Sprintf("((_ zero_extend %d) %s)", w-e.Width, e.Text), Width: w}
+	}
+}
+
+// signExtend sign-extends or truncates a bit-vector expression to a given
+// width.
+func (e smtExpr) signExtend(w uint) smtExpr {
+	if e.Width >= w {
+		return e.resize(w)
+	}
+	return smtExpr{Text: fmt.Sprintf("((_ sign_extend %d) %s)", w-e.Width, e.Text), Width: w}
+}
+
+// smtConcat concatenates bit-vector expressions, with the first expression in
+// the least-significant position.  Zero-width expressions are omitted.
+func smtConcat(es ...smtExpr) smtExpr {
+	texts := make([]string, 0, len(es))
+	w := uint(0)
+	for i := len(es) - 1; i >= 0; i-- {
+		if es[i].Width > 0 {
+			texts = append(texts, es[i].Text)
+			w += es[i].Width
+		}
+	}
+	if len(texts) == 1 {
+		return smtExpr{Text: texts[0], Width: w}
+	}
+	return smtExpr{Text: "(concat " + strings.Join(texts, " ") + ")", Width: w}
+}
+
+// smtApply returns an SMT-LIB function application.  A nullary function is
+// represented by its name alone.
+func smtApply(fn string, args []string) string {
+	if len(args) == 0 {
+		return fn
+	}
+	return "(" + fn + " " + strings.Join(args, " ") + ")"
+}
+
+// smtAnd returns the conjunction of a list of Boolean expressions.
+func smtAnd(conds []string) string {
+	switch len(conds) {
+	case 0:
+		return "true"
+	case 1:
+		return conds[0]
+	default:
+		return smtApply("and", conds)
+	}
+}
+
+// smtOr returns the disjunction of a list of Boolean expressions.
+func smtOr(conds []string) string {
+	switch len(conds) {
+	case 0:
+		return "false"
+	case 1:
+		return conds[0]
+	default:
+		return smtApply("or", conds)
+	}
+}
+
+// smtEqual returns an expression that is true if and only if two bit-vector
+// expressions are equal after being extended to a common width.
+func smtEqual(e1, e2 smtExpr) string {
+	w := e1.Width
+	if e2.Width > w {
+		w = e2.Width
+	}
+	return fmt.Sprintf("(= %s %s)", e1.resize(w).Text, e2.resize(w).Text)
+}
+
+// prologToSMTRel maps a Prolog relational operator to an SMT-LIB bit-vector
+// predicate.
+var prologToSMTRel = map[string]string{
+	"=<":  "bvule",
+	">=":  "bvuge",
+	"<":   "bvult",
+	">":   "bvugt",
+	"=":   "=",
+	"\\=": "distinct",
+	"is":  "=",
+}
+
+// prologToSMTSignedRel maps a Prolog relational operator to an SMT-LIB
+// bit-vector predicate on signed integers.
+var prologToSMTSignedRel = map[string]string{
+	"=<":  "bvsle",
+	">=":  "bvsge",
+	"<":   "bvslt",
+	">":   "bvsgt",
+	"=":   "=",
+	"\\=": "distinct",
+	"is":  "=",
+}
+
+// prologToSMTArith maps a Prolog arithmetic operator to an SMT-LIB bit-vector
+// function.
+var prologToSMTArith = map[string]string{
+	"+":   "bvadd",
+	"-":   "bvsub",
+	"*":   "bvmul",
+	"/\\": "bvand",
+	"\\/": "bvor",
+	"xor": "bvxor",
+}
+
+// prologToSMTUnsignedDiv and prologToSMTSignedDiv map a Prolog division,
+// modulo, remainder, or shift operator to an SMT-LIB bit-vector function on
+// unsigned and signed integers, respectively.
+var (
+	prologToSMTUnsignedDiv = map[string]string{
+		"//":  "bvudiv",
+		"/":   "bvudiv",
+		"rem": "bvurem",
+		"mod": "bvurem",
+		"<<":  "bvshl",
+		">>":  "bvlshr",
+	}
+	prologToSMTSignedDiv = map[string]string{
+		"//":  "bvsdiv",
+		"/":   "bvsdiv",
+		"rem": "bvsrem",
+		"mod": "bvsmod",
+		"<<":  "bvshl",
+		">>":  "bvashr",
+	}
+)
+
+// An smtBuilder converts clause groups to SMT-LIB formulas.  It mirrors
+// circuitBuilder, but each clause group becomes a function rather than being
+// instantiated at every call site.
+type smtBuilder struct {
+	p        *Parameters           // Global parameters
+	nm2tys   map[string]ArgTypes   // Argument types of each clause group
+	clVarTys map[*ASTNode]TypeInfo // Variable types of each clause
+}
+
+// groupOrder returns the names of all clause groups reachable from the query,
+// each preceded by all of the clause groups it calls.
+func (b *smtBuilder) groupOrder(qName string) []string {
+	order := make([]string, 0, len(b.p.TopLevel))
+	seen := make(map[string]Empty, len(b.p.TopLevel))
+	var visit func(nm string)
+	visit = func(nm string) {
+		if _, ok := seen[nm]; ok {
+			return
+		}
+		seen[nm] = Empty{}
+		cls, ok := b.p.TopLevel[nm]
+		if !ok {
+			return // Built-in predicate
+		}
+		if _, lvl, ok := splitLevel(nm); !ok || lvl > 0 {
+			for _, cl := range cls {
+				for _, pr := range cl.calls() {
+					visit(pr.calleeName(b.p))
+				}
+			}
+		}
+		order = append(order, nm)
+	}
+	visit(qName)
+	return order
+}
+
+// writeGroup writes a clause group as an SMT-LIB function from its arguments
+// to a Boolean.
+func (b *smtBuilder) writeGroup(w io.Writer, nm string) {
+	// Write a comment and the function's signature.
+	p := b.p
+	cls := p.TopLevel[nm]
+	tys := b.nm2tys[nm]
+	_, vArgs := cls[0].args()
+	tyStrs := make([]string, len(tys))
+	for i, ty := range tys {
+		tyStrs[i] = ty.String()
+	}
+	fmt.Fprintf(w, "; Define %s(%s)", strings.Split(nm, "/")[0], strings.Join(tyStrs, ", "))
+	if _, lvl, ok := splitLevel(nm); ok {
+		fmt.Fprintf(w, " at recursion level %d", lvl)
+	}
+	fmt.Fprintln(w, ".")
+	params := make([]string, len(vArgs))
+	args := make([]smtExpr, len(vArgs))
+	for i, v := range vArgs {
+		args[i] = smtExpr{Text: v, Width: p.argBits(nm, i, tys[i])}
+		params[i] = fmt.Sprintf("(%s %s)", v, smtSort(args[i].Width))
+	}
+	fmt.Fprintf(w, "(define-fun %s (%s) Bool\n", smtSymbol(nm), strings.Join(params, " "))
+
+	// The lowest level of a recursive clause group always fails.
+	if _, lvl, ok := splitLevel(nm); ok && lvl == 0 {
+		fmt.Fprintln(w, "  false)")
+		return
+	}
+
+	// The function holds if any clause holds.
+	alts := make([]string, len(cls))
+	for i, cl := range cls {
+		alts[i] = b.clauseValid(nm, cl, args, tys, b.clVarTys[cl])
+	}
+	fmt.Fprintf(w, "  %s)\n", smtOr(alts))
+}
+
+// clauseValid returns a Boolean expression indicating whether a clause in
+// clause group nm holds for the given arguments.  It mirrors the native
+// version of clauseValid, existentially quantifying the clause's local
+// variables.
+func (b *smtBuilder) clauseValid(nm string, cl *ASTNode, args []smtExpr, tys ArgTypes, vTy TypeInfo) string {
+	// Map Prolog variables to expressions.  As we go along, constrain all
+	// variables with the same Prolog name to have the same value.
+	p := b.p
+	valid := make([]string, 0, 16)
+	pArgs, _ := cl.args()
+	p2e := make(map[string]smtExpr, len(pArgs))
+	rebound := make([]int, 0, len(pArgs))
+	for i, pa := range pArgs {
+		v, seen := p2e[pa]
+		switch {
+		case seen:
+			valid = append(valid, b.equal(args[i], v, vTy[pa]))
+		case p.rebound(nm, cl, i, vTy):
+			rebound = append(rebound, i)
+		default:
+			p2e[pa] = args[i]
+		}
+	}
+
+	// Map variables that appear within list or structure arguments to
+	// portions of those arguments.
+	terms := cl.Children[0].Children[1:]
+	for i, t := range terms {
+		switch c := t.Children[0]; c.Type {
+		case ListType:
+			valid = append(valid, b.bindListArg(c, args[i], tys[i], p2e)...)
+		case StructureType:
+			valid = append(valid, b.bindStructArg(c, args[i], p2e, vTy)...)
+		}
+	}
+
+	// Ensure that lists and structures passed to the query are well
+	// formed.
+	if cl.Type == QueryType {
+		for i, v := range args {
+			if c := b.canonical(v, tys[i]); c != "true" {
+				valid = append(valid, c)
+			}
+		}
+	}
+
+	// Introduce a quantified variable for each local Prolog variable.
+	locals := make([]string, 0, 8)
+	for _, pv := range cl.FindByType(VariableType) {
+		pName := pv.Text
+		if _, seen := p2e[pName]; seen {
+			continue
+		}
+		v := smtExpr{Text: smtSymbol("$" + pName), Width: p.varBits(cl, pName, vTy[pName])}
+		locals = append(locals, fmt.Sprintf("(%s %s)", v.Text, smtSort(v.Width)))
+		if c := b.canonical(v, vTy[pName]); c != "true" {
+			valid = append(valid, c)
+		}
+		p2e[pName] = v
+	}
+
+	// Copy arguments to variables of a different width.
+	for _, i := range rebound {
+		valid = append(valid, b.equal(args[i], p2e[pArgs[i]], InfNumeral))
+	}
+
+	// Compare numeral and atom arguments to their expected values.
+	for i, pa := range pArgs {
+		if t := terms[i].Children[0].Type; t == ListType || t == StructureType {
+			continue // Handled by bindListArg or bindStructArg
+		}
+		r0 := rune(pa[0])
+		switch {
+		case unicode.IsLower(r0):
+			valid = append(valid, smtEqual(args[i], b.expr(terms[i], p2e, vTy)))
+		case unicode.IsDigit(r0), r0 == '-':
+			valid = append(valid, b.equal(args[i], b.expr(terms[i], p2e, vTy), InfNumeral))
+		}
+	}
+
+	// Convert the clause body to a list of Boolean expressions.
+	valid = append(valid, b.goalConditions(cl.Children[1:], p2e, vTy)...)
+	if len(locals) == 0 {
+		return smtAnd(valid)
+	}
+	return fmt.Sprintf("(exists (%s) %s)", strings.Join(locals, " "), smtAnd(valid))
+}
+
+// canonical returns a Boolean expression indicating whether a list or
+// structure is well formed.  It returns "true" for all other types.  It
+// mirrors the native version of canonical.
+func (b *smtBuilder) canonical(v smtExpr, ty VarType) string {
+	p := b.p
+	switch {
+	case ty.IsList():
+		eBits := p.typeBits(ty.ElemType())
+		lenExpr := b.listLength(v, ty)
+		terms := make([]string, 0, p.MaxListLen+1)
+		if 1<<p.ListLenBits-1 > p.MaxListLen {
+			terms = append(terms, fmt.Sprintf("(bvule %s %s)",
+				lenExpr.Text, smtConst(p.ListLenBits, int(p.MaxListLen)).Text))
+		}
+		for i := uint(0); i < p.MaxListLen; i++ {
+			elt := v.extract((i+1)*eBits-1, i*eBits)
+			terms = append(terms, fmt.Sprintf("(or (bvugt %s %s) %s)",
+				lenExpr.Text, smtConst(p.ListLenBits, int(i)).Text, smtEqual(elt, smtConst(eBits, 0))))
+		}
+		return smtAnd(terms)
+
+	case ty == InfStructure:
+		fBits := p.fieldBits()
+		tag := v.extract(v.Width-1, p.MaxArity*fBits)
+		alts := make([]string, len(p.IntToFunctor))
+		for t, f := range p.IntToFunctor {
+			conds := []string{smtEqual(tag, smtConst(p.FunctorBits, t))}
+			tys := p.FunctorTypes[f]
+			for i := uint(0); i < p.MaxArity; i++ {
+				used := uint(0)
+				if i < uint(len(tys)) {
+					used = p.typeBits(tys[i])
+				}
+				if used < fBits {
+					conds = append(conds, smtEqual(v.extract((i+1)*fBits-1, i*fBits+used), smtConst(fBits-used, 0)))
+				}
+			}
+			alts[t] = smtAnd(conds)
+		}
+		return smtOr(alts)
+
+	default:
+		return "true"
+	}
+}
+
+// listLength returns the length field of a list.
+func (b *smtBuilder) listLength(v smtExpr, ty VarType) smtExpr {
+	lo := b.p.MaxListLen * b.p.typeBits(ty.ElemType())
+	return v.extract(lo+b.p.ListLenBits-1, lo)
+}
+
+// bindListArg matches a list-valued clause argument against the list that
+// appears in the clause's head.  It mirrors the native version of
+// bindListArg.
+func (b *smtBuilder) bindListArg(l *ASTNode, v smtExpr, ty VarType, p2e map[string]smtExpr) []string {
+	// Constrain the length of the list.
+	p := b.p
+	elts, tail := l.listParts()
+	nElts := uint(len(elts))
+	lenExpr := b.listLength(v, ty)
+	valid := make([]string, 0, nElts+1)
+	switch {
+	case tail == nil:
+		valid = append(valid, smtEqual(lenExpr, smtConst(p.ListLenBits, int(nElts))))
+	case nElts > 0:
+		valid = append(valid, fmt.Sprintf("(bvuge %s %s)", lenExpr.Text, smtConst(p.ListLenBits, int(nElts)).Text))
+	}
+
+	// Map each variable element to a slice of the list, and compare each
+	// numeral or atom element to the corresponding slice.
+	eBits := p.typeBits(ty.ElemType())
+	for i, e := range elts {
+		elt := v.extract(uint(i+1)*eBits-1, uint(i)*eBits)
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, smtEqual(elt, b.expr(c, p2e, nil)))
+			continue
+		}
+		if pv, seen := p2e[c.Value.(string)]; seen {
+			valid = append(valid, smtEqual(elt, pv))
+		} else {
+			p2e[c.Value.(string)] = elt
+		}
+	}
+	if tail == nil {
+		return valid
+	}
+
+	// Construct the list's tail.
+	cs := make([]smtExpr, 0, 3)
+	if nElts < p.MaxListLen {
+		cs = append(cs, v.extract(p.MaxListLen*eBits-1, nElts*eBits))
+	}
+	if nElts > 0 {
+		cs = append(cs, smtConst(nElts*eBits, 0))
+	}
+	cs = append(cs, smtExpr{
+		Text:  fmt.Sprintf("(bvsub %s %s)", lenExpr.Text, smtConst(p.ListLenBits, int(nElts)).Text),
+		Width: p.ListLenBits,
+	})
+	tExpr := smtConcat(cs...)
+	if pv, seen := p2e[tail.Value.(string)]; seen {
+		valid = append(valid, smtEqual(tExpr, pv))
+	} else {
+		p2e[tail.Value.(string)] = tExpr
+	}
+	return valid
+}
+
+// bindStructArg matches a structure-valued clause argument against the
+// structure that appears in the clause's head.  It mirrors the native version
+// of bindStructArg.
+func (b *smtBuilder) bindStructArg(s *ASTNode, v smtExpr, p2e map[string]smtExpr, vTy TypeInfo) []string {
+	// Constrain the functor.
+	p := b.p
+	args := s.Children[1:]
+	fBits := p.fieldBits()
+	valid := make([]string, 0, len(args)+1)
+	tag := v.extract(v.Width-1, p.MaxArity*fBits)
+	valid = append(valid, smtEqual(tag, smtConst(p.FunctorBits, p.FunctorToInt[s.functorName()])))
+
+	// Map each variable argument to a field of the structure, and compare
+	// each numeral or atom argument to the corresponding field.
+	tys := p.FunctorTypes[s.functorName()]
+	for i, e := range args {
+		lo := uint(i) * fBits
+		field := v.extract(lo+p.typeBits(tys[i])-1, lo)
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, smtEqual(field, b.expr(c, p2e, vTy)))
+			continue
+		}
+		if pv, seen := p2e[c.Value.(string)]; seen {
+			valid = append(valid, smtEqual(field, pv))
+		} else {
+			p2e[c.Value.(string)] = field
+		}
+	}
+	return valid
+}
+
+// goalConditions converts a list of predicates to a list of Boolean
+// expressions.  It mirrors the native version of goalConditions.
+func (b *smtBuilder) goalConditions(goals []*ASTNode, p2e map[string]smtExpr, tys TypeInfo) []string {
+	valid := make([]string, 0, len(goals))
+	for _, pred := range goals {
+		valid = append(valid, b.listSideConditions(pred, p2e, tys)...)
+		if c := b.cond(pred, p2e, tys); c != "true" {
+			valid = append(valid, c)
+		}
+	}
+	return valid
+}
+
+// listSideConditions returns a list of Boolean expressions that must hold for
+// the lists constructed by a predicate to fit within MaxListLen elements.  It
+// mirrors the native version of listSideConditions.
+func (b *smtBuilder) listSideConditions(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) []string {
+	if a.Type == PredicateType {
+		switch a.Children[0].Type {
+		case NegationType, DisjunctionType:
+			return nil
+		}
+	}
+	p := b.p
+	conds := make([]string, 0)
+	for _, l := range a.FindByType(ListType) {
+		elts, tail := l.listParts()
+		if tail == nil {
+			continue
+		}
+		ty, err := l.termType(tys, p.FunctorTypes)
+		checkError(err)
+		lenExpr := b.listLength(b.expr(tail, p2e, tys), ty)
+		maxLen := smtConst(p.ListLenBits, int(p.MaxListLen-uint(len(elts))))
+		conds = append(conds, fmt.Sprintf("(bvule %s %s)", lenExpr.Text, maxLen.Text))
+	}
+	return conds
+}
+
+// cond converts a predicate or relation to a Boolean expression.  It mirrors
+// the native version of cond.
+func (b *smtBuilder) cond(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) string {
+	switch a.Type {
+	case PredicateType:
+		// Handle predicate AST nodes that are really just wrappers.
+		if len(a.Children) == 1 {
+			c := a.Children[0]
+			if c.Type == AtomType {
+				// Built-in predicates with no arguments
+				switch c.Value.(string) {
+				case "true":
+					return "true"
+				case "fail", "false":
+					return "false"
+				}
+				fatalf("Internal error: Unexpected predicate %s/0", c.Value)
+			}
+			return b.cond(c, p2e, tys)
+		}
+
+		// Ignore atom/1 and integer/1, which exist solely for the type
+		// system.
+		if len(a.Children) == 2 {
+			pName := a.Children[0].Value.(string)
+			if pName == "atom" || pName == "integer" {
+				return "true"
+			}
+		}
+
+		// Apply the function that represents the clause group the
+		// predicate refers to.
+		nm := a.calleeName(b.p)
+		cTys := b.nm2tys[nm]
+		args := make([]string, len(a.Children)-1)
+		for i, c := range a.Children[1:] {
+			args[i] = b.resize(b.expr(c, p2e, tys), b.p.argBits(nm, i, cTys[i]), cTys[i]).Text
+		}
+		return smtApply(smtSymbol(nm), args)
+
+	case NegationType:
+		g := a.Children[0]
+		conds := append(b.listSideConditions(g, p2e, tys), b.cond(g, p2e, tys))
+		return "(not " + smtAnd(conds) + ")"
+
+	case DisjunctionType:
+		// Each branch is guarded by the negation of the conditions of
+		// all preceding if-then branches.
+		alts := make([]string, len(a.Children))
+		guards := make([]string, 0, len(a.Children))
+		for i, br := range a.Children {
+			goals := br.Children
+			items := append([]string{}, guards...)
+			if br.Type == IfThenType {
+				c := smtAnd(b.goalConditions(br.Children[0].Children, p2e, tys))
+				items = append(items, c)
+				guards = append(guards, "(not "+c+")")
+				goals = br.Children[1].Children
+			}
+			items = append(items, b.goalConditions(goals, p2e, tys)...)
+			alts[i] = smtAnd(items)
+		}
+		return smtOr(alts)
+
+	case RelationType:
+		return b.relation(a, p2e, tys)
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return "false" // We should never get here.
+}
+
+// relation converts a relation to a Boolean expression.
+func (b *smtBuilder) relation(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) string {
+	op := a.Value.(string)
+	e1, e2 := a.Children[0], a.Children[2]
+
+	// Lower equality and inequality between two structures to
+	// argument-wise equality, as in structRelation.
+	if e1.Type == TermType && e2.Type == TermType {
+		s1, s2 := e1.Children[0], e2.Children[0]
+		if s1.Type == StructureType && s2.Type == StructureType {
+			eq := "false"
+			if s1.functorName() == s2.functorName() {
+				eqs := make([]string, len(s1.Children)-1)
+				for i, c1 := range s1.Children[1:] {
+					eqs[i] = smtEqual(b.expr(c1, p2e, tys), b.expr(s2.Children[i+1], p2e, tys))
+				}
+				eq = smtAnd(eqs)
+			}
+			if op == "=" {
+				return eq
+			}
+			return "(not " + eq + ")"
+		}
+	}
+
+	// Compare two expressions.  As in Verilog, every operand is first
+	// extended to the width of the widest operand or, if the relation's
+	// arithmetic could overflow, to a wider width and, if integers are
+	// unsigned, biased to make every value nonnegative.
+	rels := prologToSMTRel
+	if b.p.Signed {
+		rels = prologToSMTSignedRel
+	}
+	fn, ok := rels[op]
+	if !ok {
+		fatalf("Internal error: Failed to convert %s %q to SMT-LIB", a.Type, op)
+	}
+	w := b.width(e1, p2e, tys)
+	if w2 := b.width(e2, p2e, tys); w2 > w {
+		w = w2
+	}
+	rw, widen := b.p.RelWidths[a]
+	if widen && rw.Bits > w {
+		w = rw.Bits
+	}
+	v1 := b.arith(e1, p2e, tys, w)
+	v2 := b.arith(e2, p2e, tys, w)
+	if widen && rw.Bias != 0 {
+		bias := smtConst(w, rw.Bias).Text
+		v1.Text = fmt.Sprintf("(bvadd %s %s)", v1.Text, bias)
+		v2.Text = fmt.Sprintf("(bvadd %s %s)", v2.Text, bias)
+	}
+	cmp := fmt.Sprintf("(%s %s %s)", fn, v1.Text, v2.Text)
+	return smtAnd(append([]string{cmp}, b.arithDefined(a, p2e, tys)...))
+}
+
+// arithDefined returns a list of Boolean expressions indicating whether every
+// arithmetic operation in a relation is defined: No division, modulo, or
+// remainder operation has a zero divisor, no shift operation has a negative
+// shift amount, and every operation that p.ArithChecks says to check produces
+// a value that fits in IntBits bits.  It mirrors the native version of
+// arithDefined.
+func (b *smtBuilder) arithDefined(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) []string {
+	top := b.p.storageRange(b.p.IntBits)
+	ge, le := "bvuge", "bvule"
+	if b.p.Signed {
+		ge, le = "bvsge", "bvsle"
+	}
+	oks := make([]string, 0, 4)
+	for _, e := range divisionNodes(a) {
+		dw := b.p.divWidth(e)
+		v2 := b.arith(e.Children[2], p2e, tys, dw.Bits)
+		switch op := e.Children[1].Value.(string); {
+		case op != "<<" && op != ">>":
+			oks = append(oks, fmt.Sprintf("(distinct %s %s)", v2.Text, smtConst(dw.Bits, 0).Text))
+		case dw.Signed:
+			oks = append(oks, fmt.Sprintf("(bvsge %s %s)", v2.Text, smtConst(dw.Bits, 0).Text))
+		}
+	}
+	for _, e := range arithNodes(a) {
+		ck, ok := b.p.ArithChecks[e]
+		if !ok {
+			continue
+		}
+		w := b.width(e, p2e, tys)
+		if ck.Bits > w {
+			w = ck.Bits
+		}
+		v := b.arith(e, p2e, tys, w).Text
+		if ck.Bias != 0 {
+			v = fmt.Sprintf("(bvadd %s %s)", v, smtConst(w, ck.Bias).Text)
+		}
+		if ck.Bias != 0 || b.p.Signed {
+			oks = append(oks, fmt.Sprintf("(%s %s %s)", ge, v, smtConst(w, ck.Bias+int(top.Lo)).Text))
+		}
+		oks = append(oks, fmt.Sprintf("(%s %s %s)", le, v, smtConst(w, ck.Bias+int(top.Hi)).Text))
+	}
+	return oks
+}
+
+// width returns the width of the widest operand of an expression.  It mirrors
+// the native version of width.
+func (b *smtBuilder) width(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) uint {
+	p := b.p
+	switch a.Type {
+	case NumeralType:
+		return p.IntBits
+
+	case AtomType:
+		return p.SymBits
+
+	case VariableType:
+		return p2e[a.Value.(string)].Width
+
+	case TermType, PrimaryExprType, ListTailType:
+		if isFunction(a) {
+			return p.divWidth(a).Bits
+		}
+		return b.width(a.Children[0], p2e, tys)
+
+	case UnaryExprType, AdditiveExprType, MultiplicativeExprType:
+		if isDivision(a) {
+			return p.divWidth(a).Bits
+		}
+		w := uint(0)
+		for _, c := range a.Children {
+			if cw := b.width(c, p2e, tys); cw > w {
+				w = cw
+			}
+		}
+		return w
+
+	case ListType, StructureType:
+		ty, err := a.termType(tys, p.FunctorTypes)
+		checkError(err)
+		return p.typeBits(ty)
+
+	default:
+		return 0 // Operator
+	}
+}
+
+// arith converts an arithmetic expression to a bit-vector expression of a
+// given width.  It mirrors the native version of arith.
+func (b *smtBuilder) arith(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo, w uint) smtExpr {
+	switch a.Type {
+	case TermType, PrimaryExprType:
+		if isFunction(a) {
+			return b.function(a, p2e, tys, w)
+		}
+		return b.arith(a.Children[0], p2e, tys, w)
+
+	case UnaryExprType:
+		if len(a.Children) == 1 {
+			return b.arith(a.Children[0], p2e, tys, w)
+		}
+		e := b.arith(a.Children[1], p2e, tys, w)
+		if a.Children[0].Value.(string) == "\\" {
+			return smtExpr{Text: "(bvnot " + e.Text + ")", Width: w}
+		}
+		return smtExpr{Text: "(bvneg " + e.Text + ")", Width: w}
+
+	case AdditiveExprType, MultiplicativeExprType:
+		if len(a.Children) == 1 {
+			return b.arith(a.Children[0], p2e, tys, w)
+		}
+		if isDivision(a) {
+			return b.divide(a, p2e, tys, w)
+		}
+		op := a.Children[1].Value.(string)
+		fn, ok := prologToSMTArith[op]
+		if !ok {
+			fatalf("Internal error: Failed to convert %s %q to SMT-LIB", a.Type, op)
+		}
+		v1 := b.arith(a.Children[0], p2e, tys, w)
+		v2 := b.arith(a.Children[2], p2e, tys, w)
+		return smtExpr{Text: fmt.Sprintf("(%s %s %s)", fn, v1.Text, v2.Text), Width: w}
+	}
+	e := b.expr(a, p2e, tys)
+	if intOperand(a, tys) {
+		return b.resize(e, w, InfNumeral)
+	}
+	return e.resize(w)
+}
+
+// divide converts a division, modulo, remainder, or shift operation to a
+// bit-vector expression of a given width.  It mirrors the native version of
+// divide.
+func (b *smtBuilder) divide(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo, w uint) smtExpr {
+	dw := b.p.divWidth(a)
+	fns := prologToSMTUnsignedDiv
+	if dw.Signed {
+		fns = prologToSMTSignedDiv
+	}
+	v1 := b.arith(a.Children[0], p2e, tys, dw.Bits)
+	v2 := b.arith(a.Children[2], p2e, tys, dw.Bits)
+	e := smtExpr{Text: fmt.Sprintf("(%s %s %s)", fns[a.Children[1].Value.(string)], v1.Text, v2.Text), Width: dw.Bits}
+	if dw.Signed {
+		return e.signExtend(w)
+	}
+	return e.resize(w)
+}
+
+// function converts an application of an evaluable function to a bit-vector
+// expression of a given width.  It mirrors the native version of function.
+func (b *smtBuilder) function(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo, w uint) smtExpr {
+	dw := b.p.divWidth(a)
+	args := make([]string, len(a.Children)-1)
+	for i, c := range a.Children[1:] {
+		args[i] = b.arith(c, p2e, tys, dw.Bits).Text
+	}
+	lt := "bvult"
+	if dw.Signed {
+		lt = "bvslt"
+	}
+	zero := smtConst(dw.Bits, 0).Text
+	var v string
+	switch fn := a.Value.(string); fn {
+	case "abs":
+		v = args[0]
+		if dw.Signed {
+			v = fmt.Sprintf("(ite (bvslt %s %s) (bvneg %s) %s)", v, zero, v, v)
+		}
+	case "sign":
+		v = fmt.Sprintf("(ite (= %s %s) %s %s)", args[0], zero, zero, smtConst(dw.Bits, 1).Text)
+		if dw.Signed {
+			v = fmt.Sprintf("(ite (bvslt %s %s) %s %s)", args[0], zero, smtConst(dw.Bits, -1).Text, v)
+		}
+	case "min":
+		v = fmt.Sprintf("(ite (%s %s %s) %s %s)", lt, args[1], args[0], args[1], args[0])
+	case "max":
+		v = fmt.Sprintf("(ite (%s %s %s) %s %s)", lt, args[0], args[1], args[1], args[0])
+	default:
+		fatalf("Internal error: Failed to convert function %q to SMT-LIB", fn)
+	}
+	e := smtExpr{Text: v, Width: dw.Bits}
+	if dw.Signed {
+		return e.signExtend(w)
+	}
+	return e.resize(w)
+}
+
+// resize zero-extends or truncates a bit-vector expression of a given type to
+// a given width.  It mirrors the native version of resize.
+func (b *smtBuilder) resize(e smtExpr, w uint, ty VarType) smtExpr {
+	if b.p.Signed && ty == InfNumeral {
+		return e.signExtend(w)
+	}
+	return e.resize(w)
+}
+
+// equal returns an expression that is true if and only if two bit-vector
+// expressions of a given type are equal.  It mirrors the native version of
+// equal.
+func (b *smtBuilder) equal(e1, e2 smtExpr, ty VarType) string {
+	w := e1.Width
+	if e2.Width > w {
+		w = e2.Width
+	}
+	return fmt.Sprintf("(= %s %s)", b.resize(e1, w, ty).Text, b.resize(e2, w, ty).Text)
+}
+
+// expr converts a term or arithmetic expression to a bit-vector expression.
+// It mirrors the native version of expr.
+func (b *smtBuilder) expr(a *ASTNode, p2e map[string]smtExpr, tys TypeInfo) smtExpr {
+	p := b.p
+	switch a.Type {
+	case NumeralType:
+		return smtConst(p.IntBits, a.Value.(int))
+
+	case AtomType:
+		return smtExpr{Text: smtAtom(a.Value.(string)), Width: p.SymBits}
+
+	case VariableType:
+		v, ok := p2e[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert variable %s to SMT-LIB", a.Value.(string))
+		}
+		return v
+
+	case TermType, PrimaryExprType, ListTailType:
+		if isFunction(a) {
+			return b.arith(a, p2e, tys, b.width(a, p2e, tys))
+		}
+		return b.expr(a.Children[0], p2e, tys)
+
+	case UnaryExprType, AdditiveExprType, MultiplicativeExprType:
+		return b.arith(a, p2e, tys, b.width(a, p2e, tys))
+
+	case ListType:
+		// Concatenate the list's elements, its tail (if any), and its
+		// length.
+		ty, err := a.termType(tys, p.FunctorTypes)
+		checkError(err)
+		elts, tail := a.listParts()
+		nElts := uint(len(elts))
+		eBits := p.typeBits(ty.ElemType())
+		cs := make([]smtExpr, 0, len(elts)+2)
+		for _, e := range elts {
+			cs = append(cs, b.expr(e, p2e, tys).resize(eBits))
+		}
+		if tail == nil {
+			if nElts < p.MaxListLen {
+				cs = append(cs, smtConst((p.MaxListLen-nElts)*eBits, 0))
+			}
+			cs = append(cs, smtConst(p.ListLenBits, int(nElts)))
+		} else {
+			tExpr := b.expr(tail, p2e, tys)
+			if nElts < p.MaxListLen {
+				cs = append(cs, tExpr.extract((p.MaxListLen-nElts)*eBits-1, 0))
+			}
+			cs = append(cs, smtExpr{
+				Text:  fmt.Sprintf("(bvadd %s %s)", b.listLength(tExpr, ty).Text, smtConst(p.ListLenBits, int(nElts)).Text),
+				Width: p.ListLenBits,
+			})
+		}
+		return smtConcat(cs...)
+
+	case StructureType:
+		// Concatenate the structure's arguments, each zero-extended
+		// to fill a field, and its functor tag.
+		args := a.Children[1:]
+		fBits := p.fieldBits()
+		cs := make([]smtExpr, 0, len(args)+2)
+		for _, c := range args {
+			cs = append(cs, b.expr(c, p2e, tys).resize(fBits))
+		}
+		if nArgs := uint(len(args)); nArgs < p.MaxArity {
+			cs = append(cs, smtConst((p.MaxArity-nArgs)*fBits, 0))
+		}
+		cs = append(cs, smtConst(p.FunctorBits, p.FunctorToInt[a.functorName()]))
+		return smtConcat(cs...)
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return smtExpr{} // We should never get here.
+}
+
+// WriteSMT2 writes an entire (preprocessed) AST as an SMT-LIB 2 script that
+// asserts the query and, if the query contains variables, asks for their
+// values.
+func (a *ASTNode) WriteSMT2(w io.Writer, p *Parameters,
+	nm2tys map[string]ArgTypes, clVarTys map[*ASTNode]TypeInfo) {
+	// Output some header comments.
+	fmt.Fprintf(w, "; SMT-LIB 2 version of Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "; Conversion by %s, written by Scott Pakin <pakin@lanl.gov>\n", p.ProgName)
+	fmt.Fprintln(w, ";")
+	fmt.Fprintf(w, "; Note: This program uses %d bit(s) for atoms and, by default, %d bit(s) for\n", p.SymBits, p.IntBits)
+	fmt.Fprintf(w, "; (%s) integers.  Local variables are existentially quantified.\n", p.intSignedness())
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "(set-logic BV)")
+	fmt.Fprintln(w, "")
+
+	// Define constants for all of our symbols.
+	fmt.Fprintln(w, "; Define all of the symbols used in this program.")
+	for i, s := range p.IntToSym {
+		fmt.Fprintf(w, "(define-fun %s () %s %s) ; %d = %s\n",
+			smtAtom(s), smtSort(p.SymBits), smtConst(p.SymBits, i).Text, i, s)
+	}
+
+	// Write each clause group, callees before callers.
+	b := &smtBuilder{p: p, nm2tys: nm2tys, clVarTys: clVarTys}
+	q := a.FindByType(QueryType)[0]
+	qName := q.Value.(string)
+	for _, nm := range b.groupOrder(qName) {
+		fmt.Fprintln(w, "")
+		b.writeGroup(w, nm)
+	}
+
+	// Assert the query.
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "; Assert the query.")
+	_, vArgs := q.args()
+	tys := nm2tys[qName]
+	for i, v := range vArgs {
+		fmt.Fprintf(w, "(declare-const %s %s) ; %v\n", v, smtSort(p.argBits(qName, i, tys[i])), tys[i])
+	}
+	fmt.Fprintf(w, "(assert %s)\n", smtApply(smtSymbol(qName), vArgs))
+	fmt.Fprintln(w, "(check-sat)")
+	if len(vArgs) > 0 {
+		fmt.Fprintf(w, "(get-value (%s))\n", strings.Join(vArgs, " "))
+	}
+}
@@ -0,0 +1,159 @@
+// Define an interface to the various methods of solving a compiled program
+
+package qaprolog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Problem is a compiled program as presented to a Solver.
+type Problem struct {
+	Program   *Program        // Program being solved
+	QMASMFile string          // Name of the file containing the program's QMASM code ("" if not compiled)
+	Netlist   *Netlist        // Program's netlist (nil if not compiled by the native backend)
+	Pins      map[string]bool // Value to which each of a set of ports must be pinned
+}
+
+// A Solver finds low-energy states of a compiled program and decodes each of
+// them into a Solution.  Solution values are keyed by query-variable name
+// (e.g., "X" for port "Query.X") plus "Valid" for port "Query.Valid".
+type Solver interface {
+	Solve(prob *Problem) ([]Solution, error)
+}
+
+// SolverNames lists the solvers that NewSolver recognizes.
+var SolverNames = []string{"qmasm", "sa", "sat", "reference", "external"}
+
+// NewSolver returns the Solver named by p.Solver.
+func NewSolver(p *Parameters) (Solver, error) {
+	switch p.Solver {
+	case "qmasm":
+		return qmasmSolver{}, nil
+	case "sa":
+		return annealSolver{}, nil
+	case "sat":
+		return satSolver{}, nil
+	case "reference":
+		return referenceSolver{}, nil
+	case "external":
+		if len(p.SolverCommand) == 0 {
+			return nil, &Error{Msg: "--solver=external requires a --solver-command"}
+		}
+		return externalSolver{Command: p.SolverCommand}, nil
+	default:
+		return nil, &Error{Msg: fmt.Sprintf("Unrecognized solver %q", p.Solver)}
+	}
+}
+
+// reportsEnergy reports whether the solver named by p.Solver samples the
+// program's Hamiltonian and therefore finds solutions of meaningful energy.
+// The SAT and reference solvers instead search for solutions classically.
+func (p *Parameters) reportsEnergy() bool {
+	switch p.Solver {
+	case "sat", "reference":
+		return false
+	default:
+		return true
+	}
+}
+
+// queryTypes returns the type of each of a program's query variables.
+func (prog *Program) queryTypes() TypeInfo {
+	return prog.queryTys
+}
+
+// problem packages a program for presentation to a Solver.  If the query
+// contains any variables, the solver is asked to consider only states in
+// which the query succeeds.
+func (prog *Program) problem() *Problem {
+	prob := &Problem{
+		Program:   prog,
+		QMASMFile: prog.qmasmFile,
+		Netlist:   prog.netlist,
+		Pins:      make(map[string]bool, 1),
+	}
+	if hasVariable(prog.queryTypes()) {
+		prob.Pins["Query.Valid"] = true
+	}
+	return prob
+}
+
+// signedVars returns the set of query variables whose values are signed
+// integers.  It returns nil if integers are unsigned.
+func (prob *Problem) signedVars() map[string]bool {
+	if !prob.Program.Params.Signed {
+		return nil
+	}
+	sv := make(map[string]bool)
+	for nm, ty := range prob.Program.queryTypes() {
+		if ty == InfNumeral {
+			sv[nm] = true
+		}
+	}
+	return sv
+}
+
+// signExtend interprets the low-order w bits of a value as a two's-complement
+// integer.
+func signExtend(v int, w uint) int {
+	if w == 0 || w >= 63 {
+		return v
+	}
+	v &= 1<<w - 1
+	if v>>(w-1) != 0 {
+		v -= 1 << w
+	}
+	return v
+}
+
+// portBit splits a port name of the form "Query.<name>[<bit>]" or
+// "Query.<name>" into a query-variable name and a bit number.  It returns
+// false if the port does not belong to the query.
+func portBit(pName string) (string, uint, bool) {
+	if !strings.HasPrefix(pName, "Query.") {
+		return "", 0, false
+	}
+	pName = pName[6:]
+	i := strings.LastIndex(pName, "[")
+	if i < 0 {
+		return pName, 0, true
+	}
+	b, err := strconv.Atoi(strings.TrimSuffix(pName[i+1:], "]"))
+	if err != nil || b < 0 {
+		return "", 0, false
+	}
+	return pName[:i], uint(b), true
+}
+
+// tallySolutions merges solutions that assign the same values to all query
+// variables, summing their tallies and retaining the lowest energy.  It
+// returns the merged solutions, most frequently observed first.
+func tallySolutions(sols []Solution) []Solution {
+	merged := make(map[string]*Solution, len(sols))
+	for _, sol := range sols {
+		key := fmt.Sprint(sol.Values)
+		if m, ok := merged[key]; ok {
+			m.Tally += sol.Tally
+			if sol.Energy < m.Energy {
+				m.Energy = sol.Energy
+			}
+			continue
+		}
+		s := sol
+		merged[key] = &s
+	}
+	result := make([]Solution, 0, len(merged))
+	for _, sol := range merged {
+		result = append(result, *sol)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tally != result[j].Tally {
+			return result[i].Tally > result[j].Tally
+		}
+		return fmt.Sprint(result[i].Values) < fmt.Sprint(result[j].Values)
+	})
+	return result
+}
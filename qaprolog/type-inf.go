@@ -0,0 +1,983 @@
+// Perform type inference on an AST.
+
+package qaprolog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A VarType is the inferred type of a variable.
+type VarType int
+
+// We define seven different variable types.
+const (
+	InfUnknown     VarType = iota // Unknown type
+	InfNumeral                    // Inferred numeral
+	InfAtom                       // Inferred atom
+	InfList                       // Inferred list of unknown element type
+	InfNumeralList                // Inferred list of numerals
+	InfAtomList                   // Inferred list of atoms
+	InfStructure                  // Inferred structure (any functor)
+)
+
+// Convert a VarType to a string.
+func (v VarType) String() string {
+	switch v {
+	case InfUnknown:
+		return "*"
+	case InfNumeral:
+		return "num"
+	case InfAtom:
+		return "atom"
+	case InfList, InfNumeralList, InfAtomList:
+		return "list(" + v.ElemType().String() + ")"
+	case InfStructure:
+		return "struct"
+	default:
+		fatalf("Internal error converting variable type %d to a string", v)
+	}
+	return "" // Will never get here
+}
+
+// IsList reports whether a VarType represents a list.
+func (v VarType) IsList() bool {
+	return v == InfList || v == InfNumeralList || v == InfAtomList
+}
+
+// ElemType returns the type of a list's elements.
+func (v VarType) ElemType() VarType {
+	switch v {
+	case InfNumeralList:
+		return InfNumeral
+	case InfAtomList:
+		return InfAtom
+	default:
+		return InfUnknown
+	}
+}
+
+// ListOf returns the type of a list whose elements have a given type.
+func ListOf(v VarType) VarType {
+	switch v {
+	case InfNumeral:
+		return InfNumeralList
+	case InfAtom:
+		return InfAtomList
+	default:
+		return InfList
+	}
+}
+
+// UnifyTypes returns the more specific of two compatible types.  It returns
+// false if the types are incompatible.
+func UnifyTypes(v1, v2 VarType) (VarType, bool) {
+	switch {
+	case v1 == v2:
+		return v1, true
+	case v1 == InfUnknown:
+		return v2, true
+	case v2 == InfUnknown:
+		return v1, true
+	case v1.IsList() && v2.IsList():
+		e, ok := UnifyTypes(v1.ElemType(), v2.ElemType())
+		return ListOf(e), ok
+	default:
+		return InfUnknown, false
+	}
+}
+
+// TypeInfo represents a mapping from a variable to its type.
+type TypeInfo map[string]VarType
+
+// MergeTypes merges two type mappings.
+func MergeTypes(t1, t2 TypeInfo) (TypeInfo, error) {
+	tm := make(TypeInfo, len(t1)+len(t2))
+
+	// Populate tm with the union of all variables in t1 and t2.
+	for k := range t1 {
+		tm[k] = InfUnknown
+	}
+	for k := range t2 {
+		tm[k] = InfUnknown
+	}
+
+	// Look for type conflicts for each variable in turn.
+	for k := range tm {
+		// To reduce the number of cases to check, assign each variable
+		// a default value of InfUnknown.
+		v1, in1 := t1[k]
+		if !in1 {
+			v1 = InfUnknown
+		}
+		v2, in2 := t2[k]
+		if !in2 {
+			v2 = InfUnknown
+		}
+
+		// Any type overrides InfUnknown.  Otherwise, types must match.
+		ty, ok := UnifyTypes(v1, v2)
+		if !ok {
+			// v1 and v2 have incompatible types: complain.
+			return nil, fmt.Errorf("Type conflict for variable %s", k)
+		}
+		tm[k] = ty
+	}
+	return tm, nil
+}
+
+// SameTypes reports whether two type mappings are identical.
+func SameTypes(t1, t2 TypeInfo) bool {
+	if len(t1) != len(t2) {
+		return false
+	}
+	for k, v1 := range t1 {
+		if v2, ok := t2[k]; !ok || v1 != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return a map from clause name (e.g., "my_clause/3") to a list of AST nodes.
+func (a *ASTNode) clauseNames() map[string][]*ASTNode {
+	nm2node := make(map[string][]*ASTNode)
+	var walk func(c *ASTNode)
+	walk = func(c *ASTNode) {
+		if c.Type == ClauseType || c.Type == QueryType {
+			name := c.Value.(string)
+			old, seen := nm2node[name]
+			if seen {
+				nm2node[name] = append(old, c)
+			} else {
+				nm2node[name] = []*ASTNode{c}
+			}
+			return
+		}
+		for _, cc := range c.Children {
+			walk(cc)
+		}
+	}
+	walk(a)
+	return nm2node
+}
+
+// ClauseDependencies maps a clause name to the names of all clauses that it
+// depends on.
+type ClauseDependencies map[string]map[string]Empty
+
+// findClauseDependencies walks an AST starting from a clause and reports its
+// immediate dependencies.
+func (a *ASTNode) findClauseDependencies() ClauseDependencies {
+	// Find the name and arity of the current dependency (e.g.,
+	// "my_clause/3").
+	clName := a.Value.(string)
+	deps := make(ClauseDependencies)
+
+	// Define a function to recursively search an AST for dependencies.
+	var findDeps func(c *ASTNode)
+	findDeps = func(c *ASTNode) {
+		if c.Type == PredicateType {
+			// Ensure we're not an ordinary expression.
+			if len(c.Children) <= 1 {
+				// Not a reference to another clause
+				return
+			}
+
+			// We have a dependency.  Store it.
+			chName := fmt.Sprintf("%s/%d", c.Children[0].Value.(string), len(c.Children)-1)
+			if _, ok := deps[clName]; !ok {
+				deps[clName] = make(map[string]Empty)
+			}
+			deps[clName][chName] = Empty{}
+			return
+		}
+		for _, ch := range c.Children {
+			findDeps(ch)
+		}
+	}
+
+	// Search our children for dependencies.
+	for _, ch := range a.Children[1:] {
+		findDeps(ch)
+	}
+	return deps
+}
+
+// allClauseDependencies returns the complete set of dependencies for all
+// clauses.
+func allClauseDependencies(nm2cls map[string][]*ASTNode) ClauseDependencies {
+	deps := make(ClauseDependencies)
+	for _, cls := range nm2cls {
+		for _, cl := range cls {
+			deps[cl.Value.(string)] = make(map[string]Empty, 0)
+		}
+	}
+	for _, cls := range nm2cls {
+		for _, cl := range cls {
+			for from, to2 := range cl.findClauseDependencies() {
+				if _, ok := deps[from]; !ok {
+					// First time we see from
+					deps[from] = to2
+					continue
+				}
+				for nm := range to2 {
+					// Subsequent times we see from
+					deps[from][nm] = Empty{}
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// recursiveComponents returns all strongly connected components of a
+// dependency graph that involve recursion.  Each component is returned as a
+// sorted list of clause names.
+func (d ClauseDependencies) recursiveComponents() [][]string {
+	// Visit nodes in a deterministic order.
+	names := make([]string, 0, len(d))
+	for nm := range d {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+
+	// Apply Tarjan's algorithm to find all strongly connected components.
+	index := make(map[string]int, len(d))
+	lowLink := make(map[string]int, len(d))
+	onStack := make(map[string]bool, len(d))
+	stack := make([]string, 0, len(d))
+	comps := make([][]string, 0)
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowLink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for w := range d[v] {
+			if _, seen := index[w]; !seen {
+				connect(w)
+				if lowLink[w] < lowLink[v] {
+					lowLink[v] = lowLink[w]
+				}
+			} else if onStack[w] && index[w] < lowLink[v] {
+				lowLink[v] = index[w]
+			}
+		}
+		if lowLink[v] != index[v] {
+			return
+		}
+
+		// v is the root of a component.  Pop the component from the
+		// stack and keep it only if it is recursive.
+		var comp []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, w)
+			if w == v {
+				break
+			}
+		}
+		if _, self := d[v][v]; len(comp) > 1 || self {
+			sort.Strings(comp)
+			comps = append(comps, comp)
+		}
+	}
+	for _, nm := range names {
+		if _, seen := index[nm]; !seen {
+			connect(nm)
+		}
+	}
+	return comps
+}
+
+// findRoots returns the roots of a dependency graph.
+func (d ClauseDependencies) findRoots() []string {
+	// Start with every node that depends on another node as a potential
+	// root.
+	roots := make(map[string]Empty, len(d)*2)
+	for r := range d {
+		roots[r] = Empty{}
+	}
+
+	// Delete every node that another node depends upon.
+	for _, cs := range d {
+		for c := range cs {
+			delete(roots, c)
+		}
+	}
+
+	// Return the roots as a list.
+	rList := make([]string, 0, len(roots))
+	for r := range roots {
+		rList = append(rList, r)
+	}
+	return rList
+}
+
+// orderedClauses returns a list of all of an AST's clauses sorted in
+// dependency order.
+func (a *ASTNode) orderedClauses(nm2cls map[string][]*ASTNode) []*ASTNode {
+	// Build a complete set of dependencies for all clauses.
+	deps := allClauseDependencies(nm2cls)
+
+	// Find a partial ordering of the dependency graph.
+	roots := deps.findRoots()
+	nodesSeen := make(map[string]Empty, len(roots)*2)
+	ordNames := make([]string, 0, len(roots)*2)
+	var makeOrder func(from string)
+	makeOrder = func(nm string) {
+		if _, seen := nodesSeen[nm]; seen {
+			return
+		}
+		nodesSeen[nm] = Empty{}
+		for c := range deps[nm] {
+			makeOrder(c)
+		}
+		ordNames = append(ordNames, nm)
+	}
+	for _, r := range roots {
+		makeOrder(r)
+	}
+
+	// Recursive clauses that are unreachable from any root have not yet
+	// been ordered.  Order them now.
+	for _, comp := range deps.recursiveComponents() {
+		for _, nm := range comp {
+			makeOrder(nm)
+		}
+	}
+
+	// Convert from strings to nodes.
+	nDeps := len(ordNames)
+	order := make([]*ASTNode, 0, nDeps)
+	for _, nm := range ordNames {
+		order = append(order, nm2cls[nm]...)
+	}
+	return order
+}
+
+// ArgTypes is a list of argument types for a clause.
+type ArgTypes []VarType
+
+// MergeArgTypes merges two lists of argument types.
+func MergeArgTypes(a1, a2 ArgTypes) (ArgTypes, error) {
+	if len(a1) != len(a2) {
+		fatalf("Internal error: Length mismatch between %v and %v", a1, a2)
+	}
+	aTypes := make(ArgTypes, len(a1))
+	for i, t1 := range a1 {
+		ty, ok := UnifyTypes(t1, a2[i])
+		if !ok {
+			return nil, fmt.Errorf("Polymorphic type signatures are not currently supported")
+		}
+		aTypes[i] = ty
+	}
+	return aTypes, nil
+}
+
+// copyArgTypes returns a shallow copy of a mapping from name to argument
+// types.
+func copyArgTypes(m map[string]ArgTypes) map[string]ArgTypes {
+	c := make(map[string]ArgTypes, len(m))
+	for nm, tys := range m {
+		c[nm] = tys
+	}
+	return c
+}
+
+// sameArgTypes reports whether two mappings from clause name to argument types
+// are identical.
+func sameArgTypes(m1, m2 map[string]ArgTypes) bool {
+	if len(m1) != len(m2) {
+		return false
+	}
+	for nm, tys1 := range m1 {
+		tys2, ok := m2[nm]
+		if !ok || len(tys1) != len(tys2) {
+			return false
+		}
+		for i, t := range tys1 {
+			if t != tys2[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// When applied to a clause node, findClauseTypes augments a mapping from
+// clause name to argument types and returns the type of each variable used in
+// the clause.
+func (a *ASTNode) findClauseTypes(nm2tys, fn2tys map[string]ArgTypes) TypeInfo {
+	// Determine the name of each clause argument.
+	args := a.Children[0].Children[1:]
+	argNames := make([]string, len(args))
+	for i, c := range args {
+		argNames[i] = c.Value.(string)
+	}
+
+	// Seed the variable types with whatever we already know about the
+	// clause's arguments (e.g., from a caller).
+	cl := a.Value.(string)
+	seed := make(TypeInfo, len(args))
+	for i, ty := range nm2tys[cl] {
+		tm, err := args[i].termVarTypes(ty, fn2tys)
+		if err == nil {
+			seed, err = MergeTypes(seed, tm)
+		}
+		if err != nil {
+			parseError(args[i].Pos, "%s", err)
+		}
+	}
+
+	// Initialize the list of argument types based on what we can infer
+	// about all variables that appear in the clause.
+	vTypes := a.findVariableTypes(seed, nm2tys, fn2tys)
+	argTypes := make(ArgTypes, len(argNames))
+	for i, c := range args {
+		ty, err := c.termType(vTypes, fn2tys)
+		if err != nil {
+			parseError(c.Pos, "%s", err)
+		}
+		argTypes[i] = ty
+	}
+
+	// Merge the new argument list with the existing list, if any.
+	if oldTys, ok := nm2tys[cl]; ok {
+		var err error
+		argTypes, err = MergeArgTypes(oldTys, argTypes)
+		checkError(err)
+	}
+
+	// Assign the same type to every instance of a variable name.
+	var2ty := make(map[string]VarType, len(argTypes))
+	for i, v := range argNames {
+		ty1 := argTypes[i]
+		ty2, seen := var2ty[v]
+		if seen {
+			ty, ok := UnifyTypes(ty1, ty2)
+			if !ok {
+				fatalf("Type mismatch on variable %s in %s: %v vs. %v", v, cl, ty1, ty2)
+			}
+			var2ty[v] = ty
+		} else {
+			var2ty[v] = ty1
+		}
+	}
+	for i, v := range argNames {
+		argTypes[i] = var2ty[v]
+	}
+
+	// Propagate the argument types back to the variables that appear
+	// within the arguments (e.g., the elements of a list).
+	for i, c := range args {
+		tm, err := c.termVarTypes(argTypes[i], fn2tys)
+		if err == nil {
+			vTypes, err = MergeTypes(vTypes, tm)
+		}
+		if err != nil {
+			parseError(c.Pos, "%s", err)
+		}
+	}
+
+	// Refine the argument types based on the types of the variables they
+	// contain (e.g., "T" in "foo([_|T], T)").
+	for i, c := range args {
+		ty, err := c.termType(vTypes, fn2tys)
+		if err != nil {
+			parseError(c.Pos, "%s", err)
+		}
+		var ok bool
+		argTypes[i], ok = UnifyTypes(argTypes[i], ty)
+		if !ok {
+			parseError(c.Pos, "Type mismatch in argument %d of %s: %v vs. %v", i+1, cl, argTypes[i], ty)
+		}
+	}
+
+	// Update the map.
+	nm2tys[cl] = argTypes
+	return vTypes
+}
+
+// When applied to a term, termType returns the term's type given the types
+// of the variables it contains.  As a side effect, it refines the argument
+// types of any functor that appears in the term.
+func (a *ASTNode) termType(tm TypeInfo, fn2tys map[string]ArgTypes) (VarType, error) {
+	switch a.Type {
+	case TermType:
+		return a.Children[0].termType(tm, fn2tys)
+
+	case VariableType:
+		return tm[a.Value.(string)], nil
+
+	case ListType:
+		// A list's type is determined by its elements and its tail.
+		elts, tail := a.listParts()
+		ty := InfList
+		if tail != nil {
+			tTy, err := tail.termType(tm, fn2tys)
+			if err != nil {
+				return InfUnknown, err
+			}
+			var ok bool
+			ty, ok = UnifyTypes(ty, tTy)
+			if !ok {
+				return InfUnknown, fmt.Errorf("Tail of %s is not a list", a.Text)
+			}
+		}
+		for _, e := range elts {
+			eTy, err := e.termType(tm, fn2tys)
+			if err != nil {
+				return InfUnknown, err
+			}
+			var ok bool
+			ty, ok = UnifyTypes(ty, ListOf(eTy))
+			if !ok {
+				return InfUnknown, fmt.Errorf("Mixed element types in %s", a.Text)
+			}
+		}
+		return ty, nil
+
+	case StructureType:
+		// A structure's arguments determine its functor's argument
+		// types.
+		f := a.functorName()
+		args := a.Children[1:]
+		tys, ok := fn2tys[f]
+		if !ok {
+			tys = make(ArgTypes, len(args))
+		}
+		newTys := make(ArgTypes, len(args))
+		for i, c := range args {
+			cTy, err := c.termType(tm, fn2tys)
+			if err != nil {
+				return InfUnknown, err
+			}
+			newTys[i], ok = UnifyTypes(tys[i], cTy)
+			if !ok {
+				return InfUnknown, fmt.Errorf("Type mismatch in argument %d of %s: %v vs. %v", i+1, f, tys[i], cTy)
+			}
+		}
+		fn2tys[f] = newTys
+		return InfStructure, nil
+
+	default:
+		return a.findExprType(), nil
+	}
+}
+
+// When applied to a term, termVarTypes returns the type of each variable
+// that appears in the term given the type of the term as a whole.
+func (a *ASTNode) termVarTypes(ty VarType, fn2tys map[string]ArgTypes) (TypeInfo, error) {
+	switch a.Type {
+	case TermType:
+		return a.Children[0].termVarTypes(ty, fn2tys)
+
+	case VariableType:
+		return TypeInfo{a.Value.(string): ty}, nil
+
+	case ListType:
+		// Elements have the list's element type, and the tail has
+		// the list's type.
+		lTy, ok := UnifyTypes(ty, InfList)
+		if !ok {
+			return nil, fmt.Errorf("%s is not of type %v", a.Text, ty)
+		}
+		tm := make(TypeInfo)
+		elts, tail := a.listParts()
+		var err error
+		for _, e := range elts {
+			var eTm TypeInfo
+			eTm, err = e.termVarTypes(lTy.ElemType(), fn2tys)
+			if err != nil {
+				return nil, err
+			}
+			tm, err = MergeTypes(tm, eTm)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if tail != nil {
+			tm, err = MergeTypes(tm, TypeInfo{tail.Value.(string): lTy})
+		}
+		return tm, err
+
+	case StructureType:
+		// Arguments have their functor's argument types.
+		if _, ok := UnifyTypes(ty, InfStructure); !ok {
+			return nil, fmt.Errorf("%s is not of type %v", a.Text, ty)
+		}
+		tm := make(TypeInfo)
+		tys := fn2tys[a.functorName()]
+		for i, c := range a.Children[1:] {
+			cTy := InfUnknown
+			if tys != nil {
+				cTy = tys[i]
+			}
+			cTm, err := c.termVarTypes(cTy, fn2tys)
+			if err != nil {
+				return nil, err
+			}
+			tm, err = MergeTypes(tm, cTm)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return tm, nil
+
+	default:
+		// Literals contain no variables but must be of a compatible
+		// type.
+		if _, ok := UnifyTypes(ty, a.findExprType()); !ok {
+			return nil, fmt.Errorf("%s is not of type %v", a.Text, ty)
+		}
+		return TypeInfo{}, nil
+	}
+}
+
+// When applied to an expression node (specifically, RelationType or below),
+// findExprType returns the node's type.
+func (a *ASTNode) findExprType() VarType {
+	switch a.Type {
+	case NumeralType:
+		return InfNumeral
+
+	case AtomType:
+		return InfAtom
+
+	case VariableType:
+		return InfUnknown
+
+	case PrimaryExprType, UnaryExprType, MultiplicativeExprType, AdditiveExprType:
+		// Arithmetic applies only to numerals.
+		if len(a.Children) == 1 {
+			// Trivial wrapper for an underlying expression: Ask
+			// they underlying expression for its type.
+			return a.Children[0].findExprType()
+		}
+		return InfNumeral
+
+	case TermType:
+		return a.Children[0].findExprType()
+
+	case ListType:
+		// The element type is determined by termType, which knows
+		// about variable types.
+		return InfList
+
+	case StructureType:
+		return InfStructure
+
+	case RelationType:
+		// Relations are either numeric or unknown, depending on the
+		// specific relation.
+		op := a.Children[1].Value.(string)
+		if op == "=" || op == "\\=" {
+			// Equality and inequality are polymorphic.  See if we
+			// can determine the type from our arguments.
+			t1 := a.Children[0].findExprType()
+			t2 := a.Children[2].findExprType()
+			ty, ok := UnifyTypes(t1, t2)
+			if !ok {
+				fatalf("Can't apply %q to mixed types (%v and %v)", op, t1, t2)
+			}
+			return ty
+		} else {
+			// All other relations apply only to numerals.
+			return InfNumeral
+		}
+
+	default:
+		fatalf("Internal error: findExprType doesn't recognize %v", a.Type)
+	}
+	return InfUnknown // Will never get here.
+}
+
+// When applied to any AST node, allVariables returns a set of all variables
+// named in that node.
+func (a *ASTNode) allVariables() map[string]Empty {
+	var m map[string]Empty
+	if a.Type == VariableType {
+		m = make(map[string]Empty)
+		m[a.Value.(string)] = Empty{}
+		return m
+	}
+	for _, c := range a.Children {
+		mm := c.allVariables()
+		if m == nil {
+			// Skip the copy for the first child.
+			m = mm
+		} else {
+			// Merge the remaining children's variable lists.
+			for v := range mm {
+				m[v] = Empty{}
+			}
+		}
+	}
+	return m
+}
+
+// branchGoals returns all of the top-level predicates in a branch of a
+// disjunction, including the condition of an if-then branch.
+func (a *ASTNode) branchGoals() []*ASTNode {
+	if a.Type == IfThenType {
+		return append(append([]*ASTNode{}, a.Children[0].Children...), a.Children[1].Children...)
+	}
+	return a.Children
+}
+
+// bodyGoals returns all of the predicates in a clause body.  Negated
+// predicates are replaced with the predicates they negate, and disjunctions
+// are replaced with the predicates in all of their branches.
+func (a *ASTNode) bodyGoals() []*ASTNode {
+	goals := make([]*ASTNode, 0, len(a.Children))
+	var addGoal func(p *ASTNode)
+	addGoal = func(p *ASTNode) {
+		switch c := p.Children[0]; c.Type {
+		case NegationType:
+			addGoal(c.Children[0])
+		case DisjunctionType:
+			for _, b := range c.Children {
+				for _, g := range b.branchGoals() {
+					addGoal(g)
+				}
+			}
+		default:
+			goals = append(goals, p)
+		}
+	}
+	for _, p := range a.Children[1:] {
+		addGoal(p)
+	}
+	return goals
+}
+
+// When applied to a clause node, findVariableTypes refines an initial mapping
+// from variable name to type and returns the result.
+func (a *ASTNode) findVariableTypes(tm TypeInfo, nm2tys, fn2tys map[string]ArgTypes) TypeInfo {
+	var err error
+	type ForceSame struct {
+		Vars   map[string]Empty // Set of variable names
+		Parent *ASTNode         // Parent that includes all of the variables
+	}
+	same := make([]ForceSame, 0, 16) // Sets of variables that must wind up with the same type
+
+	// Define a function that assigns the same type to all variables in all
+	// of our child nodes.
+	setAllChildren := func(c *ASTNode, ty VarType) {
+		// Assign the same type to all children.
+		vSet := c.allVariables()
+		newTm := make(TypeInfo, len(vSet))
+		for k := range vSet {
+			newTm[k] = ty
+		}
+		tm, err = MergeTypes(tm, newTm)
+		checkError(err)
+
+		// If the type is InfUnknown, check the types once we know what
+		// they are.
+		if ty != InfUnknown {
+			return
+		}
+		same = append(same, ForceSame{Vars: vSet, Parent: c})
+	}
+
+	// Figure out what to do based on the types of the clause's children.
+	// Repeat until we reach a fixed point because types inferred from a
+	// later predicate can refine those of an earlier predicate (e.g., the
+	// elements of a list).
+	for {
+		prevTm := tm
+		same = same[:0]
+		for _, p := range a.bodyGoals() {
+			c := p.Children[0]
+			switch c.Type {
+			case RelationType, TermType:
+				if c.Type == RelationType && c.Children[0].Type == TermType {
+					// Relations between arbitrary terms
+					// (e.g., lists) require the terms to
+					// have the same type, which determines
+					// the types of their constituents.
+					tm = c.unifyRelationTypes(tm, fn2tys)
+					break
+				}
+
+				// All variables in a relation or term must
+				// have the same type.
+				setAllChildren(c, c.findExprType())
+
+			case AtomType:
+				// Line up the predicate's arguments with the
+				// corresponding clause's argument types.
+				name := fmt.Sprintf("%s/%d", c.Value, len(p.Children)-1)
+				tys, ok := nm2tys[name]
+				if !ok {
+					fatalf("Internal error: Failed to find clause %s", name)
+				}
+				for i, ty := range tys {
+					arg := p.Children[i+1]
+					newTm, err := arg.termVarTypes(ty, fn2tys)
+					if err != nil {
+						parseError(arg.Pos, "%s", err)
+					}
+					tm, err = MergeTypes(tm, newTm)
+					checkError(err)
+				}
+
+				// Conversely, refine any argument types the
+				// callee left unspecified (e.g., the element
+				// type of a list) based on what we know about
+				// the caller's arguments.
+				if name == "integer/1" || name == "atom/1" {
+					break
+				}
+				newTys := make(ArgTypes, len(tys))
+				for i, ty := range tys {
+					arg := p.Children[i+1]
+					argTy, err := arg.termType(tm, fn2tys)
+					if err != nil {
+						parseError(arg.Pos, "%s", err)
+					}
+					var ok bool
+					newTys[i], ok = UnifyTypes(ty, argTy)
+					if !ok {
+						parseError(arg.Pos, "Type mismatch in argument %d of %s: %v vs. %v", i+1, name, ty, argTy)
+					}
+				}
+				nm2tys[name] = newTys
+
+			default:
+				fatalf("Internal error: findVariableTypes doesn't recognize %v", c.Type)
+			}
+		}
+
+		// Propagate the type of any variable that must have the same
+		// type as other variables to those other variables.
+		for _, s := range same {
+			ty := InfUnknown
+			for k := range s.Vars {
+				if t, ok := UnifyTypes(ty, tm[k]); ok {
+					ty = t
+				}
+			}
+			if ty == InfUnknown {
+				continue
+			}
+			newTm := make(TypeInfo, len(s.Vars))
+			for k := range s.Vars {
+				newTm[k] = ty
+			}
+			if newTm, err := MergeTypes(tm, newTm); err == nil {
+				tm = newTm
+			}
+		}
+		if SameTypes(prevTm, tm) {
+			break
+		}
+	}
+
+	// Ensure we didn't later find that two free variables in a relation
+	// wound up with different types.
+	for _, s := range same {
+		k1 := "???"
+		ty := InfUnknown
+		for k := range s.Vars {
+			if ty == InfUnknown {
+				k1 = k
+				ty = tm[k]
+			}
+			if tm[k] != ty {
+				parseError(s.Parent.Pos, "Type mismatch between variables %s and %s", k1, k)
+			}
+		}
+	}
+	return tm
+}
+
+// When applied to a relation between two terms, unifyRelationTypes returns
+// an updated mapping from variable name to type.
+func (a *ASTNode) unifyRelationTypes(tm TypeInfo, fn2tys map[string]ArgTypes) TypeInfo {
+	// Determine the type common to both terms.
+	t1, t2 := a.Children[0], a.Children[2]
+	ty1, err := t1.termType(tm, fn2tys)
+	if err != nil {
+		parseError(t1.Pos, "%s", err)
+	}
+	ty2, err := t2.termType(tm, fn2tys)
+	if err != nil {
+		parseError(t2.Pos, "%s", err)
+	}
+	ty, ok := UnifyTypes(ty1, ty2)
+	if !ok {
+		parseError(a.Pos, "Can't apply %q to mixed types (%v and %v)", a.Value, ty1, ty2)
+	}
+
+	// Assign types to all variables in both terms.
+	for _, t := range []*ASTNode{t1, t2} {
+		newTm, err := t.termVarTypes(ty, fn2tys)
+		if err == nil {
+			tm, err = MergeTypes(tm, newTm)
+		}
+		if err != nil {
+			parseError(t.Pos, "%s", err)
+		}
+	}
+	return tm
+}
+
+// PerformTypeInference returns a mapping from clause name to argument types
+// for all clauses in the target AST.  It additionally stores the argument
+// types of each functor in the Parameters structure.
+func (a *ASTNode) PerformTypeInference(p *Parameters) (map[string]ArgTypes, map[*ASTNode]TypeInfo) {
+	// Compute a clause order in which to apply type inference.
+	nm2cls := a.clauseNames()
+	clauses := a.orderedClauses(nm2cls)
+
+	// Populate our mapping from clause name to argument types with a few
+	// built-in names.
+	nm2tys := make(map[string]ArgTypes, len(clauses)+5)
+	nm2tys["integer/1"] = ArgTypes{InfNumeral}
+	nm2tys["atom/1"] = ArgTypes{InfAtom}
+	nm2tys["true/0"] = ArgTypes{}
+	nm2tys["fail/0"] = ArgTypes{}
+	nm2tys["false/0"] = ArgTypes{}
+	fn2tys := make(map[string]ArgTypes, len(p.IntToFunctor))
+
+	// Recursive clauses may be invoked before their types have been
+	// inferred.  Start by assuming all arguments are of unknown type.
+	for nm, cls := range nm2cls {
+		nm2tys[nm] = make(ArgTypes, len(cls[0].Children[0].Children)-1)
+	}
+
+	// Perform type inference on each clause in turn.  Because callers can
+	// refine the argument types of their callees, repeat until the
+	// argument types stop changing.
+	clVarTys := make(map[*ASTNode]TypeInfo, len(clauses))
+	for {
+		prevTys := copyArgTypes(nm2tys)
+		prevFnTys := copyArgTypes(fn2tys)
+		for _, cl := range clauses {
+			clVarTys[cl] = cl.findClauseTypes(nm2tys, fn2tys)
+		}
+		if sameArgTypes(prevTys, nm2tys) && sameArgTypes(prevFnTys, fn2tys) {
+			break
+		}
+	}
+
+	// Ensure that we didn't wind up with any polymorphic clauses or
+	// functors.
+	for _, m := range []map[string]ArgTypes{nm2tys, fn2tys} {
+		for nm, tys := range m {
+			for i, t := range tys {
+				if t == InfUnknown || t == InfList {
+					fatalf("%s is polymorphic (in argument %d), which is not currently supported", nm, i+1)
+				}
+			}
+		}
+	}
+	p.FunctorTypes = fn2tys
+	return nm2tys, clVarTys
+}
@@ -0,0 +1,150 @@
+// Unroll recursive predicates into a fixed number of nonrecursive levels.
+
+package qaprolog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// levelName appends a recursion level to a clause name (e.g., "path/2" and 3
+// produce "path/2@3").
+func levelName(nm string, lvl uint) string {
+	return fmt.Sprintf("%s@%d", nm, lvl)
+}
+
+// splitLevel splits a clause name into a base name and a recursion level.
+// It returns false if the name does not include a recursion level.
+func splitLevel(nm string) (string, uint, bool) {
+	at := strings.LastIndex(nm, "@")
+	if at == -1 {
+		return nm, 0, false
+	}
+	lvl, err := strconv.ParseUint(nm[at+1:], 10, 0)
+	if err != nil {
+		return nm, 0, false
+	}
+	return nm[:at], uint(lvl), true
+}
+
+// calleeName returns the name of the clause group invoked by a predicate
+// (e.g., "path/2" or, for a recursive predicate, "path/2@3").
+func (a *ASTNode) calleeName(p *Parameters) string {
+	nm := fmt.Sprintf("%s/%d", a.Children[0].Value, len(a.Children)-1)
+	if lvl, ok := p.CallLevels[a]; ok {
+		return levelName(nm, lvl)
+	}
+	return nm
+}
+
+// clone returns a deep copy of an AST.
+func (a *ASTNode) clone() *ASTNode {
+	c := *a
+	c.Children = make([]*ASTNode, len(a.Children))
+	for i, ch := range a.Children {
+		c.Children[i] = ch.clone()
+	}
+	return &c
+}
+
+// calls returns all predicates within a clause body that invoke other
+// clauses.
+func (a *ASTNode) calls() []*ASTNode {
+	cs := make([]*ASTNode, 0, 4)
+	for _, ch := range a.Children[1:] {
+		for _, pr := range ch.FindByType(PredicateType) {
+			if len(pr.Children) > 1 {
+				cs = append(cs, pr)
+			}
+		}
+	}
+	return cs
+}
+
+// UnrollRecursion replaces each recursive clause group in p.TopLevel with a
+// chain of nonrecursive clause groups, one per level of recursion.  Level 0
+// always fails; each level k > 0 invokes level k-1 wherever the original
+// clause group invoked itself (directly or via mutual recursion).  All
+// other callers invoke the topmost level.
+func (a *ASTNode) UnrollRecursion(p *Parameters, nm2tys map[string]ArgTypes, clVarTys map[*ASTNode]TypeInfo) {
+	// Determine the maximum depth of each recursive clause group.  A
+	// max_depth directive overrides --max-depth.  All clause groups in
+	// the same strongly connected component share the same depth, which
+	// is the largest given by a directive for any of them.
+	p.CallLevels = make(map[*ASTNode]uint)
+	comps := allClauseDependencies(p.TopLevel).recursiveComponents()
+	if len(comps) == 0 {
+		return
+	}
+	nm2comp := make(map[string]int, len(p.TopLevel))
+	depths := make([]uint, len(comps))
+	for i, comp := range comps {
+		haveDirective := false
+		for _, nm := range comp {
+			nm2comp[nm] = i
+			if d, ok := p.PredDepths[nm]; ok {
+				if !haveDirective || d > depths[i] {
+					depths[i] = d
+				}
+				haveDirective = true
+			}
+		}
+		if !haveDirective {
+			if p.MaxDepth == 0 {
+				fatalf("Recursion in %s requires either --max-depth or a max_depth directive", strings.Join(comp, ", "))
+			}
+			depths[i] = p.MaxDepth
+		}
+		verbosePrintf(p, "Unrolling %s to a depth of %d", strings.Join(comp, ", "), depths[i])
+	}
+
+	// Define a function that assigns a level to every call a clause
+	// makes to a recursive clause group.  Calls within the caller's
+	// component invoke the level below the caller's.  Calls to other
+	// components invoke the topmost level.
+	assignLevels := func(cl *ASTNode, comp int, lvl uint) {
+		for _, pr := range cl.calls() {
+			nm := pr.calleeName(p)
+			c, ok := nm2comp[nm]
+			switch {
+			case !ok:
+				// Nonrecursive callee
+			case c == comp:
+				p.CallLevels[pr] = lvl - 1
+			default:
+				p.CallLevels[pr] = depths[c]
+			}
+		}
+	}
+
+	// Replace each recursive clause group with one clause group per
+	// level.  Level 0 retains the original clauses but only to describe
+	// its arguments.
+	top := make(map[string][]*ASTNode, len(p.TopLevel))
+	for nm, cls := range p.TopLevel {
+		c, ok := nm2comp[nm]
+		if !ok {
+			for _, cl := range cls {
+				assignLevels(cl, -1, 0)
+			}
+			top[nm] = cls
+			continue
+		}
+		top[levelName(nm, 0)] = cls
+		nm2tys[levelName(nm, 0)] = nm2tys[nm]
+		for lvl := uint(1); lvl <= depths[c]; lvl++ {
+			lNm := levelName(nm, lvl)
+			lCls := make([]*ASTNode, len(cls))
+			for i, cl := range cls {
+				lCls[i] = cl.clone()
+				lCls[i].Value = lNm
+				clVarTys[lCls[i]] = clVarTys[cl]
+				assignLevels(lCls[i], c, lvl)
+			}
+			top[lNm] = lCls
+			nm2tys[lNm] = nm2tys[nm]
+		}
+	}
+	p.TopLevel = top
+}
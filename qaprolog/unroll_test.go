@@ -0,0 +1,50 @@
+// Test the unrolling of recursive predicates.
+
+package qaprolog
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUnrollDepth ensures that a max_depth directive overrides --max-depth
+// and that recursion requires one or the other.
+func TestUnrollDepth(t *testing.T) {
+	src := `edge(a, b).
+edge(b, c).
+edge(c, d).
+edge(d, e).
+path(X, Y) :- edge(X, Y).
+path(X, Y) :- edge(X, Z), path(Z, Y).
+`
+	tests := []struct {
+		directive string
+		maxDepth  uint
+		want      string
+	}{
+		{"", 2, "Y = b\n\nY = c"},
+		{":- max_depth(path/2, 3).\n", 0, "Y = b\n\nY = c\n\nY = d"},
+		{":- max_depth(path/2, 1).\n", 4, "Y = b"},
+		{":- max_depth(path/2, 3).\n", 1, "Y = b\n\nY = c\n\nY = d"},
+	}
+	for _, tt := range tests {
+		for _, solver := range []string{"reference", "sat"} {
+			p := testParams("path(a, Y)")
+			p.MaxDepth = tt.maxDepth
+			prog, err := typeCheck(p, tt.directive+src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := solveText(t, prog, solver); got != tt.want {
+				t.Errorf("%q with --max-depth=%d --solver=%s: expected %q but saw %q",
+					tt.directive, tt.maxDepth, solver, tt.want, got)
+			}
+		}
+	}
+
+	// Recursion requires a depth.
+	_, err := typeCheck(testParams("path(a, Y)"), src)
+	if err == nil || !strings.Contains(err.Error(), "requires either --max-depth or a max_depth directive") {
+		t.Errorf("Expected unbounded recursion to be rejected but saw %v", err)
+	}
+}
@@ -0,0 +1,1072 @@
+// Output an AST as Verilog code.
+
+package qaprolog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// instanceSuffix returns a string to use for an instance name.  The string
+// is derived from the node's position in the input file so that instance
+// names are unique within a clause yet identical from run to run.
+func (a *ASTNode) instanceSuffix() string {
+	return fmt.Sprintf("L%dC%d", a.Pos.line, a.Pos.col)
+}
+
+// writeSymbols defines all of an AST's symbols as Verilog constants.
+func (a *ASTNode) writeSymbols(w io.Writer, p *Parameters) {
+	// Determine the minimum number of characters needed to represent all
+	// symbol names.
+	nSymChars := 1
+	for _, s := range p.IntToSym {
+		if len(s) > nSymChars {
+			nSymChars = len(s)
+		}
+	}
+
+	// Output nicely formatted symbol definitions.
+	fmt.Fprintln(w, "// Define all of the symbols used in this program.")
+	for i, s := range p.IntToSym {
+		fmt.Fprintf(w, "`define %-*s %d'd%d\n", nSymChars, s, p.SymBits, i)
+	}
+}
+
+// typeBits returns the number of bits needed to represent a value of a given
+// type.
+func (p *Parameters) typeBits(ty VarType) uint {
+	switch {
+	case ty == InfAtom:
+		return p.SymBits
+	case ty.IsList():
+		return p.ListLenBits + p.MaxListLen*p.typeBits(ty.ElemType())
+	case ty == InfStructure:
+		return p.FunctorBits + p.MaxArity*p.fieldBits()
+	default:
+		return p.IntBits
+	}
+}
+
+// fieldBits returns the number of bits used for each argument of a
+// structure.
+func (p *Parameters) fieldBits() uint {
+	if p.SymBits > p.IntBits {
+		return p.SymBits
+	}
+	return p.IntBits
+}
+
+// partSelect returns a Verilog expression that selects a range of bits from
+// a vector.
+func partSelect(v string, hi, lo uint) string {
+	if hi == lo {
+		return fmt.Sprintf("%s[%d]", v, lo)
+	}
+	return fmt.Sprintf("%s[%d:%d]", v, hi, lo)
+}
+
+// listLength returns a Verilog expression for the length of a list.  Lists
+// are stored as a length field followed by MaxListLen elements, with element
+// 0 in the least-significant position.
+func (p *Parameters) listLength(v string, ty VarType) string {
+	lo := p.MaxListLen * p.typeBits(ty.ElemType())
+	return partSelect(v, lo+p.ListLenBits-1, lo)
+}
+
+// listElement returns a Verilog expression for a given element of a list.
+func (p *Parameters) listElement(v string, ty VarType, i uint) string {
+	eBits := p.typeBits(ty.ElemType())
+	return partSelect(v, (i+1)*eBits-1, i*eBits)
+}
+
+// listElements returns a Verilog expression for all elements of a list
+// starting from a given element.
+func (p *Parameters) listElements(v string, ty VarType, i uint) string {
+	eBits := p.typeBits(ty.ElemType())
+	return partSelect(v, p.MaxListLen*eBits-1, i*eBits)
+}
+
+// intConst returns a Verilog integer constant of a given width.  The
+// constant is signed if integers are signed.
+func (p *Parameters) intConst(w uint, v int) string {
+	switch {
+	case p.Signed && v < 0:
+		return fmt.Sprintf("-%d'sd%d", w, -v)
+	case p.Signed:
+		return fmt.Sprintf("%d'sd%d", w, v)
+	default:
+		return fmt.Sprintf("%d'd%d", w, v)
+	}
+}
+
+// signedInt marks a Verilog integer expression as signed if integers are
+// signed.  This is needed for operands, such as list elements, that are
+// slices of a larger vector.
+func (p *Parameters) signedInt(v string) string {
+	if p.Signed {
+		return "$signed(" + v + ")"
+	}
+	return v
+}
+
+// signedDecl returns the keyword with which to declare a Verilog variable of
+// a given type.
+func (p *Parameters) signedDecl(ty VarType) string {
+	if p.Signed && ty == InfNumeral {
+		return "signed "
+	}
+	return ""
+}
+
+// zeros returns a Verilog expression for a given number of zero bits.
+func zeros(n uint) string {
+	return fmt.Sprintf("{%d{1'b0}}", n)
+}
+
+// listCanonical returns a Verilog expression that is true if and only if a
+// list's length is in range and all of its unused elements are zero.  This
+// ensures that equal lists have equal bit patterns.
+func (p *Parameters) listCanonical(v string, ty VarType) string {
+	lenExpr := p.listLength(v, ty)
+	eBits := p.typeBits(ty.ElemType())
+	terms := make([]string, 0, p.MaxListLen+1)
+	if 1<<p.ListLenBits-1 > p.MaxListLen {
+		terms = append(terms, fmt.Sprintf("%s <= %d'd%d", lenExpr, p.ListLenBits, p.MaxListLen))
+	}
+	for i := uint(0); i < p.MaxListLen; i++ {
+		terms = append(terms, fmt.Sprintf("(%s > %d'd%d || %s == %d'd0)",
+			lenExpr, p.ListLenBits, i, p.listElement(v, ty, i), eBits))
+	}
+	return strings.Join(terms, " && ")
+}
+
+// functorTag returns a Verilog expression for the functor tag of a structure.
+// Structures are stored as a functor tag followed by MaxArity fields of
+// fieldBits bits each, with argument 0 in the least-significant position.
+func (p *Parameters) functorTag(v string) string {
+	lo := p.MaxArity * p.fieldBits()
+	return partSelect(v, lo+p.FunctorBits-1, lo)
+}
+
+// structField returns a Verilog expression for the low-order bits of a given
+// field of a structure.
+func (p *Parameters) structField(v string, i, bits uint) string {
+	lo := i * p.fieldBits()
+	return partSelect(v, lo+bits-1, lo)
+}
+
+// structCanonical returns a Verilog expression that is true if and only if a
+// structure's functor tag is valid and all bits not used by the functor's
+// arguments are zero.  This ensures that equal structures have equal bit
+// patterns.
+func (p *Parameters) structCanonical(v string) string {
+	fBits := p.fieldBits()
+	alts := make([]string, len(p.IntToFunctor))
+	for t, f := range p.IntToFunctor {
+		conds := []string{fmt.Sprintf("%s == %d'd%d", p.functorTag(v), p.FunctorBits, t)}
+		tys := p.FunctorTypes[f]
+		for i := uint(0); i < p.MaxArity; i++ {
+			used := uint(0)
+			if i < uint(len(tys)) {
+				used = p.typeBits(tys[i])
+			}
+			if used < fBits {
+				conds = append(conds, fmt.Sprintf("%s == %d'd0",
+					partSelect(v, (i+1)*fBits-1, i*fBits+used), fBits-used))
+			}
+		}
+		alts[t] = "(" + strings.Join(conds, " && ") + ")"
+	}
+	return strings.Join(alts, " || ")
+}
+
+// args retrieves a clause's or a query's arguments in both Prolog and Verilog
+// format.  In the former case, arguments are renamed to A, B, C, etc.  This is
+// needed to handle both non-variable arguments (i.e., numerals or atoms) and
+// repeated variable arguments (as in "same(X, X)").  In the latter case, the
+// original names are preserved.  This is needed to report results in terms of
+// the program-specified variables names (and it is known a priori that clauses
+// contain only unique variable names in their argument lists).
+func (a *ASTNode) args() (pArgs, vArgs []string) {
+	pred := a.Children[0]
+	terms := pred.Children[1:]
+	pArgs = make([]string, len(terms)) // Prolog arguments (terms)
+	vArgs = make([]string, len(terms)) // Verilog arguments (variables)
+	for i, t := range terms {
+		pArgs[i] = t.Text
+		if a.Type == ClauseType {
+			vArgs[i] = numToVerVar(i)
+		} else {
+			vArgs[i] = pArgs[i]
+		}
+	}
+	return
+}
+
+// prologToVerilogUnary maps a Prolog unary operator to a Verilog unary
+// operator.
+var prologToVerilogUnary = map[string]string{
+	"-":  "-",
+	"\\": "~",
+}
+
+// prologToVerilogAdd maps a Prolog additive operator to a Verilog additive
+// operator.
+var prologToVerilogAdd = map[string]string{
+	"+":   "+",
+	"-":   "-",
+	"/\\": "&",
+	"\\/": "|",
+	"xor": "^",
+}
+
+// prologToVerilogMult maps a Prolog multiplicative operator to a Verilog
+// multiplicative operator.
+var prologToVerilogMult = map[string]string{
+	"*":   "*",
+	"//":  "/",
+	"/":   "/",
+	"rem": "%",
+	"mod": "%",
+	"<<":  "<<",
+	">>":  ">>>",
+}
+
+// prologToVerilogRel maps a Prolog relational operator to a Verilog relational
+// operator.
+var prologToVerilogRel = map[string]string{
+	"=<":  "<=",
+	">=":  ">=",
+	"<":   "<",
+	">":   ">",
+	"=":   "==",
+	"\\=": "!=",
+	"is":  "==",
+}
+
+// toVerilogExpr recursively converts an AST, starting from a clause's body
+// predicate, to an expression.
+func (a *ASTNode) toVerilogExpr(p *Parameters, p2v map[string]string, tys TypeInfo) string {
+	switch a.Type {
+	case NumeralType:
+		return p.intConst(p.IntBits, a.Value.(int))
+
+	case AtomType:
+		return "`" + a.Value.(string)
+
+	case VariableType:
+		v, ok := p2v[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert variable %s from Prolog to Verilog", a.Value.(string))
+		}
+		if intOperand(a, tys) {
+			return p.signedInt(v)
+		}
+		return v
+
+	case UnaryOpType:
+		v, ok := prologToVerilogUnary[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert %s %q from Prolog to Verilog", a.Type, a.Value.(string))
+		}
+		return v
+
+	case AdditiveOpType:
+		v, ok := prologToVerilogAdd[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert %s %q from Prolog to Verilog", a.Type, a.Value.(string))
+		}
+		return v
+
+	case MultiplicativeOpType:
+		v, ok := prologToVerilogMult[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert %s %q from Prolog to Verilog", a.Type, a.Value.(string))
+		}
+		return v
+
+	case RelationOpType:
+		v, ok := prologToVerilogRel[a.Value.(string)]
+		if !ok {
+			fatalf("Internal error: Failed to convert %s %q from Prolog to Verilog", a.Type, a.Value.(string))
+		}
+		return v
+
+	case PrimaryExprType:
+		if isFunction(a) {
+			return a.functionExpr(p, p2v, tys)
+		}
+		c := a.Children[0].toVerilogExpr(p, p2v, tys)
+		if a.Value.(string) == "()" {
+			return "(" + c + ")"
+		}
+		return c
+
+	case UnaryExprType:
+		if len(a.Children) == 1 {
+			return a.Children[0].toVerilogExpr(p, p2v, tys)
+		}
+		return a.Children[0].toVerilogExpr(p, p2v, tys) + a.Children[1].toVerilogExpr(p, p2v, tys)
+
+	case MultiplicativeExprType:
+		if len(a.Children) == 1 {
+			return a.Children[0].toVerilogExpr(p, p2v, tys)
+		}
+		if isDivision(a) {
+			return a.divideExpr(p, p2v, tys)
+		}
+		c1 := a.Children[0].toVerilogExpr(p, p2v, tys)
+		v := a.Children[1].toVerilogExpr(p, p2v, tys)
+		c2 := a.Children[2].toVerilogExpr(p, p2v, tys)
+		return c1 + v + c2
+
+	case AdditiveExprType:
+		if len(a.Children) == 1 {
+			return a.Children[0].toVerilogExpr(p, p2v, tys)
+		}
+		c1 := a.Children[0].toVerilogExpr(p, p2v, tys)
+		v := a.Children[1].toVerilogExpr(p, p2v, tys)
+		c2 := a.Children[2].toVerilogExpr(p, p2v, tys)
+		if v != "+" && v != "-" {
+			// Verilog's bitwise operators bind more loosely than
+			// its arithmetic and relational operators.
+			return "(" + c1 + " " + v + " " + c2 + ")"
+		}
+		return c1 + " " + v + " " + c2
+
+	case RelationType:
+		if v, ok := a.structRelation(p, p2v, tys); ok {
+			return v
+		}
+		c1 := a.Children[0].toVerilogExpr(p, p2v, tys)
+		v := a.Children[1].toVerilogExpr(p, p2v, tys)
+		c2 := a.Children[2].toVerilogExpr(p, p2v, tys)
+		if rw, ok := p.RelWidths[a]; ok {
+			// Widen and bias both sides so that the relation's
+			// arithmetic cannot overflow.
+			pfx := p.intConst(rw.Bits, rw.Bias) + " + "
+			c1, c2 = pfx+c1, pfx+c2
+		}
+		cmp := c1 + " " + v + " " + c2
+		if oks := a.arithDefined(p, p2v, tys); len(oks) > 0 {
+			cmp = "(" + cmp + ") && " + strings.Join(oks, " && ")
+		}
+		return cmp
+
+	case TermType:
+		return a.Children[0].toVerilogExpr(p, p2v, tys)
+
+	case ListType:
+		// Concatenate the list's length, its tail (if any), and its
+		// elements in reverse order.
+		ty, err := a.termType(tys, p.FunctorTypes)
+		checkError(err)
+		elts, tail := a.listParts()
+		nElts := uint(len(elts))
+		cs := make([]string, 0, len(elts)+2)
+		if tail == nil {
+			cs = append(cs, fmt.Sprintf("%d'd%d", p.ListLenBits, nElts))
+			if nElts < p.MaxListLen {
+				cs = append(cs, zeros((p.MaxListLen-nElts)*p.typeBits(ty.ElemType())))
+			}
+		} else {
+			tName := tail.toVerilogExpr(p, p2v, tys)
+			cs = append(cs, fmt.Sprintf("%s + %d'd%d", p.listLength(tName, ty), p.ListLenBits, nElts))
+			if nElts < p.MaxListLen {
+				eBits := p.typeBits(ty.ElemType())
+				cs = append(cs, partSelect(tName, (p.MaxListLen-nElts)*eBits-1, 0))
+			}
+		}
+		for i := len(elts) - 1; i >= 0; i-- {
+			cs = append(cs, elts[i].toVerilogExpr(p, p2v, tys))
+		}
+		return "{" + strings.Join(cs, ", ") + "}"
+
+	case StructureType:
+		// Concatenate the structure's functor tag and its arguments
+		// in reverse order, zero-extending each argument to fill a
+		// field.
+		args := a.Children[1:]
+		fBits := p.fieldBits()
+		cs := make([]string, 0, len(args)+2)
+		cs = append(cs, fmt.Sprintf("%d'd%d", p.FunctorBits, p.FunctorToInt[a.functorName()]))
+		if nArgs := uint(len(args)); nArgs < p.MaxArity {
+			cs = append(cs, zeros((p.MaxArity-nArgs)*fBits))
+		}
+		for i := len(args) - 1; i >= 0; i-- {
+			ty, err := args[i].termType(tys, p.FunctorTypes)
+			checkError(err)
+			e := args[i].toVerilogExpr(p, p2v, tys)
+			if bits := p.typeBits(ty); bits < fBits {
+				e = "{" + zeros(fBits-bits) + ", " + e + "}"
+			}
+			cs = append(cs, e)
+		}
+		return "{" + strings.Join(cs, ", ") + "}"
+
+	case PredicateType:
+		// Handle predicate AST nodes that are really just wrappers for
+		// expressions.
+		if len(a.Children) == 1 {
+			if c := a.Children[0]; c.Type == AtomType {
+				// Built-in predicates with no arguments
+				switch c.Value.(string) {
+				case "true":
+					return "1'b1"
+				case "fail", "false":
+					return "1'b0"
+				}
+			}
+			return a.Children[0].toVerilogExpr(p, p2v, tys)
+		}
+
+		// Ignore atom/1 and integer/1, which exist solely for the type
+		// system.
+		if len(a.Children) == 2 {
+			pName := a.Children[0].Value.(string)
+			if pName == "atom" || pName == "integer" {
+				return "1'b1"
+			}
+		}
+
+		cs := make([]string, 0, len(a.Children)*2)
+		for i, c := range a.Children {
+			switch i {
+			case 0:
+				// Name the instance after the module, inserting
+				// the call's source position before the arity
+				// (e.g., "\path/2@3 \path_L12C5/2@3").
+				mName := a.calleeName(p)
+				slash := strings.Index(mName, "/")
+				cs = append(cs, fmt.Sprintf("\\%s \\%s_%s%s",
+					mName, mName[:slash], a.instanceSuffix(), mName[slash:]))
+			case 1:
+				cs = append(cs, " (")
+				cs = append(cs, c.toVerilogExpr(p, p2v, tys))
+			default:
+				cs = append(cs, ", ")
+				cs = append(cs, c.toVerilogExpr(p, p2v, tys))
+			}
+		}
+		cs = append(cs, ", %s)")
+		return strings.Join(cs, "")
+
+	case NegationType:
+		// Invert the result of the negated goal.  A relation is
+		// inverted directly.  An instance's validity bit is routed
+		// through an intermediate wire, which is then inverted.
+		g := a.Children[0]
+		v := g.toVerilogExpr(p, p2v, tys)
+		if !strings.Contains(v, "%s") {
+			conds := append(g.listSideConditions(p, p2v, tys), v)
+			return "!(" + strings.Join(conds, " && ") + ")"
+		}
+		wName := "$not_" + a.instanceSuffix()
+		return fmt.Sprintf("wire %s;\n  %s;\n  assign %%s = ~%s",
+			wName, strings.Replace(v, "%s", wName, 1), wName)
+
+	case DisjunctionType:
+		// Assign each branch's conditions to a separate vector of
+		// validity bits.  Each branch is guarded by the negation of the
+		// conditions of all preceding if-then branches.
+		sfx := a.instanceSuffix()
+		stmts := make([]string, 0, len(a.Children)*4)
+		alts := make([]string, len(a.Children))
+		guards := make([]string, 0, len(a.Children))
+		for i, b := range a.Children {
+			bName := fmt.Sprintf("$or_%s_%d", sfx, i+1)
+			goals := b.Children
+			items := append([]string{}, guards...)
+			if b.Type == IfThenType {
+				cName := bName + "c"
+				stmts = append(stmts, assignBits(cName, goalConditions(b.Children[0].Children, p, p2v, tys))...)
+				items = append(items, "&"+cName)
+				guards = append(guards, "~&"+cName)
+				goals = b.Children[1].Children
+			}
+			items = append(items, goalConditions(goals, p, p2v, tys)...)
+			stmts = append(stmts, assignBits(bName, items)...)
+			alts[i] = "&" + bName
+		}
+		stmts = append(stmts, "assign %s = "+strings.Join(alts, " | "))
+		return strings.Join(stmts, ";\n  ")
+
+	default:
+		fatalf("Internal error: Unexpected AST node type %s", a.Type)
+	}
+	return "" // We should never get here.
+}
+
+// divOperands returns Verilog expressions for the operands of a division,
+// modulo, remainder, or shift operation or for the arguments of an evaluable
+// function.  Each is evaluated with the number of bits p.DivWidths specifies,
+// independent of the surrounding expression.
+func (a *ASTNode) divOperands(p *Parameters, p2v map[string]string, tys TypeInfo) []string {
+	dw := p.divWidth(a)
+	cs := operands(a)
+	ops := make([]string, len(cs))
+	for i, c := range cs {
+		v := p.intConst(dw.Bits, 0) + " + " + c.toVerilogExpr(p, p2v, tys)
+		if dw.Signed {
+			ops[i] = "$signed(" + v + ")"
+		} else {
+			ops[i] = "(" + v + ")"
+		}
+	}
+	return ops
+}
+
+// divideExpr converts a division, modulo, remainder, or shift operation to a
+// Verilog expression.  Verilog division truncates toward zero, and its
+// remainder takes the sign of the dividend, so a modulus, which takes the sign
+// of the divisor, adds the divisor to a remainder of the opposite sign.  A
+// right shift is arithmetic if its operands are signed.  The result is
+// computed in isolation and then extended to the width of the surrounding
+// expression.
+func (a *ASTNode) divideExpr(p *Parameters, p2v map[string]string, tys TypeInfo) string {
+	dw := p.divWidth(a)
+	cs := a.divOperands(p, p2v, tys)
+	c1, c2 := cs[0], cs[1]
+	op := a.Children[1].Value.(string)
+	q := c1 + " " + prologToVerilogMult[op] + " " + c2
+	if op == "mod" && dw.Signed {
+		zero := fmt.Sprintf("%d'sd0", dw.Bits)
+		q = fmt.Sprintf("(%s != %s && (%s < %s) != (%s < %s)) ? %s + %s : %s",
+			q, zero, q, zero, c2, zero, q, c2, q)
+	}
+	return p.extendDivResult(dw, q)
+}
+
+// functionExpr converts an application of an evaluable function to a Verilog
+// expression built from comparisons and conditional operators.  As in
+// divideExpr, the result is computed in isolation and then extended to the
+// width of the surrounding expression.
+func (a *ASTNode) functionExpr(p *Parameters, p2v map[string]string, tys TypeInfo) string {
+	dw := p.divWidth(a)
+	cs := a.divOperands(p, p2v, tys)
+	num := func(v int) string {
+		if dw.Signed {
+			return fmt.Sprintf("%d'sd%d", dw.Bits, v)
+		}
+		return fmt.Sprintf("%d'd%d", dw.Bits, v)
+	}
+	var q string
+	switch fn := a.Value.(string); fn {
+	case "abs":
+		q = cs[0]
+		if dw.Signed {
+			q = fmt.Sprintf("(%s < %s) ? -%s : %s", cs[0], num(0), cs[0], cs[0])
+		}
+	case "sign":
+		q = fmt.Sprintf("(%s != %s) ? %s : %s", cs[0], num(0), num(1), num(0))
+		if dw.Signed {
+			q = fmt.Sprintf("(%s < %s) ? -%s : %s", cs[0], num(0), num(1), q)
+		}
+	case "min":
+		q = fmt.Sprintf("(%s < %s) ? %s : %s", cs[1], cs[0], cs[1], cs[0])
+	case "max":
+		q = fmt.Sprintf("(%s < %s) ? %s : %s", cs[0], cs[1], cs[1], cs[0])
+	default:
+		fatalf("Internal error: Failed to convert function %q from Prolog to Verilog", fn)
+	}
+	return p.extendDivResult(dw, q)
+}
+
+// extendDivResult extends the result of an operation that p.DivWidths
+// describes to the width of the surrounding expression.
+func (p *Parameters) extendDivResult(dw DivWidth, q string) string {
+	switch {
+	case !dw.Signed:
+		return "$unsigned(" + q + ")"
+	case p.Signed:
+		return "$signed(" + q + ")"
+	default:
+		// Sign-extend a signed result into an unsigned expression.
+		m := p.intConst(dw.Bits, 1<<(dw.Bits-1))
+		return fmt.Sprintf("(($unsigned(%s) ^ %s) - %s)", q, m, m)
+	}
+}
+
+// arithDefined returns a list of Verilog conditions indicating whether every
+// arithmetic operation in a relation is defined: No division, modulo, or
+// remainder operation has a zero divisor, no shift operation has a negative
+// shift amount, and every operation that p.ArithChecks says to check produces
+// a value that fits in IntBits bits.
+func (a *ASTNode) arithDefined(p *Parameters, p2v map[string]string, tys TypeInfo) []string {
+	top := p.storageRange(p.IntBits)
+	oks := make([]string, 0, 4)
+	for _, e := range divisionNodes(a) {
+		dw := p.divWidth(e)
+		c2 := e.divOperands(p, p2v, tys)[1]
+		switch op := e.Children[1].Value.(string); {
+		case op != "<<" && op != ">>":
+			oks = append(oks, fmt.Sprintf("(%s != %s)", c2, p.intConst(dw.Bits, 0)))
+		case dw.Signed:
+			oks = append(oks, fmt.Sprintf("(%s >= %d'sd0)", c2, dw.Bits))
+		}
+	}
+	for _, e := range arithNodes(a) {
+		ck, ok := p.ArithChecks[e]
+		if !ok {
+			continue
+		}
+		v := "(" + e.toVerilogExpr(p, p2v, tys) + ")"
+		if ck.Bias != 0 {
+			v = p.intConst(ck.Bits, ck.Bias) + " + " + v
+		}
+		if ck.Bias != 0 || p.Signed {
+			oks = append(oks, fmt.Sprintf("(%s >= %s)", v, p.intConst(ck.Bits, ck.Bias+int(top.Lo))))
+		}
+		oks = append(oks, fmt.Sprintf("(%s <= %s)", v, p.intConst(ck.Bits, ck.Bias+int(top.Hi))))
+	}
+	return oks
+}
+
+// structRelation lowers equality or inequality between two structures to
+// argument-wise equality.  It returns false if the relation does not compare
+// two structures.
+func (a *ASTNode) structRelation(p *Parameters, p2v map[string]string, tys TypeInfo) (string, bool) {
+	// Ensure we have two structures.
+	t1, t2 := a.Children[0], a.Children[2]
+	if t1.Type != TermType || t2.Type != TermType {
+		return "", false
+	}
+	s1, s2 := t1.Children[0], t2.Children[0]
+	if s1.Type != StructureType || s2.Type != StructureType {
+		return "", false
+	}
+	equal := a.Value.(string) == "="
+
+	// Structures with different functors never unify.
+	if s1.functorName() != s2.functorName() {
+		if equal {
+			return "1'b0", true
+		}
+		return "1'b1", true
+	}
+
+	// Structures with the same functor unify if all of their arguments
+	// are equal.
+	eqs := make([]string, len(s1.Children)-1)
+	for i, c1 := range s1.Children[1:] {
+		c2 := s2.Children[i+1]
+		eqs[i] = c1.toVerilogExpr(p, p2v, tys) + " == " + c2.toVerilogExpr(p, p2v, tys)
+	}
+	if equal {
+		return strings.Join(eqs, " && "), true
+	}
+	return "!(" + strings.Join(eqs, " && ") + ")", true
+}
+
+// listSideConditions returns a list of Verilog expressions that must hold for
+// the lists constructed by a predicate to fit within MaxListLen elements.
+// Lists within a negated goal or a disjunction are excluded; they are instead
+// handled as part of the negation or the disjunction's branches.
+func (a *ASTNode) listSideConditions(p *Parameters, p2v map[string]string, tys TypeInfo) []string {
+	if a.Type == PredicateType {
+		switch a.Children[0].Type {
+		case NegationType, DisjunctionType:
+			return nil
+		}
+	}
+	conds := make([]string, 0)
+	for _, l := range a.FindByType(ListType) {
+		elts, tail := l.listParts()
+		if tail == nil {
+			continue
+		}
+		ty, err := l.termType(tys, p.FunctorTypes)
+		checkError(err)
+		tName := tail.toVerilogExpr(p, p2v, tys)
+		conds = append(conds, fmt.Sprintf("%s <= %d'd%d",
+			p.listLength(tName, ty), p.ListLenBits, p.MaxListLen-uint(len(elts))))
+	}
+	return conds
+}
+
+// process converts each predicate in a clause to an assignment to a valid bit.
+func (a *ASTNode) process(p *Parameters, p2v map[string]string, tys TypeInfo) []string {
+	// Assign validity based on matches on any specified input symbols or
+	// numbers.
+	valid := make([]string, 0, len(a.Children))
+	pArgs, vArgs := a.args()
+	terms := a.Children[0].Children[1:]
+	for i, pa := range pArgs {
+		if t := terms[i].Children[0].Type; t == ListType || t == StructureType {
+			continue // Handled by bindListArg or bindStructArg
+		}
+		r0 := rune(pa[0])
+		switch {
+		case unicode.IsLower(r0):
+			// Symbol
+			valid = append(valid, fmt.Sprintf("%s == `%s", vArgs[i], pa))
+		case unicode.IsDigit(r0), r0 == '-':
+			// Numeral
+			valid = append(valid, fmt.Sprintf("%s == %s", p.signedInt(vArgs[i]), terms[i].toVerilogExpr(p, p2v, tys)))
+		case unicode.IsUpper(r0), r0 == '_':
+			// Variable
+
+		default:
+			fatalf("Internal error processing %q", pa)
+		}
+	}
+
+	// Assign validity based on each predicate in the clause's body.
+	return append(valid, goalConditions(a.Children[1:], p, p2v, tys)...)
+}
+
+// goalConditions converts a list of predicates to a list of Boolean Verilog
+// expressions.  Expressions containing "%s" are statements that assign a
+// value to whatever bit replaces the "%s".
+func goalConditions(goals []*ASTNode, p *Parameters, p2v map[string]string, tys TypeInfo) []string {
+	valid := make([]string, 0, len(goals))
+	for _, pred := range goals {
+		valid = append(valid, pred.listSideConditions(p, p2v, tys)...)
+		v := pred.toVerilogExpr(p, p2v, tys)
+		if v != "1'b1" {
+			valid = append(valid, v)
+		}
+	}
+	return valid
+}
+
+// assignBits returns a list of Verilog statements that declare a vector of
+// validity bits and assign one Boolean expression to each bit.
+func assignBits(vName string, valid []string) []string {
+	if len(valid) == 0 {
+		// Although not normally used in practice, handle useless
+		// predicate lists that accept all inputs.
+		valid = []string{"1'b1"}
+	}
+	stmts := make([]string, 0, len(valid)+1)
+	if len(valid) == 1 {
+		// Single bit
+		stmts = append(stmts, "wire "+vName)
+	} else {
+		// Multiple bits
+		stmts = append(stmts, fmt.Sprintf("wire [%d:0] %s", len(valid)-1, vName))
+	}
+	for i, v := range valid {
+		vBit := vName
+		if len(valid) > 1 {
+			vBit = fmt.Sprintf("%s[%d]", vName, i)
+		}
+		if strings.Contains(v, "%s") {
+			stmts = append(stmts, strings.Replace(v, "%s", vBit, 1))
+		} else {
+			stmts = append(stmts, fmt.Sprintf("assign %s = %s", vBit, v))
+		}
+	}
+	return stmts
+}
+
+// writeClauseGroupHeader is used by writeClauseGroup to write a Verilog module
+// header.
+func (a *ASTNode) writeClauseGroupHeader(w io.Writer, p *Parameters, nm string, cs []*ASTNode, tys ArgTypes) {
+	// Write the module prototype.
+	_, vArgs := cs[0].args()
+	rawName := strings.Split(nm, "/")[0]
+	lvlStr := ""
+	if _, lvl, ok := splitLevel(nm); ok {
+		lvlStr = fmt.Sprintf(" at recursion level %d", lvl)
+	}
+	if len(tys) == 0 {
+		// No arguments (rare)
+		fmt.Fprintf(w, "// Define %s%s.\n", rawName, lvlStr)
+	} else {
+		// At least one argument (common)
+		for i, ty := range tys {
+			if i == 0 {
+				fmt.Fprintf(w, "// Define %s(%v", rawName, ty)
+			} else {
+				fmt.Fprintf(w, ", %v", ty)
+			}
+		}
+		fmt.Fprintf(w, ")%s.\n", lvlStr)
+	}
+	if rawName == "Query" {
+		fmt.Fprint(w, "module Query (") // Exclude the arity from the top-level query.
+	} else {
+		fmt.Fprintf(w, "module \\%s (", nm)
+	}
+	for i, a := range vArgs {
+		if i > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprint(w, a)
+	}
+	if len(vArgs) > 0 {
+		fmt.Fprintln(w, ", Valid);")
+	} else {
+		fmt.Fprintln(w, "Valid);")
+	}
+
+	// Write the module inputs.
+	for i, a := range vArgs {
+		bits := p.argBits(nm, i, tys[i])
+		if bits == 1 {
+			fmt.Fprintf(w, "  input %s%s;\n", p.signedDecl(tys[i]), a)
+		} else {
+			fmt.Fprintf(w, "  input %s[%d:0] %s;\n", p.signedDecl(tys[i]), bits-1, a)
+		}
+	}
+
+	// Write the module output.
+	fmt.Fprintln(w, "  output Valid;")
+}
+
+// bindListArg is used by writeClauseBody to match a list-valued clause
+// argument against the list that appears in the clause's head (e.g.,
+// "[H|T]").  It returns a list of Boolean Verilog expressions that must hold
+// and the new number of Verilog variables.
+func (a *ASTNode) bindListArg(w io.Writer, p *Parameters, v string, ty VarType,
+	p2v map[string]string, nVars int) ([]string, int) {
+	// Constrain the length of the list.
+	elts, tail := a.listParts()
+	nElts := uint(len(elts))
+	valid := make([]string, 0, nElts+1)
+	switch {
+	case tail == nil:
+		valid = append(valid, fmt.Sprintf("%s == %d'd%d", p.listLength(v, ty), p.ListLenBits, nElts))
+	case nElts > 0:
+		valid = append(valid, fmt.Sprintf("%s >= %d'd%d", p.listLength(v, ty), p.ListLenBits, nElts))
+	}
+
+	// Map each variable element to a slice of the list, and compare each
+	// numeral or atom element to the corresponding slice.
+	for i, e := range elts {
+		eExpr := p.listElement(v, ty, uint(i))
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, eExpr+" == "+c.toVerilogExpr(p, p2v, nil))
+			continue
+		}
+		if pv, seen := p2v[c.Value.(string)]; seen {
+			valid = append(valid, eExpr+" == "+pv)
+		} else {
+			p2v[c.Value.(string)] = eExpr
+		}
+	}
+	if tail == nil {
+		return valid, nVars
+	}
+
+	// Define a new Verilog variable for the list's tail.
+	tName := numToVerVar(nVars)
+	nVars++
+	if bits := p.typeBits(ty); bits == 1 {
+		fmt.Fprintf(w, "  wire %s;\n", tName)
+	} else {
+		fmt.Fprintf(w, "  wire [%d:0] %s;\n", bits-1, tName)
+	}
+	cs := []string{fmt.Sprintf("%s - %d'd%d", p.listLength(v, ty), p.ListLenBits, nElts)}
+	if nElts > 0 {
+		cs = append(cs, zeros(nElts*p.typeBits(ty.ElemType())))
+	}
+	if nElts < p.MaxListLen {
+		cs = append(cs, p.listElements(v, ty, nElts))
+	}
+	fmt.Fprintf(w, "  assign %s = {%s};\n", tName, strings.Join(cs, ", "))
+	if pv, seen := p2v[tail.Value.(string)]; seen {
+		valid = append(valid, tName+" == "+pv)
+	} else {
+		p2v[tail.Value.(string)] = tName
+	}
+	return valid, nVars
+}
+
+// bindStructArg is used by writeClauseBody to match a structure-valued
+// clause argument against the structure that appears in the clause's head
+// (e.g., "point(X, Y)").  It returns a list of Boolean Verilog expressions
+// that must hold.
+func (a *ASTNode) bindStructArg(p *Parameters, v string, p2v map[string]string, vTy TypeInfo) []string {
+	// Constrain the functor.
+	args := a.Children[1:]
+	valid := make([]string, 0, len(args)+1)
+	valid = append(valid, fmt.Sprintf("%s == %d'd%d",
+		p.functorTag(v), p.FunctorBits, p.FunctorToInt[a.functorName()]))
+
+	// Map each variable argument to a field of the structure, and compare
+	// each numeral or atom argument to the corresponding field.
+	tys := p.FunctorTypes[a.functorName()]
+	for i, e := range args {
+		fExpr := p.structField(v, uint(i), p.typeBits(tys[i]))
+		c := e.Children[0]
+		if c.Type != VariableType {
+			valid = append(valid, fExpr+" == "+c.toVerilogExpr(p, p2v, vTy))
+			continue
+		}
+		if pv, seen := p2v[c.Value.(string)]; seen {
+			valid = append(valid, fExpr+" == "+pv)
+		} else {
+			p2v[c.Value.(string)] = fExpr
+		}
+	}
+	return valid
+}
+
+// writeClauseBody is used by writeClauseGroup to assign a Verilog bit for each
+// Prolog predicate in a clause's body.  It returns the number of new variables
+// introduced.
+func (a *ASTNode) writeClauseBody(w io.Writer, p *Parameters, nm string,
+	cNum int, nVars int, tys ArgTypes, vTy TypeInfo) int {
+	// Construct a map from Prolog variables to Verilog variables.  As we
+	// go along, constrain all variables with the same Prolog name to have
+	// the same value.
+	valid := make([]string, 0)
+	pArgs, vArgs := a.args()
+	p2v := make(map[string]string, len(pArgs))
+	rebound := make([]int, 0, len(pArgs))
+	for i, pa := range pArgs {
+		v, seen := p2v[pa]
+		switch {
+		case seen && vTy[pa] == InfNumeral:
+			valid = append(valid, p.signedInt(vArgs[i])+" == "+p.signedInt(v))
+		case seen:
+			valid = append(valid, vArgs[i]+" == "+v)
+		case p.rebound(nm, a, i, vTy):
+			rebound = append(rebound, i)
+		default:
+			p2v[pa] = vArgs[i]
+		}
+	}
+
+	// Map variables that appear within list or structure arguments to
+	// portions of those arguments.
+	for i, t := range a.Children[0].Children[1:] {
+		switch c := t.Children[0]; c.Type {
+		case ListType:
+			var lValid []string
+			lValid, nVars = c.bindListArg(w, p, vArgs[i], tys[i], p2v, nVars)
+			valid = append(valid, lValid...)
+		case StructureType:
+			valid = append(valid, c.bindStructArg(p, vArgs[i], p2v, vTy)...)
+		}
+	}
+
+	// Ensure that lists and structures passed to the query are well
+	// formed.
+	if a.Type == QueryType {
+		for i, v := range vArgs {
+			switch {
+			case tys[i].IsList():
+				valid = append(valid, p.listCanonical(v, tys[i]))
+			case tys[i] == InfStructure:
+				valid = append(valid, p.structCanonical(v))
+			}
+		}
+	}
+
+	// Introduce more Verilog variables for local Prolog variables, in
+	// order of Verilog name.
+	newP2v := a.augmentVerilogVars(nVars, p2v)
+	pNames := make([]string, 0, len(newP2v))
+	for pName := range newP2v {
+		pNames = append(pNames, pName)
+	}
+	sort.Slice(pNames, func(i, j int) bool {
+		vi, vj := newP2v[pNames[i]], newP2v[pNames[j]]
+		if len(vi) != len(vj) {
+			return len(vi) < len(vj)
+		}
+		return vi < vj
+	})
+	for _, pName := range pNames {
+		vName := newP2v[pName]
+		bits := p.varBits(a, pName, vTy[pName])
+		if bits == 1 {
+			fmt.Fprintf(w, "  (* keep *) wire %s%s;\n", p.signedDecl(vTy[pName]), vName)
+		} else {
+			fmt.Fprintf(w, "  (* keep *) wire %s[%d:0] %s;\n", p.signedDecl(vTy[pName]), bits-1, vName)
+		}
+		switch {
+		case vTy[pName].IsList():
+			valid = append(valid, p.listCanonical(vName, vTy[pName]))
+		case vTy[pName] == InfStructure:
+			valid = append(valid, p.structCanonical(vName))
+		}
+		p2v[pName] = vName
+		nVars++
+	}
+
+	// Copy arguments to variables of a different width.
+	for _, i := range rebound {
+		valid = append(valid, p.signedInt(vArgs[i])+" == "+p.signedInt(p2v[pArgs[i]]))
+	}
+
+	// Convert the clause body to a list of Boolean Verilog
+	// expressions.
+	// Useless clauses that accept all inputs (e.g., "stupid(A, B, C).")
+	// are handled by assignBits.
+	valid = append(valid, a.process(p, p2v, vTy)...)
+	for _, stmt := range assignBits(fmt.Sprintf("$v%d", cNum+1), valid) {
+		fmt.Fprintf(w, "  %s;\n", stmt)
+	}
+	return nVars
+}
+
+// writeClauseGroup writes a Verilog module corresponding to a group of clauses
+// that have the same name and arity.
+func (a *ASTNode) writeClauseGroup(w io.Writer, p *Parameters, nm string,
+	cs []*ASTNode, tys ArgTypes, clVarTys map[*ASTNode]TypeInfo) {
+	// Write a module header.
+	a.writeClauseGroupHeader(w, p, nm, cs, tys)
+
+	// The lowest level of a recursive clause group always fails.
+	if _, lvl, ok := splitLevel(nm); ok && lvl == 0 {
+		fmt.Fprintln(w, "  assign Valid = 1'b0;")
+		fmt.Fprintln(w, "endmodule")
+		return
+	}
+
+	// Assign validity conditions based on each clause in the clause group.
+	_, vArgs := cs[0].args()
+	nVars := len(vArgs)
+	for i, c := range cs {
+		nVars += c.writeClauseBody(w, p, nm, i, nVars, tys, clVarTys[c])
+	}
+
+	// Set the final validity bit to the intersection of all predicate
+	// validity bits.
+	fmt.Fprint(w, "  assign Valid = ")
+	for i := range cs {
+		if i > 0 {
+			fmt.Fprint(w, " | ")
+		}
+		fmt.Fprintf(w, "&$v%d", i+1)
+	}
+	fmt.Fprintln(w, ";")
+	fmt.Fprintln(w, "endmodule")
+}
+
+// WriteVerilog writes an entire (preprocessed) AST as Verilog code.
+func (a *ASTNode) WriteVerilog(w io.Writer, p *Parameters,
+	nm2tys map[string]ArgTypes, clVarTys map[*ASTNode]TypeInfo) {
+	// Output some header comments.
+	fmt.Fprintf(w, "// Verilog version of Prolog program %s\n", p.InFileName)
+	fmt.Fprintf(w, "// Conversion by %s, written by Scott Pakin <pakin@lanl.gov>\n", p.ProgName)
+	fmt.Fprintln(w, `//
+// This program is intended to be passed to edif2qmasm, then to qmasm, and
+// finally run on a quantum annealer.
+//`)
+	fmt.Fprintf(w, "// Note: This program uses %d bit(s) for atoms and, by default, %d bit(s) for\n", p.SymBits, p.IntBits)
+	fmt.Fprintf(w, "// (%s) integers.\n", p.intSignedness())
+	fmt.Fprintln(w, "")
+
+	// Define constants for all of our symbols.
+	a.writeSymbols(w, p)
+
+	// Write each clause group in turn, sorted by name.
+	names := make([]string, 0, len(p.TopLevel))
+	for nm := range p.TopLevel {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	for _, nm := range names {
+		fmt.Fprintln(w, "")
+		a.writeClauseGroup(w, p, nm, p.TopLevel[nm], nm2tys[nm], clVarTys)
+	}
+}